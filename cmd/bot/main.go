@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/rs/zerolog"
 )
@@ -40,6 +41,7 @@ func main() {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGINT, syscall.SIGABRT)
 	<-c
 
+	meow.Stop()
 	meow.Client.Disconnect()
 	meow.Container.Close()
 	meow.UserDB.Close()
@@ -1,11 +1,15 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"meowabot/internal/config"
 	"meowabot/internal/database"
 	"meowabot/internal/handler"
+	"meowabot/internal/tools/media"
+	"meowabot/internal/tools/mediacache"
+	"path/filepath"
 	"time"
 
 	_ "meowabot/internal/app/commands"
@@ -19,6 +23,9 @@ import (
 	"gorm.io/gorm/schema"
 )
 
+// defaultMediaCacheMB is the size of the media cache when the config doesn't set it.
+const defaultMediaCacheMB = 256
+
 func StartMeowbot(configPath string, sessionPath string, databasePath string, logger *zerolog.Logger) (*handler.EventHandler, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*5)
 	defer cancel()
@@ -42,6 +49,13 @@ func StartMeowbot(configPath string, sessionPath string, databasePath string, lo
 		return nil, err
 	}
 
+	checkMediaTools(ctx, config, logger)
+
+	mediaCache, err := mediacache.New(filepath.Join(filepath.Dir(databasePath), "media"), cmp.Or(config.MediaCacheMB, defaultMediaCacheMB)<<20)
+	if err != nil {
+		return nil, err
+	}
+
 	deviceStore, err := container.GetFirstDevice(ctx)
 	if err != nil {
 		return nil, err
@@ -50,12 +64,13 @@ func StartMeowbot(configPath string, sessionPath string, databasePath string, lo
 	cli := whatsmeow.NewClient(deviceStore, waLog.Noop)
 
 	opts := handler.EventHandlerOptions{
-		Config:    config,
-		Client:    cli,
-		Container: container,
-		UserDB:    db,
-		Logger:    logger,
-		WaLogger:  waLog.Zerolog(logger.With().Str("Source", "Client").Logger()),
+		Config:     config,
+		Client:     cli,
+		Container:  container,
+		UserDB:     db,
+		Logger:     logger,
+		WaLogger:   waLog.Zerolog(logger.With().Str("Source", "Client").Logger()),
+		MediaCache: mediaCache,
 	}
 
 	evthandler := handler.NewEventHandler(opts)
@@ -85,5 +100,26 @@ func StartMeowbot(configPath string, sessionPath string, databasePath string, lo
 		}
 	}()
 
+	evthandler.StartBackgroundTasks()
+
 	return evthandler, nil
 }
+
+// checkMediaTools looks for ffmpeg and the encoders the media commands use,
+// warning about what is missing.
+func checkMediaTools(ctx context.Context, config *config.ConfigScheme, logger *zerolog.Logger) {
+	caps := media.DetectCapabilities(ctx)
+	if !caps.FFmpeg {
+		logger.Warn().Msg("ffmpeg not found, stickers from videos and the audio and video commands won't work")
+		return
+	}
+	logger.Info().Str("Version", caps.Version).Msg("Found ffmpeg")
+	if !caps.FFprobe {
+		logger.Warn().Msg("ffprobe not found, media durations won't be detected")
+	}
+	for _, encoder := range []string{"libwebp", "libmp3lame", "libx264", cmp.Or(config.VoiceCodec, "libopus")} {
+		if !caps.HasEncoder(encoder) {
+			logger.Warn().Str("Encoder", encoder).Msg("ffmpeg was built without an encoder the media commands use")
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package commands
+
+import (
+	"errors"
+	"meowabot/internal/command"
+	"meowabot/internal/tools/media"
+	"strconv"
+	"strings"
+
+	tmsg "meowabot/internal/tools/messages"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxAudioInput is the largest audio or video accepted by the audio commands.
+const maxAudioInput = 20 << 20
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"tovoice", "ptt", "paravoz"},
+		Run:     convertToVoiceNote,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"tomp3", "paramp3"},
+		Run: func(ctx *command.CommandContext) error {
+			return convertToMP3(ctx, false)
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"toaudio", "extrairaudio"},
+		Run: func(ctx *command.CommandContext) error {
+			return convertToMP3(ctx, true)
+		},
+	})
+
+	effects := map[string][]string{
+		"bass":      {"bass", "bassboost", "grave"},
+		"speed":     {"speed", "acelerar", "rapido"},
+		"slow":      {"slow", "lento"},
+		"pitch":     {"pitch", "tom"},
+		"reverse":   {"reverse", "reverso"},
+		"nightcore": {"nightcore"},
+		"echo":      {"echo", "eco"},
+		"robot":     {"robot", "robo"},
+		"8d":        {"8d"},
+	}
+	for name, aliases := range effects {
+		effect, _ := media.GetAudioEffect(name)
+		cmd.Register(&command.Command{
+			Aliases: aliases,
+			Run: func(ctx *command.CommandContext) error {
+				return applyAudioEffect(ctx, effect)
+			},
+		})
+	}
+}
+
+// audioOptions returns the encoder settings from the config.
+func audioOptions(ctx *command.CommandContext) media.AudioOptions {
+	return media.AudioOptions{
+		VoiceCodec:   ctx.Config.VoiceCodec,
+		VoiceBitrate: ctx.Config.VoiceBitrate,
+		MP3Bitrate:   ctx.Config.AudioBitrate,
+	}
+}
+
+// downloadQuotedAudio downloads the sent or quoted audio or video, as allowed
+// by allowAudio and allowVideo, replying if there is none or it is too large.
+func downloadQuotedAudio(ctx *command.CommandContext, allowAudio, allowVideo bool) ([]byte, bool, error) {
+	var kinds command.MediaKind
+	if allowAudio {
+		kinds |= command.MediaAudio
+	}
+	if allowVideo {
+		kinds |= command.MediaVideo
+	}
+	downloaded, err := ctx.DownloadMedia(kinds, maxAudioInput)
+	if errors.Is(err, command.ErrNoMedia) {
+		var usage *i18n.Message
+		switch {
+		case allowAudio && allowVideo:
+			usage = &i18n.Message{
+				ID:    "cmd.audio.usage",
+				Other: "ℹ️ Envie ou responda um áudio ou vídeo com `{{.Prefix}}{{.Command}}`",
+			}
+		case allowVideo:
+			usage = &i18n.Message{
+				ID:    "cmd.audio.usagevideo",
+				Other: "ℹ️ Envie ou responda um vídeo com `{{.Prefix}}{{.Command}}`",
+			}
+		default:
+			usage = &i18n.Message{
+				ID:    "cmd.audio.usageaudio",
+				Other: "ℹ️ Responda um áudio ou mensagem de voz com `{{.Prefix}}{{.Command}}`",
+			}
+		}
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: usage,
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil, false, nil
+	}
+	if errors.Is(err, command.ErrMediaTooLarge) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.audio.toolarge",
+				Other: "❌ O arquivo é grande demais, o limite é {{.Max}} MB",
+			},
+			TemplateData: map[string]any{
+				"Max": maxAudioInput >> 20,
+			},
+		}))
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return downloaded.Data, true, nil
+}
+
+// replyNoAudio tells the user the media has no audio if err is
+// media.ErrNoAudio, returning whether it did.
+func replyNoAudio(ctx *command.CommandContext, err error) bool {
+	if !errors.Is(err, media.ErrNoAudio) {
+		return false
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.audio.noaudio",
+			Other: "❌ Esse arquivo não tem áudio",
+		},
+	}))
+	return true
+}
+
+// sendVoiceNote sends note as a voice note replying to the command.
+func sendVoiceNote(ctx *command.CommandContext, note *media.VoiceNote) {
+	ctx.SendAudioMessage(ctx.Msg.Info.Chat, note.Data, &command.MessageOptions{
+		QuotedMessage: ctx.Msg,
+		PTT:           true,
+		Seconds:       proto.Uint32(note.Seconds),
+		Waveform:      note.Waveform,
+	})
+}
+
+func convertToVoiceNote(ctx *command.CommandContext) error {
+	data, ok, err := downloadQuotedAudio(ctx, true, true)
+	if !ok {
+		return err
+	}
+	note, err := media.ToVoiceNote(data, audioOptions(ctx))
+	if replyNoAudio(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sendVoiceNote(ctx, note)
+	return nil
+}
+
+// convertToMP3 sends the quoted audio as MP3, or the audio of the quoted
+// video when fromVideo is set.
+func convertToMP3(ctx *command.CommandContext, fromVideo bool) error {
+	data, ok, err := downloadQuotedAudio(ctx, !fromVideo, fromVideo)
+	if !ok {
+		return err
+	}
+	mp3, err := media.ToMP3(data, audioOptions(ctx))
+	if replyNoAudio(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var seconds *uint32
+	if audio := tmsg.GetAudioMessage(ctx.Msg); !fromVideo && audio != nil {
+		seconds = audio.GetAudioMessage().Seconds
+	} else if video := tmsg.GetVideoMessage(ctx.Msg); fromVideo && video != nil {
+		seconds = video.GetVideoMessage().Seconds
+	}
+	ctx.SendAudioMessage(ctx.Msg.Info.Chat, mp3, &command.MessageOptions{
+		QuotedMessage: ctx.Msg,
+		Mimetype:      proto.String(media.MP3Mimetype),
+		Seconds:       seconds,
+	})
+	return nil
+}
+
+func applyAudioEffect(ctx *command.CommandContext, effect *media.AudioEffect) error {
+	amount := effect.Default
+	if args := strings.TrimSpace(ctx.Args); args != "" && effect.TakesAmount() {
+		parsed, err := strconv.ParseFloat(strings.Replace(args, ",", ".", 1), 64)
+		if err == nil {
+			_, err = effect.Filter(parsed)
+		}
+		if err != nil {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.effect.amount",
+					Other: "❌ Use um valor de {{.Min}} a {{.Max}}, por exemplo `{{.Prefix}}{{.Command}} {{.Default}}`",
+				},
+				TemplateData: map[string]any{
+					"Min":     media.FormatAmount(effect.Min),
+					"Max":     media.FormatAmount(effect.Max),
+					"Default": media.FormatAmount(effect.Default),
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+				},
+			}))
+			return nil
+		}
+		amount = parsed
+	}
+
+	data, ok, err := downloadQuotedAudio(ctx, true, false)
+	if !ok {
+		return err
+	}
+	note, err := media.ApplyAudioEffect(data, effect, amount, audioOptions(ctx))
+	if replyNoAudio(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sendVoiceNote(ctx, note)
+	return nil
+}
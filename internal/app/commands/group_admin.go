@@ -1 +1,1420 @@
-package commands
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+	"meowabot/internal/tools/media"
+	"meowabot/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+const modLogPageSize = 10
+
+const defaultMuteDuration = 10 * time.Minute
+
+const maxGreetingLength = 1000
+
+// cardBackgroundSize is the largest side of the stored greeting card backgrounds.
+const cardBackgroundSize = 1024
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"kick", "ban", "remove"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets := moderationTargets(ctx)
+			if len(targets) == 0 {
+				return nil
+			}
+			if _, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, targets, whatsmeow.ParticipantChangeRemove); err != nil {
+				return err
+			}
+			for _, target := range targets {
+				logModeration(ctx, target, database.ModActionKick)
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.kick",
+					One:   "✅ {{.Count}} membro removido",
+					Other: "✅ {{.Count}} membros removidos",
+				},
+				PluralCount: len(targets),
+				TemplateData: map[string]any{
+					"Count": len(targets),
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"add"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets := slices.DeleteFunc(ctx.Targets(), func(jid types.JID) bool {
+				return ctx.IsParticipant(jid.User)
+			})
+			if len(targets) == 0 {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.add.already",
+						Other: "⚠️ Esse(s) número(s) já está(ão) no grupo",
+					},
+				}))
+				return nil
+			}
+			result, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, targets, whatsmeow.ParticipantChangeAdd)
+			if err != nil {
+				return err
+			}
+			var added, failed int
+			for _, p := range result {
+				if p.Error != 0 {
+					failed++
+				} else {
+					added++
+					logModeration(ctx, p.JID, database.ModActionAdd)
+				}
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.add",
+					Other: "✅ Adicionados: {{.Added}}\n❌ Falharam: {{.Failed}}",
+				},
+				TemplateData: map[string]any{
+					"Added":  added,
+					"Failed": failed,
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"promote"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets := slices.DeleteFunc(ctx.Targets(), func(jid types.JID) bool {
+				return !ctx.IsParticipant(jid.User) || ctx.IsParticipantAdmin(jid.User)
+			})
+			if len(targets) == 0 {
+				return nil
+			}
+			if _, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, targets, whatsmeow.ParticipantChangePromote); err != nil {
+				return err
+			}
+			for _, target := range targets {
+				logModeration(ctx, target, database.ModActionPromote)
+			}
+			ctx.ReactMessage(ctx.Msg, "✅")
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"demote"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets := slices.DeleteFunc(ctx.Targets(), func(jid types.JID) bool {
+				return !ctx.IsParticipantAdmin(jid.User) || jid.User == ctx.Client.Store.ID.User
+			})
+			if len(targets) == 0 {
+				return nil
+			}
+			if _, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, targets, whatsmeow.ParticipantChangeDemote); err != nil {
+				return err
+			}
+			for _, target := range targets {
+				logModeration(ctx, target, database.ModActionDemote)
+			}
+			ctx.ReactMessage(ctx.Msg, "✅")
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"warn", "adv"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			for _, target := range moderationTargets(ctx) {
+				participant, err := updateParticipant(ctx, target, func(p *database.GroupParticipant) {
+					p.WarnCount++
+				})
+				if err != nil {
+					return err
+				}
+				logModeration(ctx, target, database.ModActionWarn)
+
+				ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.warn",
+						Other: "⚠️ @{{.User}} recebeu uma advertência ({{.Count}}/{{.Max}})",
+					},
+					TemplateData: map[string]any{
+						"User":  target.User,
+						"Count": participant.WarnCount,
+						"Max":   database.MaxWarnCount,
+					},
+				}), &command.MessageOptions{
+					QuotedMessage: ctx.Msg,
+					MentionedJid:  []string{target.String()},
+				})
+
+				if participant.WarnCount >= database.MaxWarnCount {
+					if _, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, []types.JID{target}, whatsmeow.ParticipantChangeRemove); err != nil {
+						return err
+					}
+					ctx.DB.MU.Lock()
+					ctx.LogModeration(&database.ModerationLog{
+						GroupID:   ctx.Msg.Info.Chat.User,
+						TargetID:  target.User,
+						Action:    database.ModActionKick,
+						Reason:    database.ModReasonWarnLimit,
+						Automatic: true,
+					})
+					ctx.DB.MU.Unlock()
+				}
+			}
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"unwarn", "rmadv"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			for _, target := range ctx.Targets() {
+				participant, err := updateParticipant(ctx, target, func(p *database.GroupParticipant) {
+					if p.WarnCount > 0 {
+						p.WarnCount--
+					}
+				})
+				if err != nil {
+					return err
+				}
+				logModeration(ctx, target, database.ModActionUnwarn)
+				ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.unwarn",
+						Other: "✅ Uma advertência de @{{.User}} foi removida ({{.Count}}/{{.Max}})",
+					},
+					TemplateData: map[string]any{
+						"User":  target.User,
+						"Count": participant.WarnCount,
+						"Max":   database.MaxWarnCount,
+					},
+				}), &command.MessageOptions{
+					QuotedMessage: ctx.Msg,
+					MentionedJid:  []string{target.String()},
+				})
+			}
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"mute", "silenciar"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets, duration, ok := muteArgs(ctx)
+			if !ok {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.mute.usage",
+						Other: "ℹ️ Uso: `{{.Prefix}}mute @usuário [tempo]`, por exemplo `{{.Prefix}}mute @usuário 10m`",
+					},
+					TemplateData: map[string]any{
+						"Prefix": ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			until := time.Now().Add(duration)
+			for _, target := range targets {
+				if _, err := updateParticipant(ctx, target, func(p *database.GroupParticipant) {
+					p.MutedUntil = &until
+				}); err != nil {
+					return err
+				}
+				ctx.DB.MU.Lock()
+				err := ctx.ScheduleUnmute(ctx.Msg.Info.Chat, target.User, until)
+				ctx.DB.MU.Unlock()
+				if err != nil {
+					return err
+				}
+				logModeration(ctx, target, database.ModActionMute)
+				ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.mute",
+						Other: "🔇 @{{.User}} foi silenciado por {{.Duration}}. Suas mensagens serão apagadas.",
+					},
+					TemplateData: map[string]any{
+						"User":     target.User,
+						"Duration": util.FormatDuration(duration),
+					},
+				}), &command.MessageOptions{
+					QuotedMessage: ctx.Msg,
+					MentionedJid:  []string{target.String()},
+				})
+			}
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"unmute", "dessilenciar"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			for _, target := range ctx.Targets() {
+				if _, err := updateParticipant(ctx, target, func(p *database.GroupParticipant) {
+					p.MutedUntil = nil
+				}); err != nil {
+					return err
+				}
+				ctx.DB.MU.Lock()
+				err := ctx.CancelUnmute(ctx.Msg.Info.Chat, target.User)
+				ctx.DB.MU.Unlock()
+				if err != nil {
+					return err
+				}
+				logModeration(ctx, target, database.ModActionUnmute)
+				ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "moderation.unmute",
+						Other: "🔊 @{{.User}} pode voltar a enviar mensagens",
+					},
+					TemplateData: map[string]any{
+						"User": target.User,
+					},
+				}), &command.MessageOptions{
+					QuotedMessage: ctx.Msg,
+					MentionedJid:  []string{target.String()},
+				})
+			}
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"blacklist", "bl"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true, Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets := moderationTargets(ctx)
+			for _, target := range targets {
+				if _, err := updateParticipant(ctx, target, func(p *database.GroupParticipant) {
+					p.IsBlacklisted = true
+				}); err != nil {
+					return err
+				}
+				logModeration(ctx, target, database.ModActionBlacklist)
+			}
+			members := slices.DeleteFunc(slices.Clone(targets), func(jid types.JID) bool {
+				return !ctx.IsParticipant(jid.User)
+			})
+			if len(members) > 0 {
+				if _, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, members, whatsmeow.ParticipantChangeRemove); err != nil {
+					return err
+				}
+			}
+			if len(targets) > 0 {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.blacklist",
+						Other: "🚫 {{.Count}} usuário(s) adicionado(s) à lista negra",
+					},
+					TemplateData: map[string]any{
+						"Count": len(targets),
+					},
+				}))
+			}
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"unblacklist", "unbl"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			targets := ctx.Targets()
+			for _, target := range targets {
+				if _, err := updateParticipant(ctx, target, func(p *database.GroupParticipant) {
+					p.IsBlacklisted = false
+					p.WarnCount = 0
+				}); err != nil {
+					return err
+				}
+				logModeration(ctx, target, database.ModActionUnblacklist)
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.unblacklist",
+					Other: "✅ {{.Count}} usuário(s) removido(s) da lista negra",
+				},
+				TemplateData: map[string]any{
+					"Count": len(targets),
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"antilink"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			return toggleGroupSetting(ctx, func(g *database.Group) *bool { return &g.IsAntiLink }, "Antilink")
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"antiwalink", "antiinvite"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			return toggleGroupSetting(ctx, func(g *database.Group) *bool { return &g.IsAntiWALink }, "Anti WhatsApp link")
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"autoremove"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			return toggleGroupSetting(ctx, func(g *database.Group) *bool { return &g.RemoveUser }, "Autoremove")
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"links", "linkfilter"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			ctx.DB.MU.Lock()
+			defer ctx.DB.MU.Unlock()
+
+			group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+			if err != nil {
+				return err
+			}
+
+			fields := strings.Fields(strings.ToLower(ctx.Args))
+			if len(fields) == 0 {
+				none := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{ID: "cmd.links.none", Other: "nenhum"},
+				})
+				allow, block := group.LinkAllowlist, group.LinkBlocklist
+				if allow == "" {
+					allow = none
+				}
+				if block == "" {
+					block = none
+				}
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.links.list",
+						Other: "🔗 *Filtro de links*\n\n✅ Permitidos: {{.Allow}}\n🚫 Bloqueados: {{.Block}}\n\nℹ️ Uso: `{{.Prefix}}links [allow|block] [add|remove|clear] [domínios]`. Use `*.dominio.com` para incluir subdomínios e `shorteners` para encurtadores de links.",
+					},
+					TemplateData: map[string]any{
+						"Allow":  strings.ReplaceAll(allow, ",", ", "),
+						"Block":  strings.ReplaceAll(block, ",", ", "),
+						"Prefix": ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			var list *string
+			switch fields[0] {
+			case "allow", "allowlist":
+				list = &group.LinkAllowlist
+			case "block", "blocklist":
+				list = &group.LinkBlocklist
+			}
+			if list == nil || len(fields) < 2 || !slices.Contains([]string{"add", "remove", "rm", "del", "clear"}, fields[1]) {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.links.usage",
+						Other: "ℹ️ Uso: `{{.Prefix}}links [allow|block] [add|remove|clear] [domínios]`",
+					},
+					TemplateData: map[string]any{
+						"Prefix": ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			domains := moderation.DomainList(*list)
+			var values []string
+			for _, v := range fields[2:] {
+				for d := range strings.SplitSeq(v, ",") {
+					if d == util.ShortenersEntry {
+						values = append(values, d)
+					} else if d = util.NormalizeDomain(d); d != "" && d != "*." {
+						values = append(values, d)
+					}
+				}
+			}
+			switch fields[1] {
+			case "add":
+				for _, v := range values {
+					if !slices.Contains(domains, v) {
+						domains = append(domains, v)
+					}
+				}
+			case "remove", "rm", "del":
+				domains = slices.DeleteFunc(domains, func(d string) bool { return slices.Contains(values, d) })
+			case "clear":
+				domains = nil
+			}
+			*list = strings.Join(domains, ",")
+			if err := ctx.DB.SaveGroupInfo(group); err != nil {
+				return err
+			}
+			ctx.ReactMessage(ctx.Msg, "✅")
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"antiflood", "antispam"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			ctx.DB.MU.Lock()
+			defer ctx.DB.MU.Unlock()
+
+			group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+			if err != nil {
+				return err
+			}
+			flood := &group.Flood
+
+			fields := strings.Fields(strings.ToLower(ctx.Args))
+			if len(fields) == 0 {
+				state := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{ID: "state.disabled", Other: "desativado"},
+				})
+				if flood.Enabled {
+					state = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{ID: "state.enabled", Other: "ativado"},
+					})
+				}
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.antiflood.settings",
+						Other: "🌊 *Antiflood* {{.State}}\n\n💬 Mensagens: {{.Messages}} em {{.Window}}\n🔁 Mensagens repetidas: {{.Repeats}}\n🖼️ Mídias: {{.Media}}\n🔇 Silenciamento: {{.Mute}}\n\n⚖️ Punições: advertência → silenciamento → remoção\n\nℹ️ Uso: `{{.Prefix}}antiflood [on|off|messages N tempo|repeats N|media N|mute tempo]`",
+					},
+					TemplateData: map[string]any{
+						"State":    state,
+						"Messages": flood.Messages,
+						"Window":   util.FormatDuration(flood.Window()),
+						"Repeats":  flood.Repeats,
+						"Media":    flood.Media,
+						"Mute":     util.FormatDuration(flood.MuteDuration),
+						"Prefix":   ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			var valid bool
+			switch {
+			case fields[0] == "messages" && len(fields) == 3:
+				n, err := strconv.Atoi(fields[1])
+				window, errWindow := util.ParseDuration(fields[2])
+				if valid = err == nil && errWindow == nil && n > 1 && window >= time.Second; valid {
+					flood.Messages = n
+					flood.WindowSeconds = int(window / time.Second)
+				}
+			case (fields[0] == "repeats" || fields[0] == "media") && len(fields) == 2:
+				// 0 turns the check off, and a single message can't be a flood
+				n, err := strconv.Atoi(fields[1])
+				if valid = err == nil && (n == 0 || n >= 2); valid {
+					if fields[0] == "repeats" {
+						flood.Repeats = n
+					} else {
+						flood.Media = n
+					}
+				}
+			case fields[0] == "mute" && len(fields) == 2:
+				d, err := util.ParseDuration(fields[1])
+				if valid = err == nil && d > 0; valid {
+					flood.MuteDuration = d
+				}
+			case len(fields) == 1:
+				flood.Enabled, valid = util.ParseToggle(fields[0], flood.Enabled)
+			}
+			if !valid {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.antiflood.usage",
+						Other: "ℹ️ Uso: `{{.Prefix}}antiflood [on|off|messages N tempo|repeats N|media N|mute tempo]`",
+					},
+					TemplateData: map[string]any{
+						"Prefix": ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			if err := ctx.DB.SaveGroupInfo(group); err != nil {
+				return err
+			}
+			ctx.ReactMessage(ctx.Msg, "✅")
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"captcha"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true},
+		Run: func(ctx *command.CommandContext) error {
+			ctx.DB.MU.Lock()
+			defer ctx.DB.MU.Unlock()
+
+			group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+			if err != nil {
+				return err
+			}
+			captcha := &group.Captcha
+
+			fields := strings.Fields(strings.ToLower(ctx.Args))
+			if len(fields) == 0 {
+				state := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{ID: "state.disabled", Other: "desativado"},
+				})
+				if captcha.Enabled {
+					state = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{ID: "state.enabled", Other: "ativado"},
+					})
+				}
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.captcha.settings",
+						Other: "🤖 *Captcha* {{.State}}\n\n🧩 Modo: {{.Mode}}\n📈 Dificuldade: {{.Difficulty}}/3\n⏳ Tempo para responder: {{.Timeout}}\n\nℹ️ Uso: `{{.Prefix}}captcha [on|off|mode math|image|difficulty 1-3|timeout tempo]`",
+					},
+					TemplateData: map[string]any{
+						"State":      state,
+						"Mode":       captcha.Mode,
+						"Difficulty": captcha.Difficulty,
+						"Timeout":    util.FormatDuration(captcha.Timeout()),
+						"Prefix":     ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			var valid bool
+			switch {
+			case fields[0] == "mode" && len(fields) == 2:
+				if valid = fields[1] == moderation.CaptchaMath || fields[1] == moderation.CaptchaImage; valid {
+					captcha.Mode = fields[1]
+				}
+			case fields[0] == "difficulty" && len(fields) == 2:
+				n, err := strconv.Atoi(fields[1])
+				if valid = err == nil && n >= 1 && n <= 3; valid {
+					captcha.Difficulty = n
+				}
+			case fields[0] == "timeout" && len(fields) == 2:
+				d, err := util.ParseDuration(fields[1])
+				if valid = err == nil && d >= 30*time.Second && d <= 24*time.Hour; valid {
+					captcha.TimeoutSeconds = int(d / time.Second)
+				}
+			case len(fields) == 1:
+				captcha.Enabled, valid = util.ParseToggle(fields[0], captcha.Enabled)
+			}
+			if !valid {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.captcha.usage",
+						Other: "ℹ️ Uso: `{{.Prefix}}captcha [on|off|mode math|image|difficulty 1-3|timeout tempo]`",
+					},
+					TemplateData: map[string]any{
+						"Prefix": ctx.Prefix,
+					},
+				}))
+				return nil
+			}
+
+			if err := ctx.DB.SaveGroupInfo(group); err != nil {
+				return err
+			}
+			ctx.ReactMessage(ctx.Msg, "✅")
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"welcome", "bemvindo"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			return configureGreeting(ctx, func(g *database.Group) *database.GreetingSettings { return &g.Welcome }, false)
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"goodbye", "adeus"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			return configureGreeting(ctx, func(g *database.Group) *database.GreetingSettings { return &g.Goodbye }, true)
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"card", "cartao"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			action, arg, _ := strings.Cut(strings.ToLower(strings.TrimSpace(ctx.Args)), " ")
+			arg = strings.TrimSpace(arg)
+
+			// The background is downloaded before taking the lock
+			var background []byte
+			if action == "background" || action == "fundo" {
+				image, err := ctx.DownloadMedia(command.MediaImage, maxImageInput)
+				if errors.Is(err, command.ErrNoMedia) {
+					ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{
+							ID:    "cmd.card.noimage",
+							Other: "❌ Envie ou responda uma imagem com `{{.Prefix}}{{.Command}} {{.Action}}`",
+						},
+						TemplateData: map[string]any{
+							"Prefix":  ctx.Prefix,
+							"Command": ctx.Command,
+							"Action":  action,
+						},
+					}))
+					return nil
+				}
+				if errors.Is(err, command.ErrMediaTooLarge) {
+					ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{
+							ID:    "cmd.image.toolarge",
+							Other: "❌ A imagem é grande demais, o limite é {{.Max}} MB",
+						},
+						TemplateData: map[string]any{
+							"Max": maxImageInput >> 20,
+						},
+					}))
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				background, err = media.ResizeImg(image.Data, cardBackgroundSize, cardBackgroundSize)
+				if errors.Is(err, media.ErrImageTooLarge) {
+					ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{
+							ID:    "cmd.image.toomanypixels",
+							Other: "❌ A imagem tem pixels demais, o limite é {{.Max}} megapixels",
+						},
+						TemplateData: map[string]any{
+							"Max": media.MaxImagePixels / 1_000_000,
+						},
+					}))
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			ctx.DB.MU.Lock()
+			defer ctx.DB.MU.Unlock()
+
+			group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case background != nil:
+				if err := ctx.DB.SaveCardBackground(&database.CardBackground{GroupID: group.ID, Data: background}); err != nil {
+					return err
+				}
+			case (action == "color" || action == "cor") && arg != "":
+				if _, err := media.ParseHexColor(arg); err != nil {
+					ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{
+							ID:    "cmd.card.invalidcolor",
+							Other: "❌ Cor inválida. Use o formato hexadecimal, por exemplo `#1e3a5f`",
+						},
+					}))
+					return nil
+				}
+				group.CardColor = "#" + strings.TrimPrefix(arg, "#")
+				if err := ctx.DB.SaveGroupInfo(group); err != nil {
+					return err
+				}
+			case action == "reset":
+				if err := ctx.DB.DeleteCardBackground(group.ID); err != nil {
+					return err
+				}
+				group.CardColor = ""
+				if err := ctx.DB.SaveGroupInfo(group); err != nil {
+					return err
+				}
+			default:
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.card.usage",
+						Other: "🖼️ Personalize o cartão das mensagens de boas-vindas e despedida.\n\nℹ️ Uso: `{{.Prefix}}{{.Command}} [color #hex|background|reset]`\nPara usar uma imagem de fundo, envie ou responda uma imagem com `{{.Prefix}}{{.Command}} background`.\nAtive o cartão com `{{.Prefix}}welcome image on`.",
+					},
+					TemplateData: map[string]any{
+						"Prefix":  ctx.Prefix,
+						"Command": ctx.Command,
+					},
+				}))
+				return nil
+			}
+			ctx.ReactMessage(ctx.Msg, "✅")
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"modlog", "logs"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			fields := strings.Fields(strings.ToLower(ctx.Args))
+			if len(fields) > 0 && fields[0] == "chat" {
+				return setModLogChat(ctx, strings.TrimSpace(ctx.Args[len("chat"):]))
+			}
+
+			filter := database.ModerationLogFilter{Limit: modLogPageSize}
+			page := 1
+			if targets := ctx.Targets(); len(targets) > 0 {
+				filter.TargetID = targets[0].User
+			}
+			for _, f := range strings.Fields(strings.ToLower(ctx.ArgsWithoutTargets())) {
+				switch f {
+				case "auto":
+					filter.Automatic = proto.Bool(true)
+				case "manual":
+					filter.Automatic = proto.Bool(false)
+				case database.ModActionKick, database.ModActionAdd, database.ModActionPromote, database.ModActionDemote,
+					database.ModActionWarn, database.ModActionUnwarn, database.ModActionBlacklist, database.ModActionUnblacklist,
+					database.ModActionDelete, database.ModActionMute, database.ModActionUnmute:
+					filter.Action = f
+				default:
+					if n, err := strconv.Atoi(f); err == nil && n > 0 {
+						page = n
+					}
+				}
+			}
+			filter.Offset = (page - 1) * modLogPageSize
+
+			entries, total, err := ctx.DB.GetModerationLogs(ctx.Msg.Info.Chat.User, filter)
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.modlog.empty",
+						Other: "📭 Nenhum registro de moderação encontrado",
+					},
+				}))
+				return nil
+			}
+
+			pages := (int(total) + modLogPageSize - 1) / modLogPageSize
+			var b strings.Builder
+			b.WriteString(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.modlog.header",
+					Other: "🛡️ *Registro de moderação* (página {{.Page}}/{{.Pages}}, {{.Total}} registros)",
+				},
+				TemplateData: map[string]any{
+					"Page":  page,
+					"Pages": pages,
+					"Total": total,
+				},
+			}))
+			var mentions []string
+			for _, e := range entries {
+				b.WriteString("\n\n")
+				b.WriteString(ctx.FormatModerationLog(&e, ctx.GroupMetadata.Name))
+				mentions = append(mentions, types.NewJID(e.TargetID, types.DefaultUserServer).String())
+				if e.ActorID != "" {
+					mentions = append(mentions, types.NewJID(e.ActorID, types.DefaultUserServer).String())
+				}
+			}
+			ctx.SendTextMessage(ctx.Msg.Info.Chat, b.String(), &command.MessageOptions{
+				QuotedMessage: ctx.Msg,
+				MentionedJid:  mentions,
+			})
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"rule", "rules", "regra"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			ctx.DB.MU.Lock()
+			defer ctx.DB.MU.Unlock()
+
+			action, args, _ := strings.Cut(strings.TrimSpace(ctx.Args), " ")
+			args = strings.TrimSpace(args)
+			switch strings.ToLower(action) {
+			case "", "list":
+				rules, err := ctx.DB.GetModerationRules(ctx.Msg.Info.Chat.User)
+				if err != nil {
+					return err
+				}
+				if len(rules) == 0 {
+					ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{
+							ID:    "cmd.rule.empty",
+							Other: "📭 Nenhuma regra de moderação personalizada. Use `{{.Prefix}}rule add` para criar uma.",
+						},
+						TemplateData: map[string]any{
+							"Prefix": ctx.Prefix,
+						},
+					}))
+					return nil
+				}
+				var b strings.Builder
+				b.WriteString(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.rule.header",
+						Other: "🛡️ *Regras de moderação*",
+					},
+				}))
+				for _, rule := range rules {
+					status := "✅"
+					if !rule.Enabled {
+						status = "⏸️"
+					} else if rule.DryRun {
+						status = "🧪"
+					}
+					fmt.Fprintf(&b, "\n\n%s *#%d* `%s`", status, rule.ID, moderation.FormatRule(&rule))
+				}
+				ctx.Reply(b.String())
+				return nil
+
+			case "add":
+				rule, err := moderation.ParseRule(args)
+				if err != nil {
+					ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+						DefaultMessage: &i18n.Message{
+							ID:    "cmd.rule.invalid",
+							Other: "❌ Regra inválida: {{.Error}}\n\nℹ️ Exemplo: `{{.Prefix}}rule add pix regex=\"(?i)pix\" types=image,video actions=delete,mute mute=10m exempt=admins,premium`\n\n*Condições:* regex, url, invite, domains, mentions, massmention, types, caption, forwarded, newmember\n*Ações:* {{.Actions}}",
+						},
+						TemplateData: map[string]any{
+							"Error":   err.Error(),
+							"Prefix":  ctx.Prefix,
+							"Actions": strings.Join(moderation.Actions, ", "),
+						},
+					}))
+					return nil
+				}
+				rule.GroupID = ctx.Msg.Info.Chat.User
+				if err := ctx.DB.SaveModerationRule(rule); err != nil {
+					return err
+				}
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.rule.added",
+						Other: "✅ Regra *#{{.ID}}* criada: `{{.Rule}}`",
+					},
+					TemplateData: map[string]any{
+						"ID":   rule.ID,
+						"Rule": moderation.FormatRule(rule),
+					},
+				}))
+				return nil
+
+			case "remove", "rm", "del", "enable", "disable", "dryrun":
+				idArg, value, _ := strings.Cut(args, " ")
+				id, err := strconv.ParseUint(strings.TrimPrefix(idArg, "#"), 10, 32)
+				if err != nil {
+					break
+				}
+				rule, err := ctx.DB.GetModerationRule(ctx.Msg.Info.Chat.User, uint32(id))
+				if err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+							DefaultMessage: &i18n.Message{
+								ID:    "cmd.rule.notfound",
+								Other: "❌ Regra #{{.ID}} não encontrada",
+							},
+							TemplateData: map[string]any{
+								"ID": id,
+							},
+						}))
+						return nil
+					}
+					return err
+				}
+				switch strings.ToLower(action) {
+				case "enable":
+					rule.Enabled = true
+				case "disable":
+					rule.Enabled = false
+				case "dryrun":
+					rule.DryRun, _ = util.ParseToggle(value, rule.DryRun)
+				default:
+					if err := ctx.DB.DeleteModerationRule(rule); err != nil {
+						return err
+					}
+					ctx.ReactMessage(ctx.Msg, "🗑️")
+					return nil
+				}
+				if err := ctx.DB.SaveModerationRule(rule); err != nil {
+					return err
+				}
+				ctx.ReactMessage(ctx.Msg, "✅")
+				return nil
+			}
+
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.rule.usage",
+					Other: "ℹ️ Uso: `{{.Prefix}}rule [list|add|remove|enable|disable|dryrun] [regra|id]`",
+				},
+				TemplateData: map[string]any{
+					"Prefix": ctx.Prefix,
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"open", "abrir"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true},
+		Run: func(ctx *command.CommandContext) error {
+			if err := ctx.Client.SetGroupAnnounce(ctx.Msg.Info.Chat, false); err != nil {
+				return err
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.open",
+					Other: "🔓 Grupo aberto! Todos os membros podem enviar mensagens.",
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"close", "fechar"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true},
+		Run: func(ctx *command.CommandContext) error {
+			if err := ctx.Client.SetGroupAnnounce(ctx.Msg.Info.Chat, true); err != nil {
+				return err
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.close",
+					Other: "🔒 Grupo fechado! Apenas administradores podem enviar mensagens.",
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"night", "noturno"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true},
+		Run:     configureNightMode,
+	})
+}
+
+// configureNightMode sets the daily schedule that closes the group at night.
+// The schedule is applied by a background task within a minute.
+func configureNightMode(ctx *command.CommandContext) error {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return err
+	}
+	night := &group.Night
+
+	args := strings.Fields(ctx.Args)
+	switch {
+	case len(args) == 1 && strings.EqualFold(args[0], "on"):
+		night.Enabled = true
+	case len(args) == 1 && strings.EqualFold(args[0], "off"):
+		night.Enabled = false
+	case len(args) == 2 && (strings.EqualFold(args[0], "timezone") || strings.EqualFold(args[0], "fuso")):
+		if _, err := time.LoadLocation(args[1]); err != nil || args[1] == "Local" {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.night.timezone.invalid",
+					Other: "❌ Fuso horário inválido: {{.Timezone}}. Use nomes como `America/Sao_Paulo` ou `Europe/Lisbon`.",
+				},
+				TemplateData: map[string]any{
+					"Timezone": args[1],
+				},
+			}))
+			return nil
+		}
+		night.Timezone = args[1]
+	case len(args) == 2:
+		closeAt, errClose := util.ParseClock(args[0])
+		openAt, errOpen := util.ParseClock(args[1])
+		if errClose != nil || errOpen != nil || closeAt == openAt {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.night.schedule.invalid",
+					Other: "❌ Horários inválidos. Use `{{.Prefix}}{{.Command}} 22:00 07:00` para fechar às 22h e abrir às 7h.",
+				},
+				TemplateData: map[string]any{
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+				},
+			}))
+			return nil
+		}
+		night.CloseAt, night.OpenAt = closeAt, openAt
+		night.Enabled = true
+	default:
+		state := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "state.disabled", Other: "desativado"},
+		})
+		if night.Enabled {
+			state = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{ID: "state.enabled", Other: "ativado"},
+			})
+		}
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.night.status",
+				Other: "🌙 *Modo noturno* {{.State}}\n\n🔒 Fecha às {{.Close}}\n🔓 Abre às {{.Open}}\n🌍 Fuso horário: {{.Timezone}}\n\nℹ️ Uso: `{{.Prefix}}{{.Command}} [on|off|fecha abre|timezone fuso]`\nExemplo: `{{.Prefix}}{{.Command}} 22:00 07:00`",
+			},
+			TemplateData: map[string]any{
+				"State":    state,
+				"Close":    util.FormatClock(night.CloseAt),
+				"Open":     util.FormatClock(night.OpenAt),
+				"Timezone": night.Timezone,
+				"Prefix":   ctx.Prefix,
+				"Command":  ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	if err := ctx.DB.SaveGroupInfo(group); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
+
+// moderationTargets filters out the bot, the bot owners and group admins from
+// the command targets, warning the sender about the skipped users.
+// muteArgs returns the users to mute and for how long, the first argument
+// that isn't a target. ok is false if the duration is invalid.
+func muteArgs(ctx *command.CommandContext) (targets []types.JID, duration time.Duration, ok bool) {
+	duration = defaultMuteDuration
+	if fields := strings.Fields(ctx.ArgsWithoutTargets()); len(fields) > 0 {
+		d, err := util.ParseDuration(fields[0])
+		if err != nil || d <= 0 {
+			return nil, 0, false
+		}
+		duration = d
+	}
+	return moderationTargets(ctx), duration, true
+}
+
+func moderationTargets(ctx *command.CommandContext) []types.JID {
+	var skipped int
+	targets := slices.DeleteFunc(ctx.Targets(), func(jid types.JID) bool {
+		protected := jid.User == ctx.Client.Store.ID.User ||
+			slices.Contains(ctx.Config.OwnerNumbers, jid.User) ||
+			ctx.IsParticipantAdmin(jid.User)
+		if protected {
+			skipped++
+		}
+		return protected
+	})
+	if skipped > 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.moderation.protected",
+				Other: "⚠️ Administradores, meu dono e eu não podemos ser punidos",
+			},
+		}))
+	}
+	return targets
+}
+
+func logModeration(ctx *command.CommandContext, target types.JID, action string) {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	ctx.LogModeration(&database.ModerationLog{
+		GroupID:  ctx.Msg.Info.Chat.User,
+		ActorID:  ctx.Msg.Info.Sender.User,
+		TargetID: target.User,
+		Action:   action,
+		Reason:   ctx.ArgsWithoutTargets(),
+		Excerpt:  ctx.QuotedText(),
+	})
+}
+
+func updateParticipant(ctx *command.CommandContext, jid types.JID, update func(p *database.GroupParticipant)) (*database.GroupParticipant, error) {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	if _, err := ctx.DB.GetUserInfo(jid.User); err != nil {
+		return nil, err
+	}
+	participant, err := ctx.DB.GetParticipant(jid.User, ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return nil, err
+	}
+	update(participant)
+	return participant, ctx.DB.SaveParticipant(participant)
+}
+
+func toggleGroupSetting(ctx *command.CommandContext, field func(g *database.Group) *bool, name string) error {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return err
+	}
+	value, ok := util.ParseToggle(ctx.Args, *field(group))
+	if !ok {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.toggle.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [on|off]`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+	*field(group) = value
+	if err := ctx.DB.SaveGroupInfo(group); err != nil {
+		return fmt.Errorf("saving %s setting: %w", name, err)
+	}
+
+	var state string
+	if value {
+		state = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "state.enabled", Other: "ativado"},
+		})
+	} else {
+		state = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "state.disabled", Other: "desativado"},
+		})
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.toggle",
+			Other: "⚙️ {{.Setting}} {{.State}}",
+		},
+		TemplateData: map[string]any{
+			"Setting": name,
+			"State":   state,
+		},
+	}))
+	return nil
+}
+
+func setModLogChat(ctx *command.CommandContext, arg string) error {
+	var chat string
+	switch keyword := strings.ToLower(arg); {
+	case keyword == "off":
+	case keyword == "here":
+		chat = ctx.Msg.Info.Chat.String()
+	case keyword == "me":
+		chat = ctx.Msg.Info.Sender.ToNonAD().String()
+	case util.MatchWaUrl(arg):
+		code := arg[strings.LastIndex(arg, "/")+1:]
+		info, err := ctx.Client.GetGroupInfoFromLink(code)
+		if err != nil {
+			return err
+		}
+		// The log may only go to a group the bot is in and the sender manages,
+		// otherwise anyone could leak the moderation of this group elsewhere
+		target, err := ctx.Client.GetGroupInfo(info.JID)
+		if err != nil {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.modlog.chat.notmember",
+					Other: "❌ Eu preciso estar nesse grupo para enviar o registro de moderação nele",
+				},
+			}))
+			return nil
+		}
+		sender := ctx.Msg.Info.Sender.User
+		if !slices.ContainsFunc(target.Participants, func(p types.GroupParticipant) bool {
+			return (p.JID.User == sender || p.PhoneNumber.User == sender) && (p.IsAdmin || p.IsSuperAdmin)
+		}) {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.modlog.chat.notadmin",
+					Other: "❌ Você precisa ser administrador desse grupo para enviar o registro de moderação nele",
+				},
+			}))
+			return nil
+		}
+		chat = target.JID.String()
+	default:
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.modlog.chat.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}modlog chat [here|me|off|link do grupo]`",
+			},
+			TemplateData: map[string]any{
+				"Prefix": ctx.Prefix,
+			},
+		}))
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return err
+	}
+	group.ModLogChat = chat
+	if err := ctx.DB.SaveGroupInfo(group); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
+
+// configureGreeting implements the welcome and goodbye commands, which share
+// their subcommands and only differ in the settings they change.
+func configureGreeting(ctx *command.CommandContext, field func(g *database.Group) *database.GreetingSettings, leaving bool) error {
+	// The template may start on a new line, so split on any whitespace
+	action, args := strings.TrimSpace(ctx.Args), ""
+	if i := strings.IndexFunc(action, unicode.IsSpace); i >= 0 {
+		action, args = action[:i], action[i:]
+	}
+	action = strings.ToLower(action)
+
+	// The preview may download a profile picture, so these run without the lock
+	switch action {
+	case "":
+		settings := field(ctx.GroupInfo)
+		template := settings.Message
+		if template == "" {
+			template = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{ID: "cmd.greeting.default", Other: "(padrão)"},
+			})
+		}
+		state := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "state.disabled", Other: "desativado"},
+		})
+		if settings.Enabled {
+			state = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{ID: "state.enabled", Other: "ativado"},
+			})
+		}
+		image := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "state.disabled", Other: "desativado"},
+		})
+		if settings.Image {
+			image = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{ID: "state.enabled", Other: "ativado"},
+			})
+		}
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.greeting.settings",
+				Other: "👋 *{{.Command}}* {{.State}}\n🖼️ Cartão: {{.Image}}\n\n📝 Mensagem:\n{{.Template}}\n\n🔖 Variáveis: $name, $mention, $group, $count, $desc, $date, $hour\n\nℹ️ Uso: `{{.Prefix}}{{.Command}} [on|off|set texto|reset|image on|off|preview]`",
+			},
+			TemplateData: map[string]any{
+				"Command":  ctx.Command,
+				"State":    state,
+				"Image":    image,
+				"Template": template,
+				"Prefix":   ctx.Prefix,
+			},
+		}))
+		return nil
+	case "preview":
+		ctx.SendGreeting(ctx.GroupMetadata, field(ctx.GroupInfo), ctx.Msg.Info.Sender, ctx.Msg.Info.PushName, leaving)
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return err
+	}
+	settings := field(group)
+
+	var valid bool
+	switch action {
+	case "set":
+		args = strings.TrimSpace(args)
+		if valid = args != "" && len([]rune(args)) <= maxGreetingLength; valid {
+			settings.Message = args
+			settings.Enabled = true
+		}
+	case "reset":
+		settings.Message = ""
+		valid = true
+	case "image", "imagem":
+		settings.Image, valid = util.ParseToggle(args, settings.Image)
+	default:
+		if strings.TrimSpace(args) == "" {
+			settings.Enabled, valid = util.ParseToggle(action, settings.Enabled)
+		}
+	}
+	if !valid {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.greeting.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [on|off|set texto|reset|image on|off|preview]`\nA mensagem pode ter até {{.Max}} caracteres.",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+				"Max":     maxGreetingLength,
+			},
+		}))
+		return nil
+	}
+
+	if err := ctx.DB.SaveGroupInfo(group); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
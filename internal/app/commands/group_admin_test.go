@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"meowabot/internal/command"
+	"meowabot/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// testGroupCommand returns the context of a group command with the given
+// args that mentions the users.
+func testGroupCommand(args string, mentions ...types.JID) *command.CommandContext {
+	mentioned := make([]string, len(mentions))
+	for n, jid := range mentions {
+		mentioned[n] = jid.String()
+	}
+	bot := types.NewJID("5511000000000", types.DefaultUserServer)
+	return &command.CommandContext{
+		Client: &whatsmeow.Client{Store: &store.Device{ID: &bot}},
+		Config: &config.ConfigScheme{},
+		Args:   args,
+		Msg: &events.Message{Message: &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text:        proto.String("!mute " + args),
+			ContextInfo: &waE2E.ContextInfo{MentionedJID: mentioned},
+		}}},
+		GroupMetadata: &types.GroupInfo{},
+	}
+}
+
+func TestMuteArgs(t *testing.T) {
+	user := types.NewJID("5511999999999", types.DefaultUserServer)
+
+	targets, duration, ok := muteArgs(testGroupCommand("@5511999999999 10m", user))
+	assert.True(t, ok)
+	assert.Equal(t, []types.JID{user}, targets)
+	assert.Equal(t, 10*time.Minute, duration)
+
+	targets, duration, ok = muteArgs(testGroupCommand("@5511999999999 2h spam", user))
+	assert.True(t, ok)
+	assert.Equal(t, []types.JID{user}, targets)
+	assert.Equal(t, 2*time.Hour, duration)
+
+	targets, duration, ok = muteArgs(testGroupCommand("@5511999999999", user))
+	assert.True(t, ok)
+	assert.Equal(t, []types.JID{user}, targets)
+	assert.Equal(t, defaultMuteDuration, duration)
+
+	_, _, ok = muteArgs(testGroupCommand("@5511999999999 soon", user))
+	assert.False(t, ok)
+}
@@ -0,0 +1,340 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/scheduler"
+	"meowabot/internal/util"
+	"strconv"
+	"strings"
+	"time"
+
+	tmsg "meowabot/internal/tools/messages"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+const (
+	// maxAnnouncements is how many scheduled announcements a group can have.
+	maxAnnouncements = 20
+	// minAnnouncementInterval is the shortest interval between the posts of a
+	// recurring announcement.
+	minAnnouncementInterval = time.Hour
+)
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"announce", "anunciar", "anuncios"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			action, args, _ := strings.Cut(strings.TrimSpace(ctx.Args), " ")
+			switch strings.ToLower(action) {
+			case "", "list", "lista":
+				return listAnnouncements(ctx)
+			case "add", "criar":
+				return createAnnouncement(ctx, args, false)
+			case "every", "repetir":
+				return createAnnouncement(ctx, args, true)
+			case "preview", "ver":
+				return previewAnnouncement(ctx, strings.TrimSpace(args))
+			case "delete", "rm", "apagar":
+				return deleteAnnouncements(ctx, strings.TrimSpace(args))
+			}
+			replyAnnouncementUsage(ctx)
+			return nil
+		},
+	})
+}
+
+func replyAnnouncementUsage(ctx *command.CommandContext) {
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.announce.usage",
+			Other: "ℹ️ Uso:\n`{{.Prefix}}{{.Command}} add [quando] [mensagem]`\n`{{.Prefix}}{{.Command}} every [recorrência] [mensagem]`\n`{{.Prefix}}{{.Command}} [list|preview número|delete número|delete all]`\n\nExemplos:\n• `{{.Prefix}}{{.Command}} add amanhã 9h bom dia!`\n• `{{.Prefix}}{{.Command}} every weekly segunda 9h leiam as regras`\n• `{{.Prefix}}{{.Command}} every daily 20:00` respondendo uma imagem\n• `{{.Prefix}}{{.Command}} every \"0 12 1 * *\" mensalidade`\n\nRecorrências: `hourly`, `daily HH:MM`, `weekly dia HH:MM`, `monthly dia HH:MM` ou uma expressão cron entre aspas.\n🌍 Horários no fuso {{.Timezone}}, use `{{.Prefix}}fuso` para mudar.",
+		},
+		TemplateData: map[string]any{
+			"Prefix":   ctx.Prefix,
+			"Command":  ctx.Command,
+			"Timezone": ctx.UserInfo.Location().String(),
+		},
+	}))
+}
+
+// announcementSource returns the message to announce: the quoted message, or
+// the command message itself if it has media.
+func announcementSource(ctx *command.CommandContext) *waE2E.Message {
+	if quoted := tmsg.GetContextInfo(ctx.Msg.Message).GetQuotedMessage(); quoted != nil {
+		return quoted
+	}
+	switch tmsg.GetMessageType(ctx.Msg.Message) {
+	case "image", "video", "gif", "document", "audio", "ptt", "sticker":
+		return ctx.Msg.Message
+	}
+	return &waE2E.Message{}
+}
+
+func createAnnouncement(ctx *command.CommandContext, args string, recurring bool) error {
+	loc := ctx.UserInfo.Location()
+	now := time.Now().In(loc)
+
+	var at time.Time
+	var cron, text string
+	var err error
+	if recurring {
+		cron, text, err = scheduler.ParseRecurrence(args)
+		if err == nil {
+			err = checkAnnouncementInterval(cron, loc.String(), now)
+		}
+	} else {
+		at, text, err = util.ParseWhen(args, now)
+	}
+	switch {
+	case errors.Is(err, util.ErrPastTime):
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.past",
+				Other: "❌ Esse horário já passou",
+			},
+		}))
+		return nil
+	case errors.Is(err, errAnnouncementTooOften):
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.tooOften",
+				Other: "❌ Anúncios podem se repetir no máximo de hora em hora",
+			},
+		}))
+		return nil
+	case err != nil:
+		replyAnnouncementUsage(ctx)
+		return nil
+	}
+
+	payload, err := ctx.NewAnnouncement(announcementSource(ctx), text)
+	if errors.Is(err, command.ErrAnnouncementTooLarge) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.tooLarge",
+				Other: "❌ A mídia é grande demais, o limite é {{.Max}} MB",
+			},
+			TemplateData: map[string]any{
+				"Max": command.MaxAnnouncementMedia >> 20,
+			},
+		}))
+		return nil
+	}
+	if err != nil {
+		ctx.Log.Warn().Err(err).Msg("Error creating announcement")
+		replyAnnouncementUsage(ctx)
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	jobs, err := ctx.DB.GetChatJobs(command.JobAnnouncement, ctx.Msg.Info.Chat.String())
+	if err != nil {
+		return err
+	}
+	if len(jobs) >= maxAnnouncements {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.limit",
+				Other: "❌ O grupo já tem {{.Max}} anúncios agendados",
+			},
+			TemplateData: map[string]any{
+				"Max": maxAnnouncements,
+			},
+		}))
+		return nil
+	}
+	var timezone string
+	if recurring {
+		timezone = loc.String()
+	}
+	job, err := ctx.ScheduleAnnouncement(payload, at, cron, timezone)
+	if err != nil {
+		return err
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.announce.created",
+			Other: "📣 Anúncio agendado para {{.Time}} (daqui a {{.In}})",
+		},
+		TemplateData: map[string]any{
+			"Time": job.RunAt.In(loc).Format(reminderTimeLayout),
+			"In":   util.FormatDuration(job.RunAt.Sub(now).Round(time.Minute)),
+		},
+	}))
+	return nil
+}
+
+var errAnnouncementTooOften = errors.New("announcement repeats too often")
+
+// checkAnnouncementInterval rejects cron expressions that would post more
+// often than minAnnouncementInterval.
+func checkAnnouncementInterval(cron string, timezone string, now time.Time) error {
+	job := &database.Job{Cron: cron, Timezone: timezone}
+	first, err := scheduler.NextRun(job, now)
+	if err != nil {
+		return err
+	}
+	second, err := scheduler.NextRun(job, first)
+	if err != nil {
+		return err
+	}
+	if second.Sub(first) < minAnnouncementInterval {
+		return errAnnouncementTooOften
+	}
+	return nil
+}
+
+// groupAnnouncements returns the announcements of the group with their
+// payloads. Must be called with the database lock held.
+func groupAnnouncements(ctx *command.CommandContext) ([]database.Job, []command.AnnouncementPayload, error) {
+	jobs, err := ctx.DB.GetChatJobs(command.JobAnnouncement, ctx.Msg.Info.Chat.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	payloads := make([]command.AnnouncementPayload, len(jobs))
+	for n, job := range jobs {
+		if err := json.Unmarshal([]byte(job.Payload), &payloads[n]); err != nil {
+			return nil, nil, err
+		}
+	}
+	return jobs, payloads, nil
+}
+
+func listAnnouncements(ctx *command.CommandContext) error {
+	ctx.DB.MU.Lock()
+	jobs, payloads, err := groupAnnouncements(ctx)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.empty",
+				Other: "📭 Nenhum anúncio agendado neste grupo",
+			},
+		}))
+		return nil
+	}
+
+	loc := ctx.UserInfo.Location()
+	var b strings.Builder
+	for n, job := range jobs {
+		fmt.Fprintf(&b, "\n%d. %s · %s", n+1, job.RunAt.In(loc).Format(reminderTimeLayout), payloads[n].Kind())
+		if job.Cron != "" {
+			fmt.Fprintf(&b, " · 🔁 `%s`", job.Cron)
+		}
+		if message, err := payloads[n].ParseMessage(); err == nil {
+			if text, _ := tmsg.GetMessageText(message); text != "" {
+				fmt.Fprintf(&b, " · %s", util.Truncate(text, 60))
+			}
+		}
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.announce.list",
+			Other: "📣 *Anúncios agendados* ({{.Count}}){{.List}}\n\nℹ️ Use `{{.Prefix}}{{.Command}} preview 1` ou `{{.Prefix}}{{.Command}} delete 1`",
+		},
+		TemplateData: map[string]any{
+			"Count":   len(jobs),
+			"List":    b.String(),
+			"Prefix":  ctx.Prefix,
+			"Command": ctx.Command,
+		},
+	}))
+	return nil
+}
+
+// parseAnnouncementIndex parses the number of an announcement in the list,
+// replying if it is invalid.
+func parseAnnouncementIndex(ctx *command.CommandContext, arg string, count int) (int, bool) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > count {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.invalid",
+				Other: "❌ Anúncio inválido: {{.Index}}. Use `{{.Prefix}}{{.Command}}` para ver a lista.",
+			},
+			TemplateData: map[string]any{
+				"Index":   arg,
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return 0, false
+	}
+	return n - 1, true
+}
+
+func previewAnnouncement(ctx *command.CommandContext, arg string) error {
+	ctx.DB.MU.Lock()
+	jobs, payloads, err := groupAnnouncements(ctx)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+	n, ok := parseAnnouncementIndex(ctx, arg, len(jobs))
+	if !ok {
+		return nil
+	}
+	ctx.DB.MU.Lock()
+	err = payloads[n].LoadMedia(ctx.DB)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+	return ctx.SendAnnouncement(ctx.Msg.Info.Chat, &payloads[n])
+}
+
+func deleteAnnouncements(ctx *command.CommandContext, arg string) error {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	jobs, _, err := groupAnnouncements(ctx)
+	if err != nil {
+		return err
+	}
+	var selected []database.Job
+	if arg == "all" || arg == "todos" {
+		selected = jobs
+	} else {
+		for field := range strings.FieldsSeq(arg) {
+			n, ok := parseAnnouncementIndex(ctx, field, len(jobs))
+			if !ok {
+				return nil
+			}
+			selected = append(selected, jobs[n])
+		}
+	}
+	if len(selected) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.announce.empty",
+				Other: "📭 Nenhum anúncio agendado neste grupo",
+			},
+		}))
+		return nil
+	}
+
+	for _, job := range selected {
+		if err := ctx.Scheduler.Cancel(job.ID); err != nil {
+			return err
+		}
+	}
+	if err := ctx.DB.DeleteUnusedAnnouncementMedia(0); err != nil {
+		ctx.Log.Error().Err(err).Msg("Error deleting unused announcement media")
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
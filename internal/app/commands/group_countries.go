@@ -0,0 +1,364 @@
+package commands
+
+import (
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+	"meowabot/internal/phone"
+	"slices"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"countries", "paises", "ddi"},
+		Only:    command.Only{Group: true, Admin: true},
+		Run: func(ctx *command.CommandContext) error {
+			action, args, _ := strings.Cut(strings.TrimSpace(ctx.Args), " ")
+			switch strings.ToLower(action) {
+			case "", "list", "lista":
+				return listCountryRules(ctx)
+			case "allow", "permitir":
+				return addCountryRules(ctx, args, true)
+			case "deny", "bloquear":
+				return addCountryRules(ctx, args, false)
+			case "remove", "rm", "del":
+				return removeCountryRules(ctx, args)
+			case "clear":
+				return updateCountryRules(ctx, func([]database.CountryRule) []database.CountryRule { return nil })
+			case "audit", "verificar":
+				kick := strings.EqualFold(strings.TrimSpace(args), "kick")
+				return auditCountries(ctx, kick)
+			case "lookup", "consultar":
+				return lookupCountry(ctx)
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.countries.usage",
+					Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [list|allow|deny|remove|clear] [países]`\n`{{.Prefix}}{{.Command}} audit [kick]`\n`{{.Prefix}}{{.Command}} lookup [número|@membro]`\n\nOs países podem ser nomes, siglas (BR), DDIs (+55) ou DDDs (BR:11, +1:242), separados por vírgula.",
+				},
+				TemplateData: map[string]any{
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+				},
+			}))
+			return nil
+		},
+	})
+}
+
+// parseCountryRules parses the comma-separated targets of the countries
+// command, replying with the first invalid one.
+func parseCountryRules(ctx *command.CommandContext, args string, allow bool) ([]database.CountryRule, bool) {
+	var rules []database.CountryRule
+	for target := range strings.SplitSeq(args, ",") {
+		if strings.TrimSpace(target) == "" {
+			continue
+		}
+		rule, err := moderation.ParseCountryRule(target, allow)
+		if err != nil {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.countries.invalid",
+					Other: "❌ País ou DDI desconhecido: {{.Target}}",
+				},
+				TemplateData: map[string]any{
+					"Target": strings.TrimSpace(target),
+				},
+			}))
+			return nil, false
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.missing",
+				Other: "❌ Informe os países, por exemplo `{{.Prefix}}{{.Command}} allow BR, PT` ou `{{.Prefix}}{{.Command}} deny BR:11`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil, false
+	}
+	return rules, true
+}
+
+func updateCountryRules(ctx *command.CommandContext, update func([]database.CountryRule) []database.CountryRule) error {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return err
+	}
+	group.CountryRules = update(group.CountryRules)
+	if err := ctx.DB.SaveGroupInfo(group); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
+
+func addCountryRules(ctx *command.CommandContext, args string, allow bool) error {
+	added, ok := parseCountryRules(ctx, args, allow)
+	if !ok {
+		return nil
+	}
+	return updateCountryRules(ctx, func(rules []database.CountryRule) []database.CountryRule {
+		// A new rule replaces the existing one for the same numbers
+		for _, rule := range added {
+			rules = slices.DeleteFunc(rules, func(r database.CountryRule) bool { return moderation.SameCountryTarget(&r, &rule) })
+			rules = append(rules, rule)
+		}
+		return rules
+	})
+}
+
+func removeCountryRules(ctx *command.CommandContext, args string) error {
+	removed, ok := parseCountryRules(ctx, args, false)
+	if !ok {
+		return nil
+	}
+	return updateCountryRules(ctx, func(rules []database.CountryRule) []database.CountryRule {
+		return slices.DeleteFunc(rules, func(r database.CountryRule) bool {
+			return slices.ContainsFunc(removed, func(rule database.CountryRule) bool { return moderation.SameCountryTarget(&r, &rule) })
+		})
+	})
+}
+
+func listCountryRules(ctx *command.CommandContext) error {
+	ctx.DB.MU.Lock()
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(group.CountryRules) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.empty",
+				Other: "🌍 Nenhum filtro de país configurado. Use `{{.Prefix}}{{.Command}} allow BR` para permitir apenas números do Brasil.",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	var allowed, denied strings.Builder
+	for _, rule := range group.CountryRules {
+		if rule.Allow {
+			fmt.Fprintf(&allowed, "\n✅ %s", moderation.FormatCountryRule(&rule))
+		} else {
+			fmt.Fprintf(&denied, "\n🚫 %s", moderation.FormatCountryRule(&rule))
+		}
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.countries.list",
+			Other: "🌍 *Filtro de países*{{.Allowed}}{{.Denied}}",
+		},
+		TemplateData: map[string]any{
+			"Allowed": allowed.String(),
+			"Denied":  denied.String(),
+		},
+	}))
+	return nil
+}
+
+// participantNumber returns the phone number of a participant, which isn't
+// the user part of its JID in groups using LIDs.
+func participantNumber(p *types.GroupParticipant) string {
+	if !p.PhoneNumber.IsEmpty() {
+		return p.PhoneNumber.User
+	}
+	if p.JID.Server == types.DefaultUserServer {
+		return p.JID.User
+	}
+	return ""
+}
+
+func auditCountries(ctx *command.CommandContext, kick bool) error {
+	if ctx.GroupMetadata == nil {
+		return nil
+	}
+	ctx.DB.MU.Lock()
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var offenders []types.JID
+	var numbers []string
+	for _, p := range ctx.GroupMetadata.Participants {
+		number := participantNumber(&p)
+		if p.IsAdmin || p.IsSuperAdmin || number == "" || number == ctx.Client.Store.ID.User {
+			continue
+		}
+		if !moderation.CountryAllowed(group.CountryRules, number) {
+			offenders = append(offenders, p.JID)
+			numbers = append(numbers, number)
+		}
+	}
+
+	if len(offenders) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.audit.clean",
+				Other: "✅ Todos os membros passam no filtro de países",
+			},
+		}))
+		return nil
+	}
+
+	if !kick {
+		var b strings.Builder
+		mentions := make([]string, 0, len(numbers))
+		for _, number := range numbers {
+			b.WriteString("\n• @" + number)
+			if n, ok := phone.Parse(number); ok {
+				b.WriteString(" · " + n.Country.Name)
+			}
+			mentions = append(mentions, types.NewJID(number, types.DefaultUserServer).String())
+		}
+		ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.audit.list",
+				Other: "🌍 *Membros fora do filtro de países* ({{.Count}}){{.List}}\n\nℹ️ Use `{{.Prefix}}{{.Command}} audit kick` para removê-los",
+			},
+			TemplateData: map[string]any{
+				"Count":   len(numbers),
+				"List":    b.String(),
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}), &command.MessageOptions{QuotedMessage: ctx.Msg, MentionedJid: mentions})
+		return nil
+	}
+
+	if !ctx.IsParticipantAdmin(ctx.Client.Store.ID.User) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.audit.botadmin",
+				Other: "❌ O bot precisa ser administrador para remover membros",
+			},
+		}))
+		return nil
+	}
+
+	updated, err := ctx.Client.UpdateGroupParticipants(ctx.Msg.Info.Chat, offenders, whatsmeow.ParticipantChangeRemove)
+	if err != nil {
+		return err
+	}
+	var count int
+	for _, p := range updated {
+		if p.Error != 0 {
+			continue
+		}
+		count++
+		target := p.JID.User
+		if !p.PhoneNumber.IsEmpty() {
+			target = p.PhoneNumber.User
+		}
+		ctx.DB.MU.Lock()
+		ctx.LogModeration(&database.ModerationLog{
+			GroupID:  ctx.Msg.Info.Chat.User,
+			ActorID:  ctx.Msg.Info.Sender.User,
+			TargetID: target,
+			Action:   database.ModActionKick,
+			Reason:   database.ModReasonDDI,
+		})
+		ctx.DB.MU.Unlock()
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.countries.audit.kicked",
+			Other: "🚫 {{.Count}} de {{.Total}} membros fora do filtro de países removidos",
+		},
+		TemplateData: map[string]any{
+			"Count": count,
+			"Total": len(offenders),
+		},
+	}))
+	return nil
+}
+
+func lookupCountry(ctx *command.CommandContext) error {
+	targets := ctx.Targets()
+	if len(targets) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.lookup.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} lookup [número|@membro]`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	for n, target := range targets {
+		if n > 0 {
+			b.WriteString("\n\n")
+		}
+		// Members of groups using LIDs are looked up by their phone number
+		digits := ctx.PhoneNumber(target)
+		number, ok := phone.Parse(digits)
+		if !ok {
+			b.WriteString(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.countries.lookup.unknown",
+					Other: "❓ +{{.Number}}: país desconhecido",
+				},
+				TemplateData: map[string]any{
+					"Number": target.User,
+				},
+			}))
+			continue
+		}
+		verdict := "✅"
+		if !moderation.CountryAllowed(group.CountryRules, digits) {
+			verdict = "🚫"
+		}
+		b.WriteString(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.countries.lookup.result",
+				Other: "{{.Verdict}} +{{.Number}}\n🌍 {{.Country}} ({{.ISO}})\n📞 DDI +{{.Code}} · número nacional {{.National}}",
+			},
+			TemplateData: map[string]any{
+				"Verdict":  verdict,
+				"Number":   digits,
+				"Country":  number.Country.Name,
+				"ISO":      number.Country.ISO,
+				"Code":     number.Country.CallingCode,
+				"National": number.National,
+			},
+		}))
+	}
+	ctx.Reply(b.String())
+	return nil
+}
@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+	"meowabot/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// maxRequestIndex separates list positions from phone numbers in the
+// arguments of the requests command.
+const maxRequestIndex = 999
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"requests", "pedidos", "solicitacoes"},
+		Only:    command.Only{Group: true, Admin: true},
+		Need:    command.Requirements{BotAdmin: true},
+		Run: func(ctx *command.CommandContext) error {
+			action, args, _ := strings.Cut(strings.ToLower(strings.TrimSpace(ctx.Args)), " ")
+			switch action {
+			case "", "list", "lista":
+				return listJoinRequests(ctx)
+			case "approve", "aprovar":
+				return updateJoinRequests(ctx, args, whatsmeow.ParticipantChangeApprove)
+			case "reject", "recusar":
+				return updateJoinRequests(ctx, args, whatsmeow.ParticipantChangeReject)
+			case "policy", "politica":
+				return setJoinPolicy(ctx, strings.TrimSpace(args))
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.requests.usage",
+					Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [list|approve|reject] [all|números da lista|@membros]`\n`{{.Prefix}}{{.Command}} policy [manual|filter|auto]`",
+				},
+				TemplateData: map[string]any{
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+				},
+			}))
+			return nil
+		},
+	})
+}
+
+func listJoinRequests(ctx *command.CommandContext) error {
+	pending, err := ctx.Client.GetGroupRequestParticipants(ctx.Msg.Info.Chat)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.requests.empty",
+				Other: "📭 Nenhuma solicitação de entrada pendente",
+			},
+		}))
+		return nil
+	}
+
+	var b strings.Builder
+	mentions := make([]string, 0, len(pending))
+	for n, request := range pending {
+		fmt.Fprintf(&b, "\n%d. @%s · %s", n+1, request.JID.User, util.FormatDuration(time.Since(request.RequestedAt).Truncate(time.Minute)))
+		mentions = append(mentions, request.JID.String())
+	}
+	ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.requests.list",
+			Other: "📨 *Solicitações de entrada* ({{.Count}}){{.List}}\n\nℹ️ Use `{{.Prefix}}{{.Command}} approve 1 2` ou `{{.Prefix}}{{.Command}} reject all`",
+		},
+		TemplateData: map[string]any{
+			"Count":   len(pending),
+			"List":    b.String(),
+			"Prefix":  ctx.Prefix,
+			"Command": ctx.Command,
+		},
+	}), &command.MessageOptions{QuotedMessage: ctx.Msg, MentionedJid: mentions})
+	return nil
+}
+
+// selectJoinRequests returns the pending requests chosen by the arguments:
+// every request, their positions in the list or the mentioned users.
+func selectJoinRequests(ctx *command.CommandContext, pending []types.GroupParticipantRequest, args string) []types.JID {
+	var selected []types.JID
+	add := func(jid types.JID) {
+		if !slices.Contains(selected, jid) {
+			selected = append(selected, jid)
+		}
+	}
+	for field := range strings.FieldsSeq(args) {
+		if field == "all" || field == "todos" {
+			for _, request := range pending {
+				add(request.JID)
+			}
+			return selected
+		}
+		if n, err := strconv.Atoi(field); err == nil && n <= maxRequestIndex {
+			if n >= 1 && n <= len(pending) {
+				add(pending[n-1].JID)
+			}
+		}
+	}
+	for _, target := range ctx.Targets() {
+		for _, request := range pending {
+			if request.JID.User == target.User {
+				add(request.JID)
+			}
+		}
+	}
+	return selected
+}
+
+func updateJoinRequests(ctx *command.CommandContext, args string, action whatsmeow.ParticipantRequestChange) error {
+	pending, err := ctx.Client.GetGroupRequestParticipants(ctx.Msg.Info.Chat)
+	if err != nil {
+		return err
+	}
+	selected := selectJoinRequests(ctx, pending, args)
+	if len(selected) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.requests.noselection",
+				Other: "❌ Nenhuma solicitação pendente corresponde à seleção. Use `{{.Prefix}}{{.Command}}` para ver a lista.",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	updated, err := ctx.Client.UpdateGroupRequestParticipants(ctx.Msg.Info.Chat, selected, action)
+	if err != nil {
+		return err
+	}
+	logAction := database.ModActionApprove
+	if action == whatsmeow.ParticipantChangeReject {
+		logAction = database.ModActionReject
+	}
+	var count int
+	for _, p := range updated {
+		if p.Error != 0 {
+			continue
+		}
+		count++
+		ctx.DB.MU.Lock()
+		ctx.LogModeration(&database.ModerationLog{
+			GroupID:  ctx.Msg.Info.Chat.User,
+			ActorID:  ctx.Msg.Info.Sender.User,
+			TargetID: p.JID.User,
+			Action:   logAction,
+		})
+		ctx.DB.MU.Unlock()
+	}
+
+	message := &i18n.Message{
+		ID:    "cmd.requests.approved",
+		Other: "✅ {{.Count}} de {{.Total}} solicitações aprovadas",
+	}
+	if action == whatsmeow.ParticipantChangeReject {
+		message = &i18n.Message{
+			ID:    "cmd.requests.rejected",
+			Other: "🚫 {{.Count}} de {{.Total}} solicitações recusadas",
+		}
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData: map[string]any{
+			"Count": count,
+			"Total": len(selected),
+		},
+	}))
+	return nil
+}
+
+func setJoinPolicy(ctx *command.CommandContext, arg string) error {
+	policy := arg
+	if policy == "manual" {
+		policy = moderation.JoinPolicyManual
+	}
+	if arg == "" || !slices.Contains(moderation.JoinPolicies, policy) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.requests.policy.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} policy [manual|filter|auto]`\n\n👤 *manual*: todas as solicitações ficam para os administradores\n🧹 *filter*: recusa quem está na blacklist ou fora do filtro de países\n🤖 *auto*: recusa como o filter e aprova os demais",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	group, err := ctx.DB.GetGroupInfo(ctx.Msg.Info.Chat.User)
+	if err != nil {
+		return err
+	}
+	group.JoinPolicy = policy
+	if err := ctx.DB.SaveGroupInfo(group); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
@@ -0,0 +1,257 @@
+package commands
+
+import (
+	"errors"
+	"image"
+	"meowabot/internal/command"
+	"meowabot/internal/tools/media"
+	"strconv"
+	"strings"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// maxImageInput is the largest image accepted by the image commands.
+const maxImageInput = 20 << 20
+
+// errImageArgs is returned by image edits when their arguments are invalid.
+var errImageArgs = errors.New("invalid arguments")
+
+// imageEdit is a command that edits the sent or quoted image.
+type imageEdit struct {
+	aliases []string
+	// usage describes the arguments, for edits that take them
+	usage *i18n.Message
+	// transparent edits are sent as PNG
+	transparent bool
+	apply       func(img image.Image, args string) (image.Image, error)
+}
+
+var imageEdits = []imageEdit{
+	{
+		aliases: []string{"resize", "redimensionar"},
+		usage: &i18n.Message{
+			ID:    "cmd.image.resize.usage",
+			Other: "ℹ️ Use `{{.Prefix}}{{.Command}} <largura>x<altura>`, ou só `<largura>` ou `x<altura>` para manter a proporção. O máximo é {{.Max}}",
+		},
+		apply: func(img image.Image, args string) (image.Image, error) {
+			w, h, ok := strings.Cut(strings.ToLower(args), "x")
+			width, err := parseOptionalInt(w)
+			if err != nil {
+				return nil, errImageArgs
+			}
+			var height int
+			if ok {
+				if height, err = parseOptionalInt(h); err != nil {
+					return nil, errImageArgs
+				}
+			}
+			resized, err := media.ResizeImage(img, width, height)
+			if errors.Is(err, media.ErrInvalidSize) || errors.Is(err, media.ErrImageTooLarge) {
+				return nil, errImageArgs
+			}
+			return resized, err
+		},
+	},
+	{
+		aliases: []string{"square", "quadrado"},
+		apply: func(img image.Image, _ string) (image.Image, error) {
+			return media.CropSquare(img), nil
+		},
+	},
+	{
+		aliases:     []string{"circle", "circulo"},
+		transparent: true,
+		apply: func(img image.Image, _ string) (image.Image, error) {
+			return media.CropCircle(img), nil
+		},
+	},
+	{
+		aliases: []string{"rotate", "girar"},
+		usage: &i18n.Message{
+			ID:    "cmd.image.rotate.usage",
+			Other: "ℹ️ Use `{{.Prefix}}{{.Command}} [90|180|270|-90]`",
+		},
+		apply: func(img image.Image, args string) (image.Image, error) {
+			degrees := 90
+			if args != "" {
+				var err error
+				if degrees, err = strconv.Atoi(strings.TrimSuffix(args, "°")); err != nil || degrees%90 != 0 {
+					return nil, errImageArgs
+				}
+			}
+			return media.Rotate(img, degrees)
+		},
+	},
+	{
+		aliases: []string{"flip", "espelhar"},
+		usage: &i18n.Message{
+			ID:    "cmd.image.flip.usage",
+			Other: "ℹ️ Use `{{.Prefix}}{{.Command}} [h|v]` para espelhar na horizontal ou na vertical",
+		},
+		apply: func(img image.Image, args string) (image.Image, error) {
+			switch strings.ToLower(args) {
+			case "", "h", "horizontal":
+				return media.Flip(img, false), nil
+			case "v", "vertical":
+				return media.Flip(img, true), nil
+			}
+			return nil, errImageArgs
+		},
+	},
+	{
+		aliases: []string{"grayscale", "gray", "cinza"},
+		apply: func(img image.Image, _ string) (image.Image, error) {
+			return media.Grayscale(img), nil
+		},
+	},
+	{
+		aliases: []string{"invert", "inverter"},
+		apply: func(img image.Image, _ string) (image.Image, error) {
+			return media.Invert(img), nil
+		},
+	},
+	{
+		aliases: []string{"blur", "desfocar"},
+		usage: &i18n.Message{
+			ID:    "cmd.image.blur.usage",
+			Other: "ℹ️ Use `{{.Prefix}}{{.Command}} [1-50]`, o padrão é 8",
+		},
+		apply: func(img image.Image, args string) (image.Image, error) {
+			radius, ok := parseImageAmount(args, 8, 1, 50)
+			if !ok {
+				return nil, errImageArgs
+			}
+			return media.Blur(img, radius), nil
+		},
+	},
+	{
+		aliases: []string{"pixelate", "pixelar"},
+		usage: &i18n.Message{
+			ID:    "cmd.image.pixelate.usage",
+			Other: "ℹ️ Use `{{.Prefix}}{{.Command}} [2-100]`, o padrão é 16",
+		},
+		apply: func(img image.Image, args string) (image.Image, error) {
+			block, ok := parseImageAmount(args, 16, 2, 100)
+			if !ok {
+				return nil, errImageArgs
+			}
+			return media.Pixelate(img, block), nil
+		},
+	},
+	{
+		aliases: []string{"meme"},
+		usage: &i18n.Message{
+			ID:    "cmd.image.meme.usage",
+			Other: "ℹ️ Use `{{.Prefix}}{{.Command}} texto de cima | texto de baixo`",
+		},
+		apply: func(img image.Image, args string) (image.Image, error) {
+			top, bottom, _ := strings.Cut(args, "|")
+			if strings.TrimSpace(top) == "" && strings.TrimSpace(bottom) == "" {
+				return nil, errImageArgs
+			}
+			return media.MemeCaption(img, top, bottom)
+		},
+	},
+}
+
+func init() {
+	cmd := command.Default
+
+	for _, edit := range imageEdits {
+		cmd.Register(&command.Command{
+			Aliases: edit.aliases,
+			Run: func(ctx *command.CommandContext) error {
+				return editImage(ctx, &edit)
+			},
+		})
+	}
+}
+
+// parseOptionalInt parses a number that can be left empty to mean 0.
+func parseOptionalInt(s string) (int, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// parseImageAmount parses the amount of an edit between lo and hi, or
+// returns def when it is empty.
+func parseImageAmount(args string, def, lo, hi int) (int, bool) {
+	if args == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(args)
+	return n, err == nil && n >= lo && n <= hi
+}
+
+func editImage(ctx *command.CommandContext, edit *imageEdit) error {
+	downloaded, err := ctx.DownloadMedia(command.MediaImage, maxImageInput)
+	if errors.Is(err, command.ErrNoMedia) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.image.noimage",
+				Other: "ℹ️ Envie ou responda uma imagem com `{{.Prefix}}{{.Command}}`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+	if errors.Is(err, command.ErrMediaTooLarge) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.image.toolarge",
+				Other: "❌ A imagem é grande demais, o limite é {{.Max}} MB",
+			},
+			TemplateData: map[string]any{
+				"Max": maxImageInput >> 20,
+			},
+		}))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	img, err := media.DecodeImage(downloaded.Data)
+	if errors.Is(err, media.ErrImageTooLarge) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.image.toomanypixels",
+				Other: "❌ A imagem tem pixels demais, o limite é {{.Max}} megapixels",
+			},
+			TemplateData: map[string]any{
+				"Max": media.MaxImagePixels / 1_000_000,
+			},
+		}))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	edited, err := edit.apply(img, strings.TrimSpace(ctx.Args))
+	if errors.Is(err, errImageArgs) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: edit.usage,
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+				"Max":     media.MaxImageSide,
+			},
+		}))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	result, err := media.EncodeImage(edited, edit.transparent)
+	if err != nil {
+		return err
+	}
+	ctx.SendImageMessage(ctx.Msg.Info.Chat, result, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
@@ -1 +1,323 @@
-package commands
+package commands
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/tools/media"
+	"meowabot/internal/tools/webp"
+	"meowabot/internal/util"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxStickerInput is the largest image or video accepted by the sticker command.
+const maxStickerInput = 20 << 20
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"sticker", "s", "fig", "figurinha"},
+		Run:     createSticker,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"take", "rename", "roubar"},
+		Run:     renameSticker,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"stickerinfo", "infofig"},
+		Run:     inspectSticker,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"stickerpack", "pacote"},
+		Run:     setStickerPack,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"toimg", "toimage", "paraimg"},
+		Run:     stickerToImage,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"togif", "paragif"},
+		Run: func(ctx *command.CommandContext) error {
+			return convertAnimatedSticker(ctx, false)
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"tovideo", "tomp4", "paravideo"},
+		Run: func(ctx *command.CommandContext) error {
+			return convertAnimatedSticker(ctx, true)
+		},
+	})
+}
+
+func createSticker(ctx *command.CommandContext) error {
+	mode := media.StickerFit
+	args := strings.TrimSpace(ctx.Args)
+	if word, rest, _ := strings.Cut(args, " "); word != "" {
+		if parsed, ok := media.ParseStickerMode(word); ok {
+			mode, args = parsed, strings.TrimSpace(rest)
+		}
+	}
+
+	downloaded, err := ctx.DownloadMedia(command.MediaImage|command.MediaVideo, maxStickerInput)
+	if errors.Is(err, command.ErrNoMedia) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.sticker.usage",
+				Other: "ℹ️ Envie ou responda uma imagem, vídeo ou GIF com `{{.Prefix}}{{.Command}} [fit|crop|stretch] [pacote | autor]`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+	if errors.Is(err, command.ErrMediaTooLarge) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.sticker.toolarge",
+				Other: "❌ O arquivo é grande demais, o limite é {{.Max}} MB",
+			},
+			TemplateData: map[string]any{
+				"Max": maxStickerInput >> 20,
+			},
+		}))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sticker, err := media.CreateSticker(downloaded.Data, downloaded.Kind == command.MediaVideo, mode)
+	if errors.Is(err, media.ErrStickerTooLarge) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.sticker.toobig",
+				Other: "❌ Não consegui deixar a figurinha dentro do limite do WhatsApp, tente um vídeo mais curto",
+			},
+		}))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	title, author := stickerPack(ctx, args)
+	if sticker, err = media.AddExifToWebp(sticker, title, author); err != nil {
+		return err
+	}
+	ctx.SendStickerMessage(ctx.Msg.Info.Chat, sticker, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
+
+// stickerPack returns the pack name and author of the stickers made by the
+// sender, with the placeholders expanded. args can override them with
+// "pack | author", otherwise the user's defaults or the bot's are used.
+func stickerPack(ctx *command.CommandContext, args string) (string, string) {
+	title := cmp.Or(ctx.UserInfo.StickerTitle, ctx.Config.StickerTitle)
+	author := cmp.Or(ctx.UserInfo.StickerDescription, ctx.Config.StickerAuthor)
+	if args != "" {
+		var override string
+		title, override, _ = strings.Cut(args, "|")
+		title = strings.TrimSpace(title)
+		if override = strings.TrimSpace(override); override != "" {
+			author = override
+		}
+	}
+
+	values := util.TimePlaceholders(time.Now().In(ctx.UserInfo.Location()))
+	values["name"] = ctx.Msg.Info.PushName
+	values["botname"] = ctx.Config.BotName
+	return util.ExpandPlaceholders(title, values), util.ExpandPlaceholders(author, values)
+}
+
+// downloadQuotedSticker downloads the sent or quoted sticker, replying if
+// there is none.
+func downloadQuotedSticker(ctx *command.CommandContext) ([]byte, bool, error) {
+	downloaded, err := ctx.DownloadMedia(command.MediaSticker, maxStickerInput)
+	if errors.Is(err, command.ErrNoMedia) {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.sticker.nosticker",
+				Other: "❌ Responda uma figurinha com `{{.Prefix}}{{.Command}}`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return downloaded.Data, true, nil
+}
+
+func renameSticker(ctx *command.CommandContext) error {
+	data, ok, err := downloadQuotedSticker(ctx)
+	if !ok {
+		return err
+	}
+
+	// The emojis and links of the original pack are kept
+	metadata, err := media.GetStickerMetadata(data)
+	if err != nil {
+		metadata = &media.StickerMetadata{}
+	}
+	metadata.PackID = ""
+	metadata.Name, metadata.Publisher = stickerPack(ctx, strings.TrimSpace(ctx.Args))
+	sticker, err := media.SetStickerMetadata(data, metadata)
+	if err != nil {
+		return err
+	}
+	ctx.SendStickerMessage(ctx.Msg.Info.Chat, sticker, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
+
+func inspectSticker(ctx *command.CommandContext) error {
+	data, ok, err := downloadQuotedSticker(ctx)
+	if !ok {
+		return err
+	}
+
+	metadata, err := media.GetStickerMetadata(data)
+	if err != nil {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.stickerinfo.empty",
+				Other: "📭 Essa figurinha não tem informações de pacote",
+			},
+		}))
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n📦 %s\n👤 %s", metadata.Name, metadata.Publisher)
+	if metadata.PackID != "" {
+		fmt.Fprintf(&b, "\n🆔 %s", metadata.PackID)
+	}
+	if len(metadata.Emojis) > 0 {
+		fmt.Fprintf(&b, "\n😀 %s", strings.Join(metadata.Emojis, " "))
+	}
+	if metadata.AndroidAppStoreLink != "" {
+		fmt.Fprintf(&b, "\n🤖 %s", metadata.AndroidAppStoreLink)
+	}
+	if metadata.IOSAppStoreLink != "" {
+		fmt.Fprintf(&b, "\n🍎 %s", metadata.IOSAppStoreLink)
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.stickerinfo.show",
+			Other: "🏷️ *Figurinha*{{.Info}}",
+		},
+		TemplateData: map[string]any{
+			"Info": b.String(),
+		},
+	}))
+	return nil
+}
+
+func setStickerPack(ctx *command.CommandContext) error {
+	args := strings.TrimSpace(ctx.Args)
+	if args == "" {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.stickerpack.show",
+				Other: "📦 Pacote: {{.Title}}\n👤 Autor: {{.Author}}\n\nℹ️ Use `{{.Prefix}}{{.Command}} pacote | autor` para mudar ou `{{.Prefix}}{{.Command}} reset` para voltar ao padrão. Você pode usar $name, $date, $hour e $botname.",
+			},
+			TemplateData: map[string]any{
+				"Title":   cmp.Or(ctx.UserInfo.StickerTitle, ctx.Config.StickerTitle),
+				"Author":  cmp.Or(ctx.UserInfo.StickerDescription, ctx.Config.StickerAuthor),
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	var title, author string
+	if !strings.EqualFold(args, "reset") {
+		title, author, _ = strings.Cut(args, "|")
+		title, author = strings.TrimSpace(title), strings.TrimSpace(author)
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	user, err := ctx.DB.GetUserInfo(ctx.Msg.Info.Sender.User)
+	if err != nil {
+		return err
+	}
+	user.StickerTitle, user.StickerDescription = title, author
+	if err := ctx.DB.SaveUserInfo(user); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
+
+func stickerToImage(ctx *command.CommandContext) error {
+	data, ok, err := downloadQuotedSticker(ctx)
+	if !ok {
+		return err
+	}
+	format := strings.ToLower(strings.TrimSpace(ctx.Args))
+	img, err := media.WebpToImage(data, format == "jpg" || format == "jpeg")
+	if err != nil {
+		return err
+	}
+	ctx.SendImageMessage(ctx.Msg.Info.Chat, img, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
+
+// convertAnimatedSticker sends an animated sticker as a GIF document, or as
+// a video when video is set.
+func convertAnimatedSticker(ctx *command.CommandContext, video bool) error {
+	data, ok, err := downloadQuotedSticker(ctx)
+	if !ok {
+		return err
+	}
+	if f, err := webp.Parse(data); err != nil || !f.Animated() {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.sticker.notanimated",
+				Other: "❌ Essa figurinha não é animada, use `{{.Prefix}}toimg`",
+			},
+			TemplateData: map[string]any{
+				"Prefix": ctx.Prefix,
+			},
+		}))
+		return nil
+	}
+
+	if video {
+		mp4, err := media.WebpToMP4(data)
+		if err != nil {
+			return err
+		}
+		ctx.SendVideoMessage(ctx.Msg.Info.Chat, mp4, &command.MessageOptions{QuotedMessage: ctx.Msg})
+		return nil
+	}
+	animGIF, err := media.WebpToGIF(data)
+	if err != nil {
+		return err
+	}
+	ctx.SendDocumentMessage(ctx.Msg.Info.Chat, animGIF, &command.MessageOptions{
+		QuotedMessage: ctx.Msg,
+		FileName:      proto.String("sticker.gif"),
+		Mimetype:      proto.String("image/gif"),
+	})
+	return nil
+}
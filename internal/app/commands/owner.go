@@ -1 +1,186 @@
-package commands
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"gorm.io/gorm"
+)
+
+// maxListedJobs is how many jobs the jobs command lists at once.
+const maxListedJobs = 20
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"jobs", "tarefas"},
+		Only:    command.Only{Owner: true},
+		Run: func(ctx *command.CommandContext) error {
+			action, arg, _ := strings.Cut(strings.TrimSpace(ctx.Args), " ")
+			switch strings.ToLower(action) {
+			case "", "list", "lista":
+				return listJobs(ctx, "")
+			case "info":
+				return showJob(ctx, arg)
+			case "cancel", "cancelar":
+				return cancelJob(ctx, arg)
+			}
+			return listJobs(ctx, action)
+		},
+	})
+}
+
+func listJobs(ctx *command.CommandContext, name string) error {
+	ctx.DB.MU.Lock()
+	jobs, err := ctx.DB.GetJobs(name)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.jobs.empty",
+				Other: "📭 Nenhuma tarefa agendada",
+			},
+		}))
+		return nil
+	}
+
+	var b strings.Builder
+	for _, job := range jobs[:min(len(jobs), maxListedJobs)] {
+		fmt.Fprintf(&b, "\n#%d *%s* · %s", job.ID, job.Name, formatJobTime(job.RunAt))
+		if job.Cron != "" {
+			fmt.Fprintf(&b, " · `%s`", job.Cron)
+		}
+		if job.Attempts > 0 {
+			fmt.Fprintf(&b, " · ⚠️ %d", job.Attempts)
+		}
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.jobs.list",
+			Other: "🗓️ *Tarefas agendadas* ({{.Count}}){{.List}}\n\nℹ️ Use `{{.Prefix}}{{.Command}} info ID` ou `{{.Prefix}}{{.Command}} cancel ID`",
+		},
+		TemplateData: map[string]any{
+			"Count":   len(jobs),
+			"List":    b.String(),
+			"Prefix":  ctx.Prefix,
+			"Command": ctx.Command,
+		},
+	}))
+	return nil
+}
+
+// formatJobTime formats the time of a job relative to now, e.g. "⏳ 5m", or
+// "⌛ -2h" for jobs that are overdue.
+func formatJobTime(t time.Time) string {
+	if d := time.Until(t); d > 0 {
+		return "⏳ " + util.FormatDuration(d.Truncate(time.Second))
+	}
+	return "⌛ -" + util.FormatDuration(time.Since(t).Truncate(time.Second))
+}
+
+func showJob(ctx *command.CommandContext, arg string) error {
+	id, ok := parseJobID(ctx, arg)
+	if !ok {
+		return nil
+	}
+	ctx.DB.MU.Lock()
+	job, err := ctx.DB.GetJob(id)
+	ctx.DB.MU.Unlock()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		replyJobNotFound(ctx, id)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🗓️ *#%d %s*\n\n⏰ %s (%s)", job.ID, job.Name, job.RunAt.Format(time.DateTime), formatJobTime(job.RunAt))
+	if job.Cron != "" {
+		fmt.Fprintf(&b, "\n🔁 `%s`", job.Cron)
+		if job.Timezone != "" {
+			fmt.Fprintf(&b, " %s", job.Timezone)
+		}
+	}
+	if job.Misfire != "" {
+		fmt.Fprintf(&b, "\n⏭️ %s", job.Misfire)
+	}
+	if job.ChatID != "" {
+		fmt.Fprintf(&b, "\n💬 %s", job.ChatID)
+	}
+	if job.CreatorID != "" {
+		fmt.Fprintf(&b, "\n👤 %s", job.CreatorID)
+	}
+	if job.LockedUntil != nil && job.LockedUntil.After(time.Now()) {
+		fmt.Fprintf(&b, "\n🔒 %s", job.LockedUntil.Format(time.DateTime))
+	}
+	if job.Attempts > 0 {
+		fmt.Fprintf(&b, "\n⚠️ %d: %s", job.Attempts, job.LastError)
+	}
+	if job.Payload != "" {
+		fmt.Fprintf(&b, "\n\n```%s```", util.Truncate(job.Payload, 500))
+	}
+	ctx.Reply(b.String())
+	return nil
+}
+
+func cancelJob(ctx *command.CommandContext, arg string) error {
+	id, ok := parseJobID(ctx, arg)
+	if !ok {
+		return nil
+	}
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	if _, err := ctx.DB.GetJob(id); errors.Is(err, gorm.ErrRecordNotFound) {
+		replyJobNotFound(ctx, id)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := ctx.Scheduler.Cancel(id); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
+
+func parseJobID(ctx *command.CommandContext, arg string) (uint64, bool) {
+	id, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 64)
+	if err != nil {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.jobs.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [nome|info ID|cancel ID]`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return 0, false
+	}
+	return id, true
+}
+
+func replyJobNotFound(ctx *command.CommandContext, id uint64) {
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.jobs.notfound",
+			Other: "❌ Tarefa #{{.ID}} não encontrada",
+		},
+		TemplateData: map[string]any{
+			"ID": id,
+		},
+	}))
+}
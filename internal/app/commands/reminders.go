@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/util"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow/types"
+)
+
+var mentionRegex = regexp.MustCompile(`@\d+`)
+
+const (
+	// maxReminders is how many pending reminders a user can have in a chat.
+	maxReminders = 25
+	// maxReminderDelay is how far in the future reminders can be set.
+	maxReminderDelay = 366 * 24 * time.Hour
+	// reminderTimeLayout formats the time of reminders.
+	reminderTimeLayout = "02/01/2006 15:04"
+)
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"remindme", "lembrar", "lembreme"},
+		Run: func(ctx *command.CommandContext) error {
+			return createReminder(ctx, ctx.Msg.Info.Sender.ToNonAD(), ctx.Args)
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"remind", "lembrete"},
+		Only:    command.Only{Group: true},
+		Need:    command.Requirements{Mention: true},
+		Run: func(ctx *command.CommandContext) error {
+			// Only mentions are stripped, dates could pass for phone numbers
+			return createReminder(ctx, ctx.Targets()[0], strings.TrimSpace(mentionRegex.ReplaceAllLiteralString(ctx.Args, "")))
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"reminders", "lembretes"},
+		Run: func(ctx *command.CommandContext) error {
+			action, arg, _ := strings.Cut(strings.TrimSpace(ctx.Args), " ")
+			switch strings.ToLower(action) {
+			case "", "list", "lista":
+				return listReminders(ctx)
+			case "cancel", "cancelar", "rm":
+				return cancelReminders(ctx, strings.TrimSpace(arg))
+			}
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.reminders.usage",
+					Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [list|cancel número|cancel all]`",
+				},
+				TemplateData: map[string]any{
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+				},
+			}))
+			return nil
+		},
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"timezone", "fuso"},
+		Run:     setUserTimezone,
+	})
+}
+
+func createReminder(ctx *command.CommandContext, target types.JID, args string) error {
+	loc := ctx.UserInfo.Location()
+	now := time.Now().In(loc)
+	at, text, err := util.ParseWhen(args, now)
+	if err == nil && text == "" {
+		text = ctx.QuotedText()
+	}
+	switch {
+	case errors.Is(err, util.ErrPastTime):
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.remind.past",
+				Other: "❌ Esse horário já passou",
+			},
+		}))
+		return nil
+	case err != nil || text == "":
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.remind.usage",
+				Other: "ℹ️ Uso: `{{.Prefix}}{{.Command}} [quando] [mensagem]`\n\nExemplos:\n• `{{.Prefix}}{{.Command}} 2h tirar a pizza`\n• `{{.Prefix}}{{.Command}} amanhã 9h reunião`\n• `{{.Prefix}}{{.Command}} sexta 18:00 happy hour`\n• `{{.Prefix}}{{.Command}} 25/12 10:00 natal`\n\n🌍 Horários no fuso {{.Timezone}}, use `{{.Prefix}}fuso` para mudar.",
+			},
+			TemplateData: map[string]any{
+				"Prefix":   ctx.Prefix,
+				"Command":  ctx.Command,
+				"Timezone": loc.String(),
+			},
+		}))
+		return nil
+	case at.Sub(now) > maxReminderDelay:
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.remind.toofar",
+				Other: "❌ Lembretes podem ser criados para no máximo um ano",
+			},
+		}))
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	pending, err := userReminders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) >= maxReminders {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.remind.limit",
+				Other: "❌ Você já tem {{.Max}} lembretes pendentes neste chat",
+			},
+			TemplateData: map[string]any{
+				"Max": maxReminders,
+			},
+		}))
+		return nil
+	}
+	if _, err := ctx.ScheduleReminder(target, text, at); err != nil {
+		return err
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.remind.created",
+			Other: "⏰ Lembrete criado para {{.Time}} (daqui a {{.In}})",
+		},
+		TemplateData: map[string]any{
+			"Time": at.Format(reminderTimeLayout),
+			"In":   util.FormatDuration(at.Sub(now).Round(time.Minute)),
+		},
+	}))
+	return nil
+}
+
+// userReminders returns the pending reminders the sender created in the
+// chat. Must be called with the database lock held.
+func userReminders(ctx *command.CommandContext) ([]database.Job, error) {
+	jobs, err := ctx.DB.GetChatJobs(command.JobReminder, ctx.Msg.Info.Chat.String())
+	if err != nil {
+		return nil, err
+	}
+	var reminders []database.Job
+	for _, job := range jobs {
+		if job.CreatorID == ctx.Msg.Info.Sender.User {
+			reminders = append(reminders, job)
+		}
+	}
+	return reminders, nil
+}
+
+func listReminders(ctx *command.CommandContext) error {
+	ctx.DB.MU.Lock()
+	reminders, err := userReminders(ctx)
+	ctx.DB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(reminders) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.reminders.empty",
+				Other: "📭 Você não tem lembretes pendentes neste chat",
+			},
+		}))
+		return nil
+	}
+
+	loc := ctx.UserInfo.Location()
+	var b strings.Builder
+	var mentions []string
+	for n, job := range reminders {
+		var payload command.ReminderPayload
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "\n%d. %s · %s", n+1, job.RunAt.In(loc).Format(reminderTimeLayout), util.Truncate(payload.Text, 60))
+		if target, err := types.ParseJID(payload.Target); err == nil && target.User != job.CreatorID {
+			fmt.Fprintf(&b, " → @%s", target.User)
+			mentions = append(mentions, target.String())
+		}
+	}
+	ctx.SendTextMessage(ctx.Msg.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "cmd.reminders.list",
+			Other: "⏰ *Seus lembretes* ({{.Count}}){{.List}}\n\nℹ️ Use `{{.Prefix}}{{.Command}} cancel 1` ou `{{.Prefix}}{{.Command}} cancel all`",
+		},
+		TemplateData: map[string]any{
+			"Count":   len(reminders),
+			"List":    b.String(),
+			"Prefix":  ctx.Prefix,
+			"Command": ctx.Command,
+		},
+	}), &command.MessageOptions{QuotedMessage: ctx.Msg, MentionedJid: mentions})
+	return nil
+}
+
+func cancelReminders(ctx *command.CommandContext, arg string) error {
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	reminders, err := userReminders(ctx)
+	if err != nil {
+		return err
+	}
+	var selected []database.Job
+	if arg == "all" || arg == "todos" {
+		selected = reminders
+	} else {
+		for field := range strings.FieldsSeq(arg) {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 || n > len(reminders) {
+				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+					DefaultMessage: &i18n.Message{
+						ID:    "cmd.reminders.invalid",
+						Other: "❌ Lembrete inválido: {{.Index}}. Use `{{.Prefix}}{{.Command}}` para ver a lista.",
+					},
+					TemplateData: map[string]any{
+						"Index":   field,
+						"Prefix":  ctx.Prefix,
+						"Command": ctx.Command,
+					},
+				}))
+				return nil
+			}
+			selected = append(selected, reminders[n-1])
+		}
+	}
+	if len(selected) == 0 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.reminders.empty",
+				Other: "📭 Você não tem lembretes pendentes neste chat",
+			},
+		}))
+		return nil
+	}
+
+	for _, job := range selected {
+		if err := ctx.Scheduler.Cancel(job.ID); err != nil {
+			return err
+		}
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
+
+func setUserTimezone(ctx *command.CommandContext) error {
+	name := strings.TrimSpace(ctx.Args)
+	if name == "" {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.timezone.show",
+				Other: "🌍 Seu fuso horário: *{{.Timezone}}* ({{.Time}})\n\nℹ️ Use `{{.Prefix}}{{.Command}} America/Sao_Paulo` para mudar",
+			},
+			TemplateData: map[string]any{
+				"Timezone": ctx.UserInfo.Location().String(),
+				"Time":     time.Now().In(ctx.UserInfo.Location()).Format("15:04"),
+				"Prefix":   ctx.Prefix,
+				"Command":  ctx.Command,
+			},
+		}))
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.timezone.invalid",
+				Other: "❌ Fuso horário inválido: {{.Timezone}}. Use nomes como `America/Sao_Paulo` ou `Europe/Lisbon`.",
+			},
+			TemplateData: map[string]any{
+				"Timezone": name,
+			},
+		}))
+		return nil
+	}
+
+	ctx.DB.MU.Lock()
+	defer ctx.DB.MU.Unlock()
+
+	user, err := ctx.DB.GetUserInfo(ctx.Msg.Info.Sender.User)
+	if err != nil {
+		return err
+	}
+	user.Timezone = loc.String()
+	if err := ctx.DB.SaveUserInfo(user); err != nil {
+		return err
+	}
+	ctx.ReactMessage(ctx.Msg, "✅")
+	return nil
+}
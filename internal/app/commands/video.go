@@ -0,0 +1,308 @@
+package commands
+
+import (
+	"errors"
+	"meowabot/internal/command"
+	"meowabot/internal/tools/media"
+	"meowabot/internal/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxVideoInput is the largest video accepted by the video commands.
+const maxVideoInput = 64 << 20
+
+// defaultCompressTarget is the size compress aims for when none is given, in MB.
+const defaultCompressTarget = 8
+
+func init() {
+	cmd := command.Default
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"trim", "cortar"},
+		Run:     trimVideo,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"compress", "comprimir"},
+		Run:     compressVideo,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"gifvideo", "videogif"},
+		Run:     videoToGIF,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"noaudio", "semaudio", "mudo"},
+		Run:     stripVideoAudio,
+	})
+
+	cmd.Register(&command.Command{
+		Aliases: []string{"frame", "quadro"},
+		Run:     extractVideoFrame,
+	})
+}
+
+// downloadQuotedVideo downloads the sent or quoted video or video note,
+// replying if there is none or it is too large or too long. When progress is
+// set, it also tells the user the video is being processed.
+func downloadQuotedVideo(ctx *command.CommandContext, progress bool) ([]byte, bool, error) {
+	downloaded, err := ctx.DownloadMedia(command.MediaVideo, maxVideoInput)
+	switch {
+	case errors.Is(err, command.ErrNoMedia):
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.video.novideo",
+				Other: "ℹ️ Envie ou responda um vídeo com `{{.Prefix}}{{.Command}}`",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil, false, nil
+	case errors.Is(err, command.ErrMediaTooLarge):
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.video.toolarge",
+				Other: "❌ O vídeo é grande demais, o limite é {{.Max}} MB",
+			},
+			TemplateData: map[string]any{
+				"Max": maxVideoInput >> 20,
+			},
+		}))
+		return nil, false, nil
+	case err != nil:
+		return nil, false, err
+	}
+	if video, ok := downloaded.Message.(interface{ GetSeconds() uint32 }); ok && time.Duration(video.GetSeconds())*time.Second > media.MaxVideoDuration {
+		replyVideoError(ctx, media.ErrVideoTooLong)
+		return nil, false, nil
+	}
+
+	if progress {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.video.processing",
+				Other: "⏳ Processando o vídeo, isso pode levar um tempo...",
+			},
+		}))
+	}
+	return downloaded.Data, true, nil
+}
+
+// replyVideoError tells the user why a video edit failed if err is one of
+// the errors of the video edits, returning whether it did.
+func replyVideoError(ctx *command.CommandContext, err error) bool {
+	var message *i18n.Message
+	switch {
+	case errors.Is(err, media.ErrNoVideo):
+		message = &i18n.Message{
+			ID:    "cmd.video.invalid",
+			Other: "❌ Não encontrei um vídeo nesse arquivo",
+		}
+	case errors.Is(err, media.ErrVideoTooLong):
+		message = &i18n.Message{
+			ID:    "cmd.video.toolong",
+			Other: "❌ O vídeo é longo demais, o limite é {{.Max}}",
+		}
+	case errors.Is(err, media.ErrVideoTooLarge):
+		message = &i18n.Message{
+			ID:    "cmd.video.outputtoolarge",
+			Other: "❌ O resultado passou de {{.MaxOutput}} MB, tente um trecho menor",
+		}
+	case errors.Is(err, media.ErrInvalidRange):
+		message = &i18n.Message{
+			ID:    "cmd.video.range",
+			Other: "❌ Esse trecho não existe no vídeo",
+		}
+	case errors.Is(err, media.ErrTargetTooSmall):
+		message = &i18n.Message{
+			ID:    "cmd.video.targettoosmall",
+			Other: "❌ Não dá para deixar esse vídeo tão pequeno, tente um tamanho maior ou corte o vídeo antes",
+		}
+	default:
+		return false
+	}
+	ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData: map[string]any{
+			"Max":       util.FormatDuration(media.MaxVideoDuration),
+			"MaxOutput": media.MaxVideoOutput >> 20,
+		},
+	}))
+	return true
+}
+
+func trimVideo(ctx *command.CommandContext) error {
+	args := strings.Fields(ctx.Args)
+	if len(args) == 1 {
+		// "0:10-0:25"
+		if start, end, ok := strings.Cut(args[0], "-"); ok {
+			args = []string{start, end}
+		}
+	}
+	var start, end time.Duration
+	var err error
+	if len(args) == 0 || len(args) > 2 {
+		err = media.ErrInvalidRange
+	} else if start, err = util.ParseTimestamp(args[0]); err == nil && len(args) == 2 {
+		end, err = util.ParseTimestamp(args[1])
+		if err == nil && end <= start {
+			err = media.ErrInvalidRange
+		}
+	}
+	if err != nil {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.trim.usage",
+				Other: "ℹ️ Use `{{.Prefix}}{{.Command}} <início> [fim]`, por exemplo `{{.Prefix}}{{.Command}} 0:10 0:25`. Sem o fim, o vídeo vai até o final",
+			},
+			TemplateData: map[string]any{
+				"Prefix":  ctx.Prefix,
+				"Command": ctx.Command,
+			},
+		}))
+		return nil
+	}
+
+	data, ok, err := downloadQuotedVideo(ctx, true)
+	if !ok {
+		return err
+	}
+	trimmed, err := media.TrimVideo(data, start, end)
+	if replyVideoError(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx.SendVideoMessage(ctx.Msg.Info.Chat, trimmed, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
+
+func compressVideo(ctx *command.CommandContext) error {
+	target := defaultCompressTarget
+	if args := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(ctx.Args)), "mb"); args != "" {
+		var err error
+		target, err = strconv.Atoi(strings.TrimSpace(args))
+		if err != nil || target < 1 || target > media.MaxVideoOutput>>20 {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.compress.usage",
+					Other: "ℹ️ Use `{{.Prefix}}{{.Command}} [tamanho em MB]`, de 1 a {{.Max}}. O padrão é {{.Default}} MB",
+				},
+				TemplateData: map[string]any{
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+					"Max":     media.MaxVideoOutput >> 20,
+					"Default": defaultCompressTarget,
+				},
+			}))
+			return nil
+		}
+	}
+
+	data, ok, err := downloadQuotedVideo(ctx, true)
+	if !ok {
+		return err
+	}
+	if len(data) <= target<<20 {
+		ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "cmd.compress.alreadysmall",
+				Other: "✅ O vídeo já tem menos de {{.Target}} MB",
+			},
+			TemplateData: map[string]any{
+				"Target": target,
+			},
+		}))
+		return nil
+	}
+	compressed, err := media.CompressVideo(data, target<<20)
+	if replyVideoError(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx.SendVideoMessage(ctx.Msg.Info.Chat, compressed, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
+
+func videoToGIF(ctx *command.CommandContext) error {
+	data, ok, err := downloadQuotedVideo(ctx, true)
+	if !ok {
+		return err
+	}
+	gif, err := media.ToGIFVideo(data)
+	if replyVideoError(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx.SendVideoMessage(ctx.Msg.Info.Chat, gif, &command.MessageOptions{
+		QuotedMessage: ctx.Msg,
+		GifPlayback:   true,
+	})
+	return nil
+}
+
+func stripVideoAudio(ctx *command.CommandContext) error {
+	data, ok, err := downloadQuotedVideo(ctx, false)
+	if !ok {
+		return err
+	}
+	silent, err := media.StripAudio(data)
+	if replyVideoError(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx.SendVideoMessage(ctx.Msg.Info.Chat, silent, &command.MessageOptions{QuotedMessage: ctx.Msg})
+	return nil
+}
+
+func extractVideoFrame(ctx *command.CommandContext) error {
+	var at time.Duration
+	if args := strings.TrimSpace(ctx.Args); args != "" {
+		var err error
+		if at, err = util.ParseTimestamp(args); err != nil {
+			ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "cmd.frame.usage",
+					Other: "ℹ️ Use `{{.Prefix}}{{.Command}} [tempo]`, por exemplo `{{.Prefix}}{{.Command}} 1:30`",
+				},
+				TemplateData: map[string]any{
+					"Prefix":  ctx.Prefix,
+					"Command": ctx.Command,
+				},
+			}))
+			return nil
+		}
+	}
+
+	data, ok, err := downloadQuotedVideo(ctx, false)
+	if !ok {
+		return err
+	}
+	frame, err := media.ExtractFrame(data, at)
+	if replyVideoError(ctx, err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	ctx.SendImageMessage(ctx.Msg.Info.Chat, frame, &command.MessageOptions{
+		QuotedMessage: ctx.Msg,
+		Caption:       proto.String(util.FormatTimestamp(at)),
+	})
+	return nil
+}
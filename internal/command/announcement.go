@@ -0,0 +1,231 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meowabot/internal/database"
+	"meowabot/internal/scheduler"
+	"time"
+
+	tmsg "meowabot/internal/tools/messages"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// JobAnnouncement is the scheduler job that posts an announcement in a group.
+const JobAnnouncement = "announcement"
+
+// MaxAnnouncementMedia is the size limit of the media of announcements, which
+// is kept in the database.
+const MaxAnnouncementMedia = 16 << 20
+
+var ErrAnnouncementTooLarge = errors.New("announcement media is too large")
+
+// AnnouncementPayload is the payload of the announcement jobs. Message is a
+// waE2E.Message in wire format holding the text or the media message with
+// its caption. The media itself is downloaded when the announcement is
+// created, as WhatsApp doesn't keep it forever, and stored apart from the job
+// under MediaHash. Media only holds it while the announcement is created or
+// sent.
+type AnnouncementPayload struct {
+	Message   []byte `json:"message"`
+	MediaHash string `json:"media_hash,omitempty"`
+	Media     []byte `json:"-"`
+}
+
+// NewAnnouncement builds the payload of an announcement from a message. Media
+// messages are downloaded, and their caption is replaced by text if it isn't
+// empty.
+func (ctx *CommandContext) NewAnnouncement(message *waE2E.Message, text string) (*AnnouncementPayload, error) {
+	var caption *string
+	if text != "" {
+		caption = &text
+	}
+
+	var content *waE2E.Message
+	var downloadable whatsmeow.DownloadableMessage
+	switch {
+	case message.GetImageMessage() != nil:
+		image := proto.Clone(message.GetImageMessage()).(*waE2E.ImageMessage)
+		if caption != nil {
+			image.Caption = caption
+		}
+		content = &waE2E.Message{ImageMessage: image}
+		downloadable = image
+	case message.GetVideoMessage() != nil:
+		video := proto.Clone(message.GetVideoMessage()).(*waE2E.VideoMessage)
+		if caption != nil {
+			video.Caption = caption
+		}
+		content = &waE2E.Message{VideoMessage: video}
+		downloadable = video
+	case message.GetDocumentWithCaptionMessage().GetMessage().GetDocumentMessage() != nil || message.GetDocumentMessage() != nil:
+		document := message.GetDocumentMessage()
+		if document == nil {
+			document = message.GetDocumentWithCaptionMessage().GetMessage().GetDocumentMessage()
+		}
+		document = proto.Clone(document).(*waE2E.DocumentMessage)
+		if caption != nil {
+			document.Caption = caption
+		}
+		content = &waE2E.Message{DocumentMessage: document}
+		downloadable = document
+	case message.GetAudioMessage() != nil:
+		audio := proto.Clone(message.GetAudioMessage()).(*waE2E.AudioMessage)
+		content = &waE2E.Message{AudioMessage: audio}
+		downloadable = audio
+	case message.GetStickerMessage() != nil:
+		sticker := proto.Clone(message.GetStickerMessage()).(*waE2E.StickerMessage)
+		content = &waE2E.Message{StickerMessage: sticker}
+		downloadable = sticker
+	default:
+		if text == "" {
+			text, _ = tmsg.GetMessageText(message)
+		}
+		if text == "" {
+			return nil, errors.New("announcement has no content")
+		}
+		content = &waE2E.Message{Conversation: proto.String(text)}
+	}
+
+	payload := &AnnouncementPayload{}
+	if downloadable != nil {
+		data, err := ctx.download(downloadable, MaxAnnouncementMedia, true)
+		if errors.Is(err, ErrMediaTooLarge) {
+			return nil, ErrAnnouncementTooLarge
+		}
+		if err != nil {
+			return nil, fmt.Errorf("downloading announcement media: %w", err)
+		}
+		payload.Media = data
+		clearMediaFields(content)
+	}
+
+	var err error
+	payload.Message, err = proto.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// clearMediaFields drops what isn't needed to send the media again, like the
+// keys of the original upload and the context of the message.
+func clearMediaFields(message *waE2E.Message) {
+	if m := message.ImageMessage; m != nil {
+		m.URL, m.DirectPath, m.MediaKey, m.FileEncSHA256, m.JPEGThumbnail, m.ContextInfo = nil, nil, nil, nil, nil, nil
+	}
+	if m := message.VideoMessage; m != nil {
+		m.URL, m.DirectPath, m.MediaKey, m.FileEncSHA256, m.JPEGThumbnail, m.ContextInfo = nil, nil, nil, nil, nil, nil
+	}
+	if m := message.DocumentMessage; m != nil {
+		m.URL, m.DirectPath, m.MediaKey, m.FileEncSHA256, m.JPEGThumbnail, m.ContextInfo = nil, nil, nil, nil, nil, nil
+	}
+	if m := message.AudioMessage; m != nil {
+		m.URL, m.DirectPath, m.MediaKey, m.FileEncSHA256, m.Waveform, m.ContextInfo = nil, nil, nil, nil, nil, nil
+	}
+	if m := message.StickerMessage; m != nil {
+		m.URL, m.DirectPath, m.MediaKey, m.FileEncSHA256, m.PngThumbnail, m.ContextInfo = nil, nil, nil, nil, nil, nil
+	}
+}
+
+// ParseMessage reconstructs the message of an announcement.
+func (p *AnnouncementPayload) ParseMessage() (*waE2E.Message, error) {
+	var message waE2E.Message
+	if err := proto.Unmarshal(p.Message, &message); err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// LoadMedia loads the media of an announcement. Must be called with the
+// database lock held.
+func (p *AnnouncementPayload) LoadMedia(db *database.DBInstance) error {
+	if p.MediaHash == "" || p.Media != nil {
+		return nil
+	}
+	var err error
+	p.Media, err = db.GetAnnouncementMedia(p.MediaHash)
+	return err
+}
+
+// Kind returns the kind of content of an announcement, e.g. "text" or "image".
+func (p *AnnouncementPayload) Kind() string {
+	message, err := p.ParseMessage()
+	if err != nil {
+		return "invalid"
+	}
+	return tmsg.GetMessageType(message)
+}
+
+// ScheduleAnnouncement schedules an announcement in the command's chat, either
+// once at the given time or following a cron expression in the timezone.
+// Must be called with the database lock held.
+func (ctx *CommandContext) ScheduleAnnouncement(payload *AnnouncementPayload, at time.Time, cron string, timezone string) (*database.Job, error) {
+	if payload.Media != nil {
+		hash, err := ctx.DB.SaveAnnouncementMedia(payload.Media)
+		if err != nil {
+			return nil, err
+		}
+		payload.MediaHash = hash
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &database.Job{
+		Name:      JobAnnouncement,
+		ChatID:    ctx.Msg.Info.Chat.String(),
+		CreatorID: ctx.Msg.Info.Sender.User,
+		Payload:   string(data),
+		RunAt:     at,
+		Cron:      cron,
+		Timezone:  timezone,
+	}
+	if cron != "" {
+		// A missed weekly reminder is better skipped than posted late
+		job.Misfire = scheduler.MisfireSkip
+	}
+	return job, ctx.Scheduler.Schedule(job)
+}
+
+// SendAnnouncement posts an announcement, sending its media again through
+// the Send*Message helpers. The media must have been loaded.
+func (ctx *CommandContext) SendAnnouncement(to types.JID, payload *AnnouncementPayload) error {
+	message, err := payload.ParseMessage()
+	if err != nil {
+		return err
+	}
+	switch {
+	case message.ImageMessage != nil:
+		return ctx.SendImageMessage(to, payload.Media, &MessageOptions{
+			Caption:  message.ImageMessage.Caption,
+			Mimetype: message.ImageMessage.Mimetype,
+		})
+	case message.VideoMessage != nil:
+		return ctx.SendVideoMessage(to, payload.Media, &MessageOptions{
+			Caption:  message.VideoMessage.Caption,
+			Mimetype: message.VideoMessage.Mimetype,
+		})
+	case message.DocumentMessage != nil:
+		return ctx.SendDocumentMessage(to, payload.Media, &MessageOptions{
+			Caption:  message.DocumentMessage.Caption,
+			FileName: message.DocumentMessage.FileName,
+			Mimetype: message.DocumentMessage.Mimetype,
+		})
+	case message.AudioMessage != nil:
+		return ctx.SendAudioMessage(to, payload.Media, &MessageOptions{
+			Seconds:  message.AudioMessage.Seconds,
+			Mimetype: message.AudioMessage.Mimetype,
+		})
+	case message.StickerMessage != nil:
+		return ctx.SendStickerMessage(to, payload.Media, nil)
+	default:
+		text, _ := tmsg.GetMessageText(message)
+		return ctx.SendTextMessage(to, text, nil)
+	}
+}
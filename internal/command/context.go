@@ -0,0 +1,113 @@
+package command
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	tmsg "meowabot/internal/tools/messages"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// phoneNumberRegex matches phone numbers written without spaces, like
+// +5511999999999 or (11)99999-9999. Spaces would join a number to the
+// arguments after it, like the duration of a mute.
+var phoneNumberRegex = regexp.MustCompile(`\+?[\d\-()]{8,}`)
+var nonDigitRegex = regexp.MustCompile(`\D+`)
+var mentionRegex = regexp.MustCompile(`@\d+`)
+
+// Targets returns the users referenced by the command message: the mentioned
+// users, the author of the quoted message and any phone numbers in the args.
+func (ctx *CommandContext) Targets() []types.JID {
+	var targets []types.JID
+	add := func(jid types.JID) {
+		if jid.IsEmpty() || jid.User == "" {
+			return
+		}
+		jid = jid.ToNonAD()
+		if !slices.ContainsFunc(targets, func(j types.JID) bool { return j.User == jid.User }) {
+			targets = append(targets, jid)
+		}
+	}
+
+	for _, m := range tmsg.GetMentionedJIDS(ctx.Msg.Message) {
+		if jid, err := types.ParseJID(m); err == nil {
+			add(jid)
+		}
+	}
+	if jid, err := tmsg.GetQuotedJid(ctx.Msg); err == nil {
+		add(jid)
+	}
+	// Mentions are stripped first, their numbers are already in the mentions
+	args := mentionRegex.ReplaceAllLiteralString(ctx.Args, "")
+	for _, number := range phoneNumberRegex.FindAllString(args, -1) {
+		number = nonDigitRegex.ReplaceAllLiteralString(number, "")
+		if len(number) >= 8 {
+			add(types.NewJID(number, types.DefaultUserServer))
+		}
+	}
+	return targets
+}
+
+// IsParticipantAdmin reports whether the user is an admin of the current group.
+func (ctx *CommandContext) IsParticipantAdmin(user string) bool {
+	if ctx.GroupMetadata == nil {
+		return false
+	}
+	for _, p := range ctx.GroupMetadata.Participants {
+		if (p.JID.User == user || p.PhoneNumber.User == user) && (p.IsAdmin || p.IsSuperAdmin) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsParticipant reports whether the user is a member of the current group.
+func (ctx *CommandContext) IsParticipant(user string) bool {
+	if ctx.GroupMetadata == nil {
+		return false
+	}
+	for _, p := range ctx.GroupMetadata.Participants {
+		if p.JID.User == user || p.PhoneNumber.User == user {
+			return true
+		}
+	}
+	return false
+}
+
+// PhoneNumber returns the phone number of a user, which isn't the user part of
+// their JID when it's a LID. It returns "" if the number isn't known.
+func (ctx *CommandContext) PhoneNumber(jid types.JID) string {
+	switch jid.Server {
+	case types.DefaultUserServer:
+		return jid.User
+	case types.HiddenUserServer:
+		if ctx.GroupMetadata != nil {
+			for _, p := range ctx.GroupMetadata.Participants {
+				if (p.JID.User == jid.User || p.LID.User == jid.User) && !p.PhoneNumber.IsEmpty() {
+					return p.PhoneNumber.User
+				}
+			}
+		}
+		if pn, err := ctx.Client.Store.LIDs.GetPNForLID(context.Background(), jid); err == nil && !pn.IsEmpty() {
+			return pn.User
+		}
+	}
+	return ""
+}
+
+// ArgsWithoutTargets returns the command args with mentions and phone numbers
+// stripped, usually the reason given for a moderation command.
+func (ctx *CommandContext) ArgsWithoutTargets() string {
+	args := mentionRegex.ReplaceAllLiteralString(ctx.Args, "")
+	args = phoneNumberRegex.ReplaceAllLiteralString(args, "")
+	return strings.Join(strings.Fields(args), " ")
+}
+
+// QuotedText returns the text of the message quoted by the command message.
+func (ctx *CommandContext) QuotedText() string {
+	text, _ := tmsg.GetMessageText(ctx.Msg.Message.GetExtendedTextMessage().GetContextInfo().GetQuotedMessage())
+	return text
+}
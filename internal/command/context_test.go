@@ -0,0 +1,57 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// testCommand returns the context of a command with the given args that
+// mentions the users.
+func testCommand(args string, mentions ...string) *CommandContext {
+	return &CommandContext{
+		Args: args,
+		Msg: &events.Message{Message: &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text:        proto.String("!cmd " + args),
+			ContextInfo: &waE2E.ContextInfo{MentionedJID: mentions},
+		}}},
+	}
+}
+
+func TestTargets(t *testing.T) {
+	mentioned := types.NewJID("5511999999999", types.DefaultUserServer)
+	tests := []struct {
+		name     string
+		args     string
+		mentions []string
+		want     []types.JID
+	}{
+		{"mention and duration", "@5511999999999 10m", []string{mentioned.String()}, []types.JID{mentioned}},
+		{"mention and reason", "@5511999999999 2h spam", []string{mentioned.String()}, []types.JID{mentioned}},
+		{"mention and numbers", "@5511999999999 1 2 3", []string{mentioned.String()}, []types.JID{mentioned}},
+		{"number", "+55 5511888888888 10m", nil, []types.JID{types.NewJID("5511888888888", types.DefaultUserServer)}},
+		{"formatted number", "(11)98888-8888", nil, []types.JID{types.NewJID("11988888888", types.DefaultUserServer)}},
+		{
+			"two numbers",
+			"5511888888888 5511777777777",
+			nil,
+			[]types.JID{types.NewJID("5511888888888", types.DefaultUserServer), types.NewJID("5511777777777", types.DefaultUserServer)},
+		},
+		{"short numbers", "1234 5678 90", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, testCommand(tt.args, tt.mentions...).Targets())
+		})
+	}
+}
+
+func TestArgsWithoutTargets(t *testing.T) {
+	ctx := testCommand("@5511999999999 2h spam", "5511999999999@s.whatsapp.net")
+	assert.Equal(t, "2h spam", ctx.ArgsWithoutTargets())
+	assert.Equal(t, "flood", testCommand("5511888888888 flood").ArgsWithoutTargets())
+}
@@ -0,0 +1,306 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	tmsg "meowabot/internal/tools/messages"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+)
+
+// MediaKind is a set of the kinds of media DownloadMedia looks for.
+type MediaKind int
+
+const (
+	MediaImage MediaKind = 1 << iota
+	// MediaVideo includes GIFs and round video notes
+	MediaVideo
+	// MediaAudio includes voice notes
+	MediaAudio
+	MediaSticker
+	MediaDocument
+
+	MediaAny = MediaImage | MediaVideo | MediaAudio | MediaSticker | MediaDocument
+)
+
+// uploadReuseTime is how long an upload is reused for the same data. The
+// servers keep media for longer, but not forever.
+const uploadReuseTime = 7 * 24 * time.Hour
+
+var ErrNoMedia = errors.New("no media found")
+var ErrMediaTooLarge = errors.New("media is too large")
+
+// Media is media downloaded by DownloadMedia.
+type Media struct {
+	Data []byte
+	Kind MediaKind
+	// Message is the media message, e.g. a *waE2E.ImageMessage
+	Message whatsmeow.DownloadableMessage
+	// Quoted is set when the media was found in the quoted message
+	Quoted bool
+}
+
+// findMedia returns the first media of one of the kinds in the message,
+// unwrapping view once and document with caption messages, and whether it is
+// view once media.
+func findMedia(message *waE2E.Message, kinds MediaKind) (whatsmeow.DownloadableMessage, MediaKind, bool) {
+	viewOnce := isViewOnce(message)
+	message = tmsg.UnwrapMessage(message)
+	var media interface {
+		whatsmeow.DownloadableMessage
+		GetViewOnce() bool
+	}
+	var kind MediaKind
+	switch {
+	case message == nil:
+		return nil, 0, false
+	case kinds&MediaImage != 0 && message.GetImageMessage() != nil:
+		media, kind = message.GetImageMessage(), MediaImage
+	case kinds&MediaVideo != 0 && message.GetVideoMessage() != nil:
+		media, kind = message.GetVideoMessage(), MediaVideo
+	case kinds&MediaVideo != 0 && message.GetPtvMessage() != nil:
+		media, kind = message.GetPtvMessage(), MediaVideo
+	case kinds&MediaAudio != 0 && message.GetAudioMessage() != nil:
+		media, kind = message.GetAudioMessage(), MediaAudio
+	case kinds&MediaSticker != 0 && message.GetStickerMessage() != nil:
+		return message.GetStickerMessage(), MediaSticker, viewOnce
+	case kinds&MediaDocument != 0 && message.GetDocumentMessage() != nil:
+		return message.GetDocumentMessage(), MediaDocument, viewOnce
+	default:
+		return nil, 0, false
+	}
+	return media, kind, viewOnce || media.GetViewOnce()
+}
+
+// isViewOnce reports whether the message is wrapped in a view once message.
+func isViewOnce(message *waE2E.Message) bool {
+	for message != nil {
+		if message.GetViewOnceMessage() != nil || message.GetViewOnceMessageV2() != nil || message.GetViewOnceMessageV2Extension() != nil {
+			return true
+		}
+		message = message.GetEphemeralMessage().GetMessage()
+	}
+	return false
+}
+
+// DownloadMedia downloads media of one of the kinds from the command
+// message, or from the message it quotes. It returns ErrNoMedia if there is
+// none, and ErrMediaTooLarge if it is larger than maxSize bytes. The
+// decrypted media is cached on disk by its hash, unless it is view once.
+func (ctx *CommandContext) DownloadMedia(kinds MediaKind, maxSize uint64) (*Media, error) {
+	quoted := false
+	msg, kind, viewOnce := findMedia(ctx.Msg.Message, kinds)
+	if msg == nil {
+		msg, kind, viewOnce = findMedia(tmsg.GetContextInfo(ctx.Msg.Message).GetQuotedMessage(), kinds)
+		quoted = true
+	}
+	if msg == nil {
+		return nil, ErrNoMedia
+	}
+	data, err := ctx.download(msg, maxSize, !viewOnce)
+	if err != nil {
+		return nil, err
+	}
+	return &Media{Data: data, Kind: kind, Message: msg, Quoted: quoted}, nil
+}
+
+// download downloads a media message of at most maxSize bytes, through the
+// media cache. View once media is downloaded without storing it in the cache.
+func (ctx *CommandContext) download(msg whatsmeow.DownloadableMessage, maxSize uint64, store bool) ([]byte, error) {
+	if sized, ok := msg.(interface{ GetFileLength() uint64 }); ok && sized.GetFileLength() > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, sized.GetFileLength())
+	}
+
+	key := hex.EncodeToString(msg.GetFileSHA256())
+	cached := ctx.MediaCache != nil && len(msg.GetFileSHA256()) == sha256.Size
+	if cached {
+		// The hash is checked again in case the file got corrupted
+		if data, ok := ctx.MediaCache.Get(key); ok && bytes.Equal(sha256Sum(data), msg.GetFileSHA256()) {
+			return data, nil
+		}
+	}
+
+	data, err := ctx.downloadLimited(msg, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, len(data))
+	}
+	if cached && store {
+		if err := ctx.MediaCache.Put(key, data); err != nil {
+			ctx.Log.Warn().Err(err).Msg("Failed to cache media")
+		}
+	}
+	return data, nil
+}
+
+// downloadLimited downloads a media message through a temporary file that
+// doesn't grow past maxSize, as the file length in the message is set by the
+// sender and may be missing or wrong. whatsmeow checks the hash of what it
+// downloads.
+func (ctx *CommandContext) downloadLimited(msg whatsmeow.DownloadableMessage, maxSize uint64) ([]byte, error) {
+	file, err := os.CreateTemp("", "meowabot-download-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	downloadCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The encrypted file also has the padding and the MAC
+	limited := &limitedFile{File: file, limit: int64(maxSize) + downloadOverhead, exceeded: cancel}
+	downloadFunc := ctx.Client.DownloadToFile
+	if ctx.downloadFunc != nil {
+		downloadFunc = ctx.downloadFunc
+	}
+	if err := downloadFunc(downloadCtx, msg, limited); err != nil {
+		if limited.tooLarge {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrMediaTooLarge, maxSize)
+		}
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(file)
+}
+
+// downloadOverhead is how much larger than the media an encrypted download
+// can be, with the AES block padding and the HMAC.
+const downloadOverhead = 16 + 10
+
+// limitedFile is a file for DownloadToFile whose writes fail once it would
+// grow past its limit. The download is then cancelled, so whatsmeow doesn't
+// retry it on the other media hosts.
+type limitedFile struct {
+	*os.File
+	limit    int64
+	exceeded context.CancelFunc
+	tooLarge bool
+}
+
+func (f *limitedFile) Write(p []byte) (int, error) {
+	offset, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.check(offset + int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.Write(p)
+}
+
+func (f *limitedFile) WriteAt(p []byte, offset int64) (int, error) {
+	if err := f.check(offset + int64(len(p))); err != nil {
+		return 0, err
+	}
+	return f.File.WriteAt(p, offset)
+}
+
+// ReadFrom overrides the one of os.File, which io.Copy would use instead of
+// Write, skipping the limit.
+func (f *limitedFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+func (f *limitedFile) check(size int64) error {
+	if size <= f.limit {
+		return nil
+	}
+	f.tooLarge = true
+	f.exceeded()
+	return ErrMediaTooLarge
+}
+
+// cachedUpload is an upload kept in the media cache. UploadResponse doesn't
+// serialize the keys and hashes.
+type cachedUpload struct {
+	URL           string    `json:"url"`
+	DirectPath    string    `json:"direct_path"`
+	Handle        string    `json:"handle"`
+	ObjectID      string    `json:"object_id"`
+	MediaKey      []byte    `json:"media_key"`
+	FileEncSHA256 []byte    `json:"file_enc_sha256"`
+	FileSHA256    []byte    `json:"file_sha256"`
+	FileLength    uint64    `json:"file_length"`
+	UploadedAt    time.Time `json:"uploaded_at"`
+}
+
+// upload uploads media, reusing a recent upload of the same data and type.
+// Uploads are cached with the hash of the data as the downloads are, with
+// the media type as the extension.
+func (ctx *CommandContext) upload(data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	if ctx.MediaCache == nil {
+		return ctx.Client.Upload(context.TODO(), data, mediaType)
+	}
+
+	key := fmt.Sprintf("%x.%s.upload", sha256Sum(data), uploadKind(mediaType))
+	var cached cachedUpload
+	if raw, ok := ctx.MediaCache.Get(key); ok && json.Unmarshal(raw, &cached) == nil && time.Since(cached.UploadedAt) < uploadReuseTime {
+		return whatsmeow.UploadResponse{
+			URL:           cached.URL,
+			DirectPath:    cached.DirectPath,
+			Handle:        cached.Handle,
+			ObjectID:      cached.ObjectID,
+			MediaKey:      cached.MediaKey,
+			FileEncSHA256: cached.FileEncSHA256,
+			FileSHA256:    cached.FileSHA256,
+			FileLength:    cached.FileLength,
+		}, nil
+	}
+
+	uploaded, err := ctx.Client.Upload(context.TODO(), data, mediaType)
+	if err != nil {
+		return uploaded, err
+	}
+	raw, err := json.Marshal(cachedUpload{
+		URL:           uploaded.URL,
+		DirectPath:    uploaded.DirectPath,
+		Handle:        uploaded.Handle,
+		ObjectID:      uploaded.ObjectID,
+		MediaKey:      uploaded.MediaKey,
+		FileEncSHA256: uploaded.FileEncSHA256,
+		FileSHA256:    uploaded.FileSHA256,
+		FileLength:    uploaded.FileLength,
+		UploadedAt:    time.Now(),
+	})
+	if err == nil {
+		err = ctx.MediaCache.Put(key, raw)
+	}
+	if err != nil {
+		ctx.Log.Warn().Err(err).Msg("Failed to cache upload")
+	}
+	return uploaded, nil
+}
+
+// uploadKind names a media type in the keys of the cached uploads.
+func uploadKind(mediaType whatsmeow.MediaType) string {
+	switch mediaType {
+	case whatsmeow.MediaImage:
+		return "image"
+	case whatsmeow.MediaVideo:
+		return "video"
+	case whatsmeow.MediaAudio:
+		return "audio"
+	case whatsmeow.MediaDocument:
+		return "document"
+	}
+	return "other"
+}
+
+func sha256Sum(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
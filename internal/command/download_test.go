@@ -0,0 +1,226 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"meowabot/internal/tools/mediacache"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func testImage(data []byte) *waE2E.ImageMessage {
+	return &waE2E.ImageMessage{FileSHA256: sha256Sum(data), FileLength: proto.Uint64(uint64(len(data)))}
+}
+
+func TestFindMedia(t *testing.T) {
+	image := testImage([]byte("image"))
+	document := &waE2E.DocumentMessage{FileName: proto.String("file.pdf")}
+	video := &waE2E.VideoMessage{}
+
+	tests := []struct {
+		name     string
+		message  *waE2E.Message
+		kinds    MediaKind
+		media    whatsmeow.DownloadableMessage
+		kind     MediaKind
+		viewOnce bool
+	}{
+		{"nil", nil, MediaAny, nil, 0, false},
+		{"text", &waE2E.Message{Conversation: proto.String("hi")}, MediaAny, nil, 0, false},
+		{"image", &waE2E.Message{ImageMessage: image}, MediaAny, image, MediaImage, false},
+		{"other kind", &waE2E.Message{ImageMessage: image}, MediaVideo, nil, 0, false},
+		{"video note", &waE2E.Message{PtvMessage: video}, MediaVideo, video, MediaVideo, false},
+		{
+			"view once",
+			&waE2E.Message{ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: &waE2E.Message{ImageMessage: image}}},
+			MediaImage, image, MediaImage, true,
+		},
+		{
+			"ephemeral view once",
+			&waE2E.Message{EphemeralMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{
+				ViewOnceMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{ImageMessage: image}},
+			}}},
+			MediaImage, image, MediaImage, true,
+		},
+		{
+			"view once flag",
+			&waE2E.Message{VideoMessage: &waE2E.VideoMessage{ViewOnce: proto.Bool(true)}},
+			MediaVideo, &waE2E.VideoMessage{ViewOnce: proto.Bool(true)}, MediaVideo, true,
+		},
+		{
+			"document with caption",
+			&waE2E.Message{DocumentWithCaptionMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{DocumentMessage: document}}},
+			MediaDocument, document, MediaDocument, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			media, kind, viewOnce := findMedia(tt.message, tt.kinds)
+			if tt.media == nil {
+				assert.Nil(t, media)
+			} else {
+				assert.True(t, proto.Equal(tt.media.(proto.Message), media.(proto.Message)))
+			}
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.viewOnce, viewOnce)
+		})
+	}
+}
+
+// testDownloadContext returns a context for a command message whose downloads
+// return the data in files, keyed by the hash of the media, and are counted.
+func testDownloadContext(t *testing.T, message *waE2E.Message, files map[string][]byte) (*CommandContext, *int) {
+	cache, err := mediacache.New(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	downloads := new(int)
+	ctx := &CommandContext{
+		Msg:        &events.Message{Message: message},
+		Log:        &log,
+		MediaCache: cache,
+		downloadFunc: func(_ context.Context, msg whatsmeow.DownloadableMessage, file whatsmeow.File) error {
+			*downloads++
+			data, ok := files[hex.EncodeToString(msg.GetFileSHA256())]
+			if !ok {
+				return errors.New("not found")
+			}
+			// Copied like whatsmeow copies the response body, which has no WriteTo
+			_, err := io.Copy(file, struct{ io.Reader }{bytes.NewReader(data)})
+			return err
+		},
+	}
+	return ctx, downloads
+}
+
+func TestDownloadMedia(t *testing.T) {
+	data := []byte("image data")
+	image := testImage(data)
+	key := hex.EncodeToString(image.GetFileSHA256())
+	files := map[string][]byte{key: data}
+
+	t.Run("no media", func(t *testing.T) {
+		ctx, downloads := testDownloadContext(t, &waE2E.Message{Conversation: proto.String("hi")}, files)
+		_, err := ctx.DownloadMedia(MediaAny, 1<<10)
+		assert.ErrorIs(t, err, ErrNoMedia)
+		assert.Zero(t, *downloads)
+	})
+
+	t.Run("quoted and cached", func(t *testing.T) {
+		message := &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{
+			Text:        proto.String("!sticker"),
+			ContextInfo: &waE2E.ContextInfo{QuotedMessage: &waE2E.Message{ImageMessage: image}},
+		}}
+		ctx, downloads := testDownloadContext(t, message, files)
+		media, err := ctx.DownloadMedia(MediaImage, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, data, media.Data)
+		assert.Equal(t, MediaImage, media.Kind)
+		assert.True(t, media.Quoted)
+
+		// The second download is served by the cache
+		media, err = ctx.DownloadMedia(MediaImage, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, data, media.Data)
+		assert.Equal(t, 1, *downloads)
+	})
+
+	t.Run("view once", func(t *testing.T) {
+		message := &waE2E.Message{ViewOnceMessageV2: &waE2E.FutureProofMessage{Message: &waE2E.Message{ImageMessage: image}}}
+		ctx, downloads := testDownloadContext(t, message, files)
+		media, err := ctx.DownloadMedia(MediaImage, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, data, media.Data)
+		assert.False(t, media.Quoted)
+		assert.Equal(t, 1, *downloads)
+
+		_, ok := ctx.MediaCache.Get(key)
+		assert.False(t, ok, "view once media must not be cached")
+	})
+
+	t.Run("document with caption", func(t *testing.T) {
+		document := &waE2E.DocumentMessage{FileSHA256: image.GetFileSHA256(), Caption: proto.String("caption")}
+		message := &waE2E.Message{DocumentWithCaptionMessage: &waE2E.FutureProofMessage{Message: &waE2E.Message{DocumentMessage: document}}}
+		ctx, _ := testDownloadContext(t, message, files)
+		media, err := ctx.DownloadMedia(MediaDocument, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, data, media.Data)
+		assert.Equal(t, MediaDocument, media.Kind)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+		// Rejected by the size in the message, before downloading
+		ctx, downloads := testDownloadContext(t, &waE2E.Message{ImageMessage: image}, files)
+		_, err := ctx.DownloadMedia(MediaImage, 4)
+		assert.ErrorIs(t, err, ErrMediaTooLarge)
+		assert.Zero(t, *downloads)
+
+		// Cut off while downloading when the message doesn't tell the size
+		large := bytes.Repeat([]byte("x"), 1<<10)
+		unsized := &waE2E.ImageMessage{FileSHA256: sha256Sum(large)}
+		ctx, downloads = testDownloadContext(t, &waE2E.Message{ImageMessage: unsized}, map[string][]byte{hex.EncodeToString(sha256Sum(large)): large})
+		_, err = ctx.DownloadMedia(MediaImage, 4)
+		assert.ErrorIs(t, err, ErrMediaTooLarge)
+		assert.Equal(t, 1, *downloads)
+
+		// Or when the size in the message is wrong
+		lying := &waE2E.ImageMessage{FileSHA256: sha256Sum(large), FileLength: proto.Uint64(4)}
+		ctx, _ = testDownloadContext(t, &waE2E.Message{ImageMessage: lying}, map[string][]byte{hex.EncodeToString(sha256Sum(large)): large})
+		_, err = ctx.DownloadMedia(MediaImage, 4)
+		assert.ErrorIs(t, err, ErrMediaTooLarge)
+
+		// Media a few bytes over the limit fits in the file, but is still rejected
+		ctx, _ = testDownloadContext(t, &waE2E.Message{ImageMessage: &waE2E.ImageMessage{FileSHA256: image.GetFileSHA256()}}, files)
+		_, err = ctx.DownloadMedia(MediaImage, 4)
+		assert.ErrorIs(t, err, ErrMediaTooLarge)
+	})
+
+	t.Run("cache hit", func(t *testing.T) {
+		ctx, downloads := testDownloadContext(t, &waE2E.Message{ImageMessage: image}, nil)
+		require.NoError(t, ctx.MediaCache.Put(key, data))
+		media, err := ctx.DownloadMedia(MediaImage, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, data, media.Data)
+		assert.Zero(t, *downloads)
+
+		// A corrupted file is downloaded again
+		require.NoError(t, ctx.MediaCache.Put(key, []byte("corrupted")))
+		_, err = ctx.DownloadMedia(MediaImage, 1<<10)
+		assert.Error(t, err)
+		assert.Equal(t, 1, *downloads)
+	})
+}
+
+func TestLimitedFile(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "download")
+	require.NoError(t, err)
+	defer file.Close()
+	var cancelled bool
+	limited := &limitedFile{File: file, limit: 4, exceeded: func() { cancelled = true }}
+
+	_, err = io.Copy(limited, struct{ io.Reader }{bytes.NewReader([]byte("abc"))})
+	require.NoError(t, err)
+	assert.False(t, limited.tooLarge)
+
+	_, err = io.Copy(limited, struct{ io.Reader }{bytes.NewReader([]byte("defgh"))})
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+	assert.True(t, limited.tooLarge)
+	assert.True(t, cancelled)
+	info, err := file.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Size())
+
+	_, err = limited.WriteAt([]byte("xy"), 3)
+	assert.ErrorIs(t, err, ErrMediaTooLarge)
+}
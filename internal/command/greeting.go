@@ -0,0 +1,122 @@
+package command
+
+import (
+	"errors"
+	"meowabot/internal/database"
+	"meowabot/internal/tools/media"
+	"meowabot/internal/util"
+	"strconv"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// GreetingText renders the welcome or goodbye template of a group for a
+// member, falling back to the default message when the group has none.
+func (ctx *CommandContext) GreetingText(group *types.GroupInfo, settings *database.GreetingSettings, member types.JID, name string, leaving bool) string {
+	template := settings.Message
+	if template == "" {
+		if leaving {
+			template = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "greeting.goodbye",
+					Other: "👋 $name saiu do grupo. Agora somos $count membros.",
+				},
+			})
+		} else {
+			template = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "greeting.welcome",
+					Other: "👋 Olá $mention, seja bem-vindo(a) ao *$group*!\n\nAgora somos $count membros.",
+				},
+			})
+		}
+	}
+
+	if name == "" {
+		name = member.User
+	}
+	values := util.TimePlaceholders(time.Now())
+	values["name"] = name
+	values["mention"] = "@" + member.User
+	values["group"] = group.Name
+	values["count"] = strconv.Itoa(len(group.Participants))
+	values["desc"] = group.Topic
+	values["botname"] = ctx.Config.BotName
+	return util.ExpandPlaceholders(template, values)
+}
+
+// SendGreeting sends the welcome or goodbye message of a group. When the
+// settings ask for an image the message is sent as the caption of a card
+// with the member's profile picture, falling back to text if it fails.
+func (ctx *CommandContext) SendGreeting(group *types.GroupInfo, settings *database.GreetingSettings, member types.JID, name string, leaving bool) {
+	text := ctx.GreetingText(group, settings, member, name, leaving)
+	mention := []string{member.ToNonAD().String()}
+
+	if settings.Image {
+		card, err := ctx.greetingCard(group, member, name, leaving)
+		if err == nil {
+			ctx.SendImageMessage(group.JID, card, &MessageOptions{
+				Caption:      proto.String(text),
+				MentionedJid: mention,
+			})
+			return
+		}
+		ctx.Log.Error().Err(err).Str("GroupID", group.JID.User).Msg("Error generating greeting card")
+	}
+	ctx.SendTextMessage(group.JID, text, &MessageOptions{MentionedJid: mention})
+}
+
+func (ctx *CommandContext) greetingCard(group *types.GroupInfo, member types.JID, name string, leaving bool) ([]byte, error) {
+	card := &media.GreetingCard{
+		Color: media.DefaultCardColor,
+		Name:  name,
+	}
+	if card.Name == "" {
+		card.Name = "+" + member.User
+	}
+
+	avatar, err := ctx.GetProfilePicture(member.ToNonAD())
+	if err == nil {
+		card.Avatar = avatar
+	} else if !errors.Is(err, ErrNoProfilePicture) && !errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized) {
+		ctx.Log.Warn().Err(err).Str("User", member.User).Msg("Error getting profile picture")
+	}
+
+	if background, err := ctx.DB.GetCardBackground(group.JID.User); err == nil {
+		card.Background = background.Data
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if groupInfo, err := ctx.DB.GetGroupInfo(group.JID.User); err == nil && groupInfo.CardColor != "" {
+		if c, err := media.ParseHexColor(groupInfo.CardColor); err == nil {
+			card.Color = c
+		}
+	}
+
+	if leaving {
+		card.Title = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "greeting.card.goodbye", Other: "ATÉ LOGO!"},
+		})
+		card.Subtitle = group.Name
+	} else {
+		card.Title = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{ID: "greeting.card.welcome", Other: "BEM-VINDO(A)!"},
+		})
+		card.Subtitle = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "greeting.card.member",
+				Other: "Membro nº {{.Count}} de {{.Group}}",
+			},
+			TemplateData: map[string]any{
+				"Count": len(group.Participants),
+				"Group": group.Name,
+			},
+		})
+	}
+	return media.GenerateGreetingCard(card)
+}
@@ -21,6 +21,9 @@ type MessageOptions struct {
 
 	Seconds         *uint32
 	Mimetype        *string
+	PTT             bool
+	GifPlayback     bool
+	Waveform        []byte
 	ExternalAdReply *waProto.ContextInfo_ExternalAdReplyInfo
 }
 
@@ -48,7 +51,7 @@ func (ctx *CommandContext) DeleteMessage(chatID types.JID, senderJID types.JID,
 	}
 }
 
-func (ctx *CommandContext) SendTextMessage(to types.JID, text string, msgExtras *MessageOptions) {
+func (ctx *CommandContext) SendTextMessage(to types.JID, text string, msgExtras *MessageOptions) error {
 	message := &waProto.Message{
 		ExtendedTextMessage: &waProto.ExtendedTextMessage{
 			Text:        &text,
@@ -70,6 +73,7 @@ func (ctx *CommandContext) SendTextMessage(to types.JID, text string, msgExtras
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error sending text message")
 	}
+	return err
 }
 
 func (ctx *CommandContext) ReactMessage(message *events.Message, emoji string) {
@@ -86,11 +90,11 @@ func (ctx *CommandContext) ReactMessage(message *events.Message, emoji string) {
 	}
 }
 
-func (ctx *CommandContext) SendImageMessage(to types.JID, data []byte, msgExtras *MessageOptions) {
-	uploaded, err := ctx.Client.Upload(context.TODO(), data, whatsmeow.MediaImage)
+func (ctx *CommandContext) SendImageMessage(to types.JID, data []byte, msgExtras *MessageOptions) error {
+	uploaded, err := ctx.upload(data, whatsmeow.MediaImage)
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error uploading image")
-		return
+		return err
 	}
 
 	thumbnail, err := media.ResizeImg(data, 74, 74)
@@ -130,14 +134,14 @@ func (ctx *CommandContext) SendImageMessage(to types.JID, data []byte, msgExtras
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error sending image message")
 	}
-
+	return err
 }
 
-func (ctx *CommandContext) SendVideoMessage(to types.JID, data []byte, msgExtras *MessageOptions) {
-	uploaded, err := ctx.Client.Upload(context.TODO(), data, whatsmeow.MediaVideo)
+func (ctx *CommandContext) SendVideoMessage(to types.JID, data []byte, msgExtras *MessageOptions) error {
+	uploaded, err := ctx.upload(data, whatsmeow.MediaVideo)
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error uploading video")
-		return
+		return err
 	}
 
 	var thumbnail []byte
@@ -165,6 +169,10 @@ func (ctx *CommandContext) SendVideoMessage(to types.JID, data []byte, msgExtras
 		message.VideoMessage.ContextInfo.MentionedJID = msgExtras.MentionedJid
 		message.VideoMessage.Caption = msgExtras.Caption
 
+		if msgExtras.GifPlayback {
+			message.VideoMessage.GifPlayback = proto.Bool(true)
+		}
+
 		if msgExtras.QuotedMessage != nil {
 			message.VideoMessage.ContextInfo.StanzaID = &msgExtras.QuotedMessage.Info.ID
 			message.VideoMessage.ContextInfo.Participant = proto.String(msgExtras.QuotedMessage.Info.Sender.String())
@@ -179,14 +187,14 @@ func (ctx *CommandContext) SendVideoMessage(to types.JID, data []byte, msgExtras
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("error sending video message")
 	}
-
+	return err
 }
 
-func (ctx *CommandContext) SendDocumentMessage(to types.JID, data []byte, msgExtras *MessageOptions) {
-	uploaded, err := ctx.Client.Upload(context.TODO(), data, whatsmeow.MediaDocument)
+func (ctx *CommandContext) SendDocumentMessage(to types.JID, data []byte, msgExtras *MessageOptions) error {
+	uploaded, err := ctx.upload(data, whatsmeow.MediaDocument)
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error uploading document")
-		return
+		return err
 	}
 
 	message := &waProto.Message{
@@ -230,14 +238,14 @@ func (ctx *CommandContext) SendDocumentMessage(to types.JID, data []byte, msgExt
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("error sending video message")
 	}
-
+	return err
 }
 
-func (ctx *CommandContext) SendStickerMessage(to types.JID, data []byte, msgExtras *MessageOptions) {
-	uploaded, err := ctx.Client.Upload(context.TODO(), data, whatsmeow.MediaImage)
+func (ctx *CommandContext) SendStickerMessage(to types.JID, data []byte, msgExtras *MessageOptions) error {
+	uploaded, err := ctx.upload(data, whatsmeow.MediaImage)
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error uploading sticker")
-		return
+		return err
 	}
 
 	message := &waProto.Message{
@@ -267,13 +275,14 @@ func (ctx *CommandContext) SendStickerMessage(to types.JID, data []byte, msgExtr
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error sending sticker message")
 	}
+	return err
 }
 
-func (ctx *CommandContext) SendAudioMessage(to types.JID, data []byte, msgExtras *MessageOptions) {
-	uploaded, err := ctx.Client.Upload(context.TODO(), data, whatsmeow.MediaAudio)
+func (ctx *CommandContext) SendAudioMessage(to types.JID, data []byte, msgExtras *MessageOptions) error {
+	uploaded, err := ctx.upload(data, whatsmeow.MediaAudio)
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error uploading audio")
-		return
+		return err
 	}
 
 	message := &waProto.Message{
@@ -293,6 +302,12 @@ func (ctx *CommandContext) SendAudioMessage(to types.JID, data []byte, msgExtras
 		message.AudioMessage.ContextInfo.ExternalAdReply = msgExtras.ExternalAdReply
 		message.AudioMessage.ContextInfo.MentionedJID = msgExtras.MentionedJid
 		message.AudioMessage.Seconds = msgExtras.Seconds
+		message.AudioMessage.Waveform = msgExtras.Waveform
+
+		if msgExtras.PTT {
+			message.AudioMessage.PTT = proto.Bool(true)
+			message.AudioMessage.Mimetype = proto.String(media.VoiceNoteMimetype)
+		}
 
 		if msgExtras.QuotedMessage != nil {
 			message.AudioMessage.ContextInfo.StanzaID = &msgExtras.QuotedMessage.Info.ID
@@ -313,4 +328,5 @@ func (ctx *CommandContext) SendAudioMessage(to types.JID, data []byte, msgExtras
 	if err != nil {
 		ctx.Log.Error().Err(err).Msg("Error sending audio message")
 	}
+	return err
 }
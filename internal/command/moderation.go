@@ -0,0 +1,103 @@
+package command
+
+import (
+	"meowabot/internal/database"
+	"meowabot/internal/util"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const excerptLength = 200
+
+// LogModeration persists a moderation action and mirrors it to the group's
+// mod log chat, if one is configured. Must be called with the database lock
+// held. The mod log message is sent in the background, so the lock isn't held
+// while it's delivered.
+func (ctx *CommandContext) LogModeration(entry *database.ModerationLog) {
+	entry.Excerpt = util.Truncate(entry.Excerpt, excerptLength)
+	if err := ctx.DB.AddModerationLog(entry); err != nil {
+		ctx.Log.Error().Err(err).Str("GroupID", entry.GroupID).Str("Action", entry.Action).Msg("Error saving moderation log")
+		return
+	}
+
+	group, err := ctx.DB.GetGroupInfo(entry.GroupID)
+	if err != nil {
+		ctx.Log.Error().Err(err).Str("GroupID", entry.GroupID).Msg("Error retrieving group info from database")
+		return
+	}
+	if group.ModLogChat == "" {
+		return
+	}
+	chat, err := types.ParseJID(group.ModLogChat)
+	if err != nil {
+		ctx.Log.Error().Err(err).Str("GroupID", entry.GroupID).Msg("Invalid mod log chat")
+		return
+	}
+
+	groupName := entry.GroupID
+	if ctx.GroupMetadata != nil && ctx.GroupMetadata.JID.User == entry.GroupID {
+		groupName = ctx.GroupMetadata.Name
+	}
+
+	mentions := []string{types.NewJID(entry.TargetID, types.DefaultUserServer).String()}
+	if entry.ActorID != "" {
+		mentions = append(mentions, types.NewJID(entry.ActorID, types.DefaultUserServer).String())
+	}
+	text := ctx.FormatModerationLog(entry, groupName)
+	go func() {
+		if err := ctx.SendTextMessage(chat, text, &MessageOptions{MentionedJid: mentions}); err != nil {
+			ctx.Log.Error().Err(err).Str("GroupID", entry.GroupID).Msg("Error sending moderation log")
+		}
+	}()
+}
+
+// FormatModerationLog renders a moderation log entry as a localized message.
+func (ctx *CommandContext) FormatModerationLog(entry *database.ModerationLog, groupName string) string {
+	actor := "@" + entry.ActorID
+	if entry.Automatic || entry.ActorID == "" {
+		actor = ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "modlog.automatic",
+				Other: "🤖 automático",
+			},
+		})
+	}
+	text := ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "modlog.entry",
+			Other: "🛡️ *{{.Action}}* #{{.ID}}\n👥 Grupo: {{.Group}}\n👤 Alvo: @{{.Target}}\n👮 Por: {{.Actor}}\n🕒 {{.Date}}",
+		},
+		TemplateData: map[string]any{
+			"ID":     entry.ID,
+			"Action": entry.Action,
+			"Group":  groupName,
+			"Target": entry.TargetID,
+			"Actor":  actor,
+			"Date":   entry.CreatedAt.Format("02/01/2006 15:04"),
+		},
+	})
+	if entry.Reason != "" {
+		text += "\n" + ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "modlog.reason",
+				Other: "📋 Motivo: {{.Reason}}",
+			},
+			TemplateData: map[string]any{
+				"Reason": entry.Reason,
+			},
+		})
+	}
+	if entry.Excerpt != "" {
+		text += "\n" + ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "modlog.excerpt",
+				Other: "💬 Mensagem: {{.Excerpt}}",
+			},
+			TemplateData: map[string]any{
+				"Excerpt": entry.Excerpt,
+			},
+		})
+	}
+	return text
+}
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"encoding/json"
+	"meowabot/internal/database"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// JobUnmute is the scheduler job that lifts a mute when it expires.
+const JobUnmute = "unmute"
+
+// UnmutePayload is the payload of the unmute jobs.
+type UnmutePayload struct {
+	GroupID string `json:"group_id"`
+	UserID  string `json:"user_id"`
+}
+
+func unmuteJobKey(groupID string, userID string) string {
+	return JobUnmute + ":" + groupID + ":" + userID
+}
+
+// ScheduleUnmute schedules the end of a mute, replacing the previous one of
+// the same member. Must be called with the database lock held.
+func (ctx *CommandContext) ScheduleUnmute(group types.JID, userID string, until time.Time) error {
+	payload, err := json.Marshal(UnmutePayload{GroupID: group.User, UserID: userID})
+	if err != nil {
+		return err
+	}
+	return ctx.Scheduler.Schedule(&database.Job{
+		Name:    JobUnmute,
+		Key:     unmuteJobKey(group.User, userID),
+		ChatID:  group.String(),
+		Payload: string(payload),
+		RunAt:   until,
+	})
+}
+
+// CancelUnmute cancels the scheduled end of a mute lifted by hand. Must be
+// called with the database lock held.
+func (ctx *CommandContext) CancelUnmute(group types.JID, userID string) error {
+	return ctx.Scheduler.CancelKey(unmuteJobKey(group.User, userID))
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const profilePictureTimeout = 15 * time.Second
+
+// maxProfilePictureSize bounds the download of a profile picture.
+const maxProfilePictureSize = 5 << 20
+
+var ErrNoProfilePicture = fmt.Errorf("no profile picture")
+
+// GetProfilePicture downloads the full size profile picture of a user or
+// group. It returns ErrNoProfilePicture if there is none or it is hidden.
+func (ctx *CommandContext) GetProfilePicture(jid types.JID) ([]byte, error) {
+	info, err := ctx.Client.GetProfilePictureInfo(jid, &whatsmeow.GetProfilePictureParams{})
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || info.URL == "" {
+		return nil, ErrNoProfilePicture
+	}
+
+	reqCtx, cancel := context.WithTimeout(context.Background(), profilePictureTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, info.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status downloading profile picture: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxProfilePictureSize))
+}
@@ -1,13 +1,17 @@
 package command
 
 import (
+	"context"
 	"fmt"
 	"meowabot/internal/config"
 	"meowabot/internal/database"
+	"meowabot/internal/scheduler"
+	"meowabot/internal/tools/mediacache"
 
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/rs/zerolog"
 	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
@@ -24,6 +28,18 @@ type CommandContext struct {
 	Command   string
 	Localizer *i18n.Localizer
 	Log       *zerolog.Logger
+	Scheduler *scheduler.Scheduler
+	// MediaCache caches downloads and uploads, it can be nil
+	MediaCache *mediacache.Cache
+	// downloadFunc replaces Client.DownloadToFile in tests
+	downloadFunc func(context.Context, whatsmeow.DownloadableMessage, whatsmeow.File) error
+
+	IsOwner         bool
+	IsGroupAdmin    bool
+	IsBotGroupAdmin bool
+	UserInfo        *database.User
+	GroupInfo       *database.Group
+	GroupMetadata   *types.GroupInfo
 }
 
 type Requirements struct {
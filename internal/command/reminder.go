@@ -0,0 +1,64 @@
+package command
+
+import (
+	"encoding/json"
+	"meowabot/internal/database"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// JobReminder is the scheduler job that delivers a reminder.
+const JobReminder = "reminder"
+
+// ReminderPayload is the payload of the reminder jobs. The message that
+// created the reminder is kept so the delivery can quote it.
+type ReminderPayload struct {
+	Target    string `json:"target"`
+	Text      string `json:"text"`
+	MessageID string `json:"message_id,omitempty"`
+	Sender    string `json:"sender,omitempty"`
+	Body      string `json:"body,omitempty"`
+}
+
+// ScheduleReminder schedules a reminder for the target user created by the
+// command message. Must be called with the database lock held.
+func (ctx *CommandContext) ScheduleReminder(target types.JID, text string, at time.Time) (*database.Job, error) {
+	payload, err := json.Marshal(ReminderPayload{
+		Target:    target.ToNonAD().String(),
+		Text:      text,
+		MessageID: ctx.Msg.Info.ID,
+		Sender:    ctx.Msg.Info.Sender.ToNonAD().String(),
+		Body:      ctx.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	job := &database.Job{
+		Name:      JobReminder,
+		ChatID:    ctx.Msg.Info.Chat.String(),
+		CreatorID: ctx.Msg.Info.Sender.User,
+		Payload:   string(payload),
+		RunAt:     at,
+	}
+	return job, ctx.Scheduler.Schedule(job)
+}
+
+// QuotedMessage rebuilds the message that created the reminder, so it can be
+// quoted when the reminder is delivered. It returns nil if it wasn't kept.
+func (p *ReminderPayload) QuotedMessage(chat types.JID) *events.Message {
+	sender, err := types.ParseJID(p.Sender)
+	if p.MessageID == "" || err != nil {
+		return nil
+	}
+	return &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: chat, Sender: sender},
+			ID:            p.MessageID,
+		},
+		Message: &waE2E.Message{Conversation: proto.String(p.Body)},
+	}
+}
@@ -19,6 +19,10 @@ type ConfigScheme struct {
 	StickerAuthor string   `mapstructure:"stickerauthor"`
 	Language      string   `mapstructure:"language"`
 	PairWithCode  bool     `mapstructure:"pairwithcode"`
+	VoiceCodec    string   `mapstructure:"voicecodec"`
+	VoiceBitrate  string   `mapstructure:"voicebitrate"`
+	AudioBitrate  string   `mapstructure:"audiobitrate"`
+	MediaCacheMB  int64    `mapstructure:"mediacachemb"`
 
 	v *viper.Viper
 }
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm/clause"
+)
+
+// SaveAnnouncementMedia stores the media of an announcement and returns the
+// hash it can be loaded with.
+func (d *DBInstance) SaveAnnouncementMedia(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	err := d.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&AnnouncementMedia{Hash: hash, Data: data}).Error
+	return hash, err
+}
+
+func (d *DBInstance) GetAnnouncementMedia(hash string) ([]byte, error) {
+	var media AnnouncementMedia
+	if err := d.db.Where("hash = ?", hash).First(&media).Error; err != nil {
+		return nil, err
+	}
+	return media.Data, nil
+}
+
+// DeleteUnusedAnnouncementMedia deletes the media no job refers to anymore.
+// The job ignoreJobID doesn't count, so a job that is about to be deleted can
+// clean up after itself. Zero counts every job.
+func (d *DBInstance) DeleteUnusedAnnouncementMedia(ignoreJobID uint64) error {
+	// hash isn't a column of the jobs, so it refers to the media being deleted
+	used := d.db.Model(&Job{}).Select("1").Where("id <> ? AND instr(payload, hash) > 0", ignoreJobID)
+	return d.db.Where("NOT EXISTS (?)", used).Delete(&AnnouncementMedia{}).Error
+}
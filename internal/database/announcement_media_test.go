@@ -0,0 +1,44 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAnnouncementMedia(t *testing.T) {
+	db := setupTestDB(t)
+
+	hash, err := db.SaveAnnouncementMedia([]byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.Len(t, hash, 64)
+
+	// The same media is stored once
+	again, err := db.SaveAnnouncementMedia([]byte{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, hash, again)
+
+	data, err := db.GetAnnouncementMedia(hash)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, data)
+
+	unused, err := db.SaveAnnouncementMedia([]byte{4, 5})
+	require.NoError(t, err)
+
+	job := &Job{Name: "announcement", Payload: `{"media_hash":"` + hash + `"}`, RunAt: time.Now()}
+	require.NoError(t, db.CreateJob(job))
+
+	require.NoError(t, db.DeleteUnusedAnnouncementMedia(0))
+	_, err = db.GetAnnouncementMedia(hash)
+	require.NoError(t, err)
+	_, err = db.GetAnnouncementMedia(unused)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// Ignoring the only job that refers to the media deletes it
+	require.NoError(t, db.DeleteUnusedAnnouncementMedia(job.ID))
+	_, err = db.GetAnnouncementMedia(hash)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
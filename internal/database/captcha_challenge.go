@@ -0,0 +1,27 @@
+package database
+
+import "time"
+
+func (d *DBInstance) GetCaptchaChallenge(groupID string, userID string) (*CaptchaChallenge, error) {
+	var challenge CaptchaChallenge
+	err := d.db.Where("group_id = ? AND user_id = ?", groupID, userID).First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (d *DBInstance) SaveCaptchaChallenge(challenge *CaptchaChallenge) error {
+	return d.db.Save(challenge).Error
+}
+
+func (d *DBInstance) DeleteCaptchaChallenge(groupID string, userID string) error {
+	return d.db.Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&CaptchaChallenge{}).Error
+}
+
+// GetExpiredCaptchaChallenges returns the challenges that were not answered before the given time.
+func (d *DBInstance) GetExpiredCaptchaChallenges(before time.Time) ([]CaptchaChallenge, error) {
+	var challenges = []CaptchaChallenge{}
+	err := d.db.Where("expires_at <= ?", before).Find(&challenges).Error
+	return challenges, err
+}
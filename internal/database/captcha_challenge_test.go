@@ -0,0 +1,67 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCaptchaChallenge(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group1"
+	userID := "user1"
+
+	_, err := db.GetCaptchaChallenge(groupID, userID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	challenge := &CaptchaChallenge{
+		GroupID:   groupID,
+		UserID:    userID,
+		Answer:    "42",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+	require.NoError(t, db.SaveCaptchaChallenge(challenge))
+
+	challenge.Attempts++
+	require.NoError(t, db.SaveCaptchaChallenge(challenge))
+
+	saved, err := db.GetCaptchaChallenge(groupID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "42", saved.Answer)
+	assert.Equal(t, 1, saved.Attempts)
+
+	require.NoError(t, db.DeleteCaptchaChallenge(groupID, userID))
+	_, err = db.GetCaptchaChallenge(groupID, userID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestGetExpiredCaptchaChallenges(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+
+	require.NoError(t, db.SaveCaptchaChallenge(&CaptchaChallenge{GroupID: "group1", UserID: "expired", Answer: "1", ExpiresAt: now.Add(-time.Minute)}))
+	require.NoError(t, db.SaveCaptchaChallenge(&CaptchaChallenge{GroupID: "group1", UserID: "pending", Answer: "2", ExpiresAt: now.Add(time.Minute)}))
+
+	expired, err := db.GetExpiredCaptchaChallenges(now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "expired", expired[0].UserID)
+}
+
+func TestGroupCaptchaSettingsDefaults(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "testgroup_captcha"
+
+	_, err := db.GetGroupInfo(groupID)
+	require.NoError(t, err)
+
+	group, err := db.GetGroupInfo(groupID)
+	require.NoError(t, err)
+	assert.False(t, group.Captcha.Enabled)
+	assert.Equal(t, "math", group.Captcha.Mode)
+	assert.Equal(t, 1, group.Captcha.Difficulty)
+	assert.Equal(t, 5*time.Minute, group.Captcha.Timeout())
+}
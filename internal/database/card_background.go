@@ -0,0 +1,18 @@
+package database
+
+func (d *DBInstance) GetCardBackground(groupID string) (*CardBackground, error) {
+	var background CardBackground
+	err := d.db.Where("group_id = ?", groupID).First(&background).Error
+	if err != nil {
+		return nil, err
+	}
+	return &background, nil
+}
+
+func (d *DBInstance) SaveCardBackground(background *CardBackground) error {
+	return d.db.Save(background).Error
+}
+
+func (d *DBInstance) DeleteCardBackground(groupID string) error {
+	return d.db.Where("group_id = ?", groupID).Delete(&CardBackground{}).Error
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCardBackground(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group1"
+
+	_, err := db.GetCardBackground(groupID)
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, db.SaveCardBackground(&CardBackground{GroupID: groupID, Data: []byte{1, 2, 3}}))
+	require.NoError(t, db.SaveCardBackground(&CardBackground{GroupID: groupID, Data: []byte{4, 5}}))
+
+	background, err := db.GetCardBackground(groupID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{4, 5}, background.Data)
+
+	require.NoError(t, db.DeleteCardBackground(groupID))
+	_, err = db.GetCardBackground(groupID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
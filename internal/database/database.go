@@ -22,11 +22,19 @@ func NewDB(dialector gorm.Dialector, config *gorm.Config) (*DBInstance, error) {
 		&GroupParticipant{},
 		&Feed{},
 		&FeedSubscriptions{},
-		
+		&ModerationLog{},
+		&ModerationRule{},
+		&CaptchaChallenge{},
+		&CardBackground{},
+		&Job{},
+		&AnnouncementMedia{},
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := migrateAllowedDDIS(db); err != nil {
+		return nil, err
+	}
 	return &DBInstance{db: db}, nil
 }
 
@@ -2,6 +2,7 @@ package database
 
 import (
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -22,6 +23,47 @@ func (d *DBInstance) GetGroupInfo(groupID string) (*Group, error) {
 	return &group, nil
 }
 
+func (f *FloodSettings) Window() time.Duration {
+	return time.Duration(f.WindowSeconds) * time.Second
+}
+
+func (c *CaptchaSettings) Timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// Location returns the timezone of the schedule, falling back to UTC when it
+// isn't a known timezone.
+func (n *NightSettings) Location() *time.Location {
+	return loadLocation(n.Timezone)
+}
+
+func loadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsNight reports whether the group should be closed at the given time. The
+// window wraps around midnight when CloseAt is later than OpenAt.
+func (n *NightSettings) IsNight(t time.Time) bool {
+	t = t.In(n.Location())
+	minute := t.Hour()*60 + t.Minute()
+	if n.CloseAt <= n.OpenAt {
+		return minute >= n.CloseAt && minute < n.OpenAt
+	}
+	return minute >= n.CloseAt || minute < n.OpenAt
+}
+
+// GetNightModeGroups returns the groups with night mode enabled and the ones
+// the bot closed and still has to reopen.
+func (d *DBInstance) GetNightModeGroups() ([]Group, error) {
+	var groups []Group
+	err := d.db.Where("night_enabled = ? OR night_closed = ?", true, true).Find(&groups).Error
+	return groups, err
+}
+
 func (d *DBInstance) SaveGroupInfo(groupInfo *Group) error {
 	return d.db.Save(groupInfo).Error
 }
@@ -1,6 +1,11 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"gorm.io/gorm"
+)
+
+// MaxWarnCount is the number of warnings after which a member is removed.
+const MaxWarnCount = 3
 
 func (d *DBInstance) GetParticipant(userID string, groupID string) (*GroupParticipant, error) {
 	var participant = GroupParticipant{}
@@ -19,6 +24,21 @@ func (d *DBInstance) DeleteParticipant(member *GroupParticipant) error {
 	return d.db.Where(member).Delete(&GroupParticipant{}).Error
 }
 
+// IsBlacklisted reports whether a user is blacklisted in a group, without
+// creating a participant row for users who aren't members.
+func (d *DBInstance) IsBlacklisted(userID string, groupID string) (bool, error) {
+	var count int64
+	err := d.db.Model(&GroupParticipant{}).Where("group_id = ? AND user_id = ? AND is_blacklisted = ?", groupID, userID, true).Count(&count).Error
+	return count > 0, err
+}
+
+// GetMutedParticipants returns the participants with a mute that wasn't lifted yet.
+func (d *DBInstance) GetMutedParticipants() ([]GroupParticipant, error) {
+	var members = []GroupParticipant{}
+	err := d.db.Where("muted_until IS NOT NULL").Find(&members).Error
+	return members, err
+}
+
 func (d *DBInstance) GetAllParticipants(groupID string) ([]GroupParticipant, error) {
 	var members = []GroupParticipant{}
 	err := d.db.Where(&GroupParticipant{GroupID: groupID}).Find(&members).Error
@@ -55,7 +75,7 @@ func (d *DBInstance) UpdateGroupParticipants(groupID string, participantIDs []st
 		}
 
 		if len(toDelete) > 0 {
-			if err := tx.Where("group_id = ? AND user_id IN ?", groupID, toDelete).Delete(&GroupParticipant{}).Error; err != nil {
+			if err := tx.Where("group_id = ? AND user_id IN ? AND is_blacklisted = ?", groupID, toDelete, false).Delete(&GroupParticipant{}).Error; err != nil {
 				return err
 			}
 		}
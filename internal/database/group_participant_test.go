@@ -4,6 +4,7 @@ package database
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -219,3 +220,81 @@ func TestUpdateGroupParticipants(t *testing.T) {
 	require.Len(t, participants, 1)
 	assert.Equal(t, "userE", participants[0].UserID)
 }
+
+func TestUpdateGroupParticipantsKeepsBlacklisted(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group6"
+
+	err := db.UpdateGroupParticipants(groupID, []string{"userA", "userB"})
+	require.NoError(t, err)
+
+	participant, err := db.GetParticipant("userB", groupID)
+	require.NoError(t, err)
+	participant.IsBlacklisted = true
+	require.NoError(t, db.SaveParticipant(participant))
+
+	err = db.UpdateGroupParticipants(groupID, []string{"userC"})
+	require.NoError(t, err)
+
+	participants, err := db.GetAllParticipants(groupID)
+	require.NoError(t, err)
+	participantMap := make(map[string]GroupParticipant)
+	for _, p := range participants {
+		participantMap[p.UserID] = p
+	}
+	assert.NotContains(t, participantMap, "userA")
+	assert.Contains(t, participantMap, "userC")
+	require.Contains(t, participantMap, "userB")
+	assert.True(t, participantMap["userB"].IsBlacklisted)
+}
+
+func TestGetMutedParticipants(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group7"
+	now := time.Now()
+
+	for userID, until := range map[string]time.Time{
+		"expired": now.Add(-time.Minute),
+		"active":  now.Add(time.Hour),
+	} {
+		participant, err := db.GetParticipant(userID, groupID)
+		require.NoError(t, err)
+		participant.MutedUntil = &until
+		require.NoError(t, db.SaveParticipant(participant))
+	}
+	_, err := db.GetParticipant("never", groupID)
+	require.NoError(t, err)
+
+	muted, err := db.GetMutedParticipants()
+	require.NoError(t, err)
+	require.Len(t, muted, 2)
+
+	muted[0].MutedUntil = nil
+	require.NoError(t, db.SaveParticipant(&muted[0]))
+	muted, err = db.GetMutedParticipants()
+	require.NoError(t, err)
+	assert.Len(t, muted, 1)
+}
+
+func TestIsBlacklisted(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group_blacklist"
+
+	participant, err := db.GetParticipant("banned", groupID)
+	require.NoError(t, err)
+	participant.IsBlacklisted = true
+	require.NoError(t, db.SaveParticipant(participant))
+
+	blacklisted, err := db.IsBlacklisted("banned", groupID)
+	require.NoError(t, err)
+	assert.True(t, blacklisted)
+
+	blacklisted, err = db.IsBlacklisted("stranger", groupID)
+	require.NoError(t, err)
+	assert.False(t, blacklisted)
+
+	// Checking a stranger doesn't make them a participant
+	members, err := db.GetAllParticipants(groupID)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+}
@@ -4,6 +4,7 @@ package database
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -97,3 +98,78 @@ func TestGetGroupInfo_ErrorOnDBFailure(t *testing.T) {
 	require.Error(t, err)
 	require.Nil(t, group)
 }
+
+func TestGroupFloodSettingsDefaults(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "testgroup_flood"
+
+	group, err := db.GetGroupInfo(groupID)
+	require.NoError(t, err)
+	require.False(t, group.Flood.Enabled)
+
+	// Defaults are filled in by the database, so read the group back
+	group, err = db.GetGroupInfo(groupID)
+	require.NoError(t, err)
+	require.Equal(t, 8, group.Flood.Messages)
+	require.Equal(t, 10*time.Second, group.Flood.Window())
+	require.Equal(t, 10*time.Minute, group.Flood.MuteDuration)
+
+	group.Flood.Enabled = true
+	group.Flood.Repeats = 4
+	require.NoError(t, db.SaveGroupInfo(group))
+
+	group, err = db.GetGroupInfo(groupID)
+	require.NoError(t, err)
+	require.True(t, group.Flood.Enabled)
+	require.Equal(t, 4, group.Flood.Repeats)
+}
+
+func TestNightSettingsIsNight(t *testing.T) {
+	night := NightSettings{CloseAt: 22 * 60, OpenAt: 7 * 60, Timezone: "America/Sao_Paulo"}
+	at := func(hour, minute int) time.Time {
+		// São Paulo is UTC-3 all year round
+		return time.Date(2025, 6, 1, hour+3, minute, 0, 0, time.UTC)
+	}
+	require.False(t, night.IsNight(at(21, 59)))
+	require.True(t, night.IsNight(at(22, 0)))
+	require.True(t, night.IsNight(at(2, 0)))
+	require.True(t, night.IsNight(at(6, 59)))
+	require.False(t, night.IsNight(at(7, 0)))
+
+	// A window that doesn't wrap around midnight
+	night.CloseAt, night.OpenAt = 12*60, 14*60
+	require.False(t, night.IsNight(at(11, 0)))
+	require.True(t, night.IsNight(at(13, 0)))
+	require.False(t, night.IsNight(at(14, 0)))
+
+	// Unknown timezones fall back to UTC
+	night.Timezone = "Mars/Olympus_Mons"
+	require.True(t, night.IsNight(time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC)))
+}
+
+func TestGetNightModeGroups(t *testing.T) {
+	db := setupTestDB(t)
+
+	for _, id := range []string{"night_on", "night_closed", "night_off"} {
+		_, err := db.GetGroupInfo(id)
+		require.NoError(t, err)
+	}
+	group, err := db.GetGroupInfo("night_on")
+	require.NoError(t, err)
+	require.Equal(t, 22*60, group.Night.CloseAt)
+	group.Night.Enabled = true
+	require.NoError(t, db.SaveGroupInfo(group))
+
+	group, err = db.GetGroupInfo("night_closed")
+	require.NoError(t, err)
+	group.Night.Closed = true
+	require.NoError(t, db.SaveGroupInfo(group))
+
+	groups, err := db.GetNightModeGroups()
+	require.NoError(t, err)
+	var ids []string
+	for _, g := range groups {
+		ids = append(ids, g.ID)
+	}
+	require.ElementsMatch(t, []string{"night_on", "night_closed"}, ids)
+}
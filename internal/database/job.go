@@ -0,0 +1,95 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func (d *DBInstance) GetJob(id uint64) (*Job, error) {
+	var job Job
+	if err := d.db.First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetJobs returns the jobs run by the given handler, or every job if name is
+// empty, in the order they will run.
+func (d *DBInstance) GetJobs(name string) ([]Job, error) {
+	var jobs = []Job{}
+	query := d.db.Order("run_at, id")
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+	err := query.Find(&jobs).Error
+	return jobs, err
+}
+
+// GetChatJobs returns the jobs of a handler that belong to a chat.
+func (d *DBInstance) GetChatJobs(name string, chatID string) ([]Job, error) {
+	var jobs = []Job{}
+	err := d.db.Where("name = ? AND chat_id = ?", name, chatID).Order("run_at, id").Find(&jobs).Error
+	return jobs, err
+}
+
+// CreateJob inserts a job, replacing the job with the same key if it has one.
+func (d *DBInstance) CreateJob(job *Job) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if job.Key != "" {
+			if err := tx.Where("key = ?", job.Key).Delete(&Job{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(job).Error
+	})
+}
+
+func (d *DBInstance) DeleteJob(id uint64) error {
+	return d.db.Delete(&Job{}, id).Error
+}
+
+func (d *DBInstance) DeleteJobByKey(key string) error {
+	return d.db.Where("key = ?", key).Delete(&Job{}).Error
+}
+
+// ClaimDueJobs locks up to limit jobs due at the given time until the lease
+// expires. Jobs locked by a run that crashed become due again once their
+// lease expires, so every job runs at least once.
+func (d *DBInstance) ClaimDueJobs(now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	var claimed []Job
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		var due []Job
+		err := tx.Where("run_at <= ? AND (locked_until IS NULL OR locked_until <= ?)", now, now).
+			Order("run_at, id").Limit(limit).Find(&due).Error
+		if err != nil {
+			return err
+		}
+		until := now.Add(lease)
+		for _, job := range due {
+			result := tx.Model(&Job{}).
+				Where("id = ? AND (locked_until IS NULL OR locked_until <= ?)", job.ID, now).
+				Update("locked_until", until)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				job.LockedUntil = &until
+				claimed = append(claimed, job)
+			}
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+// RescheduleJob releases a claimed job to run again at the given time. Jobs
+// deleted while they ran are not recreated.
+func (d *DBInstance) RescheduleJob(id uint64, runAt time.Time, attempts int, lastError string) error {
+	return d.db.Model(&Job{}).Where("id = ?", id).Updates(map[string]any{
+		"run_at":       runAt,
+		"locked_until": nil,
+		"attempts":     attempts,
+		"last_error":   lastError,
+	}).Error
+}
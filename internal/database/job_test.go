@@ -0,0 +1,69 @@
+//go:build !integration
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimDueJobs(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+
+	due := &Job{Name: "a", RunAt: now.Add(-time.Minute)}
+	later := &Job{Name: "b", RunAt: now.Add(time.Hour)}
+	require.NoError(t, db.CreateJob(due))
+	require.NoError(t, db.CreateJob(later))
+
+	claimed, err := db.ClaimDueJobs(now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, due.ID, claimed[0].ID)
+	require.NotNil(t, claimed[0].LockedUntil)
+
+	// A claimed job can't be claimed again until its lease expires
+	claimed, err = db.ClaimDueJobs(now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	claimed, err = db.ClaimDueJobs(now.Add(time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 1)
+
+	// Rescheduling releases the lock
+	require.NoError(t, db.RescheduleJob(due.ID, now.Add(-time.Second), 2, "failed"))
+	job, err := db.GetJob(due.ID)
+	require.NoError(t, err)
+	assert.Nil(t, job.LockedUntil)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "failed", job.LastError)
+
+	// Rescheduling a deleted job doesn't bring it back
+	require.NoError(t, db.DeleteJob(due.ID))
+	require.NoError(t, db.RescheduleJob(due.ID, now, 0, ""))
+	_, err = db.GetJob(due.ID)
+	assert.Error(t, err)
+}
+
+func TestGetChatJobs(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Now()
+
+	require.NoError(t, db.CreateJob(&Job{Name: "remind", ChatID: "chat1", RunAt: now.Add(time.Hour)}))
+	require.NoError(t, db.CreateJob(&Job{Name: "remind", ChatID: "chat1", RunAt: now.Add(time.Minute)}))
+	require.NoError(t, db.CreateJob(&Job{Name: "remind", ChatID: "chat2", RunAt: now}))
+	require.NoError(t, db.CreateJob(&Job{Name: "other", ChatID: "chat1", RunAt: now}))
+
+	jobs, err := db.GetChatJobs("remind", "chat1")
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.True(t, jobs[0].RunAt.Before(jobs[1].RunAt))
+
+	all, err := db.GetJobs("")
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"meowabot/internal/phone"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// migrateAllowedDDIS converts the comma-separated country code prefixes that
+// groups used to filter members into country rules. Prefixes longer than a
+// calling code, like 1242, become area rules.
+func migrateAllowedDDIS(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&Group{}, "allowed_ddis") {
+		return nil
+	}
+	var groups []struct {
+		ID          string
+		AllowedDDIS string
+	}
+	if err := db.Model(&Group{}).Select("id", "allowed_ddis").Where("allowed_ddis <> ''").Scan(&groups).Error; err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, g := range groups {
+			var rules []CountryRule
+			for ddi := range strings.SplitSeq(g.AllowedDDIS, ",") {
+				if ddi = strings.TrimSpace(ddi); ddi == "" {
+					continue
+				}
+				rule := CountryRule{Allow: true, Code: ddi}
+				if !phone.IsCallingCode(ddi) {
+					if n, ok := phone.Parse(ddi); ok {
+						rule.Code = n.Country.CallingCode
+						rule.Area = n.National
+					}
+				}
+				rules = append(rules, rule)
+			}
+			if err := tx.Model(&Group{ID: g.ID}).Select("CountryRules").Updates(&Group{CountryRules: rules}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Group{ID: g.ID}).Update("allowed_ddis", "").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
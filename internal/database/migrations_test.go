@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateAllowedDDIS(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.db.Exec("ALTER TABLE `group` ADD COLUMN allowed_ddis text NOT NULL DEFAULT ''").Error)
+
+	_, err := db.GetGroupInfo("legacy")
+	require.NoError(t, err)
+	_, err = db.GetGroupInfo("untouched")
+	require.NoError(t, err)
+	require.NoError(t, db.db.Exec("UPDATE `group` SET allowed_ddis = ? WHERE id = ?", "55,1242", "legacy").Error)
+
+	require.NoError(t, migrateAllowedDDIS(db.db))
+
+	group, err := db.GetGroupInfo("legacy")
+	require.NoError(t, err)
+	assert.Equal(t, []CountryRule{
+		{Allow: true, Code: "55"},
+		{Allow: true, Code: "1", Area: "242"},
+	}, group.CountryRules)
+
+	group, err = db.GetGroupInfo("untouched")
+	require.NoError(t, err)
+	assert.Empty(t, group.CountryRules)
+
+	// The old column is cleared so the migration only runs once
+	var remaining int64
+	require.NoError(t, db.db.Table("group").Where("allowed_ddis <> ''").Count(&remaining).Error)
+	assert.Zero(t, remaining)
+}
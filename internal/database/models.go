@@ -13,17 +13,70 @@ type User struct {
 	Language           string `gorm:"default:'';not null"`
 	StickerDescription string `gorm:"default:'';not null"`
 	StickerTitle       string `gorm:"default:'';not null"`
+	Timezone           string `gorm:"default:'America/Sao_Paulo';not null"`
 }
 
 type Group struct {
-	ID                string `gorm:"column:id;primaryKey"`
-	AllowedDDIS       string `gorm:"default:'';not null"`
-	AutoDownloadMedia bool   `gorm:"default:false;not null"`
-	IsAntiLink        bool   `gorm:"default:false;not null"`
-	IsAntiWALink      bool   `gorm:"default:false;not null"`
-	IsBotDisabled     bool   `gorm:"default:false;not null"`
-	Language          string `gorm:"default:'';not null"`
-	RemoveUser        bool   `gorm:"default:true;not null"`
+	ID                string        `gorm:"column:id;primaryKey"`
+	AutoDownloadMedia bool          `gorm:"default:false;not null"`
+	CardColor         string        `gorm:"default:'';not null"`
+	IsAntiLink        bool          `gorm:"default:false;not null"`
+	IsAntiWALink      bool          `gorm:"default:false;not null"`
+	IsBotDisabled     bool          `gorm:"default:false;not null"`
+	JoinPolicy        string        `gorm:"default:'';not null"`
+	Language          string        `gorm:"default:'';not null"`
+	LinkAllowlist     string        `gorm:"default:'';not null"`
+	LinkBlocklist     string        `gorm:"default:'';not null"`
+	ModLogChat        string        `gorm:"default:'';not null"`
+	CountryRules      []CountryRule `gorm:"serializer:json"`
+	RemoveUser        bool          `gorm:"default:true;not null"`
+
+	Flood   FloodSettings    `gorm:"embedded;embeddedPrefix:flood_"`
+	Captcha CaptchaSettings  `gorm:"embedded;embeddedPrefix:captcha_"`
+	Welcome GreetingSettings `gorm:"embedded;embeddedPrefix:welcome_"`
+	Goodbye GreetingSettings `gorm:"embedded;embeddedPrefix:goodbye_"`
+	Night   NightSettings    `gorm:"embedded;embeddedPrefix:night_"`
+}
+
+// CountryRule allows or denies phone numbers by country, calling code or
+// area code. Exactly one of Country and Code is set.
+type CountryRule struct {
+	Allow   bool   `json:"allow"`
+	Country string `json:"country,omitempty"` // ISO 3166-1 alpha-2 code
+	Code    string `json:"code,omitempty"`    // Calling code, matching every country that shares it
+	Area    string `json:"area,omitempty"`    // Leading digits of the national number
+}
+
+type FloodSettings struct {
+	Enabled       bool          `gorm:"default:false;not null"`
+	Messages      int           `gorm:"default:8;not null"`
+	WindowSeconds int           `gorm:"default:10;not null"`
+	Repeats       int           `gorm:"default:3;not null"`
+	Media         int           `gorm:"default:5;not null"`
+	MuteDuration  time.Duration `gorm:"default:600000000000;not null"`
+}
+
+type CaptchaSettings struct {
+	Enabled        bool   `gorm:"default:false;not null"`
+	Mode           string `gorm:"default:'math';not null"`
+	Difficulty     int    `gorm:"default:1;not null"`
+	TimeoutSeconds int    `gorm:"default:300;not null"`
+}
+
+type GreetingSettings struct {
+	Enabled bool   `gorm:"default:false;not null"`
+	Message string `gorm:"default:'';not null"`
+	Image   bool   `gorm:"default:false;not null"`
+}
+
+// NightSettings closes the group to admins-only messages every day between
+// CloseAt and OpenAt, both in minutes after midnight in the group's timezone.
+type NightSettings struct {
+	Enabled  bool   `gorm:"default:false;not null"`
+	CloseAt  int    `gorm:"default:1320;not null"`
+	OpenAt   int    `gorm:"default:420;not null"`
+	Timezone string `gorm:"default:'America/Sao_Paulo';not null"`
+	Closed   bool   `gorm:"default:false;not null"` // The bot closed the group and has to reopen it
 }
 
 type GroupParticipant struct {
@@ -33,6 +86,8 @@ type GroupParticipant struct {
 	CommandCount  uint64 `gorm:"default:0;not null"`
 	WarnCount     uint8  `gorm:"default:0;not null"`
 	IsBlacklisted bool   `gorm:"default:false;not null"`
+	JoinedAt      *time.Time
+	MutedUntil    *time.Time
 
 	Group Group `gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	User  User  `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
@@ -51,3 +106,95 @@ type FeedSubscriptions struct {
 	Feed  Feed  `gorm:"foreignKey:FeedID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Group Group `gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
+
+type ModerationLog struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	GroupID   string    `gorm:"column:group_id;index;not null"`
+	ActorID   string    `gorm:"default:'';not null"`
+	TargetID  string    `gorm:"index;not null"`
+	Action    string    `gorm:"not null"`
+	Reason    string    `gorm:"default:'';not null"`
+	Excerpt   string    `gorm:"default:'';not null"`
+	Automatic bool      `gorm:"default:false;not null"`
+	CreatedAt time.Time `gorm:"index"`
+
+	Group Group `gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type RuleConditions struct {
+	Regex              string        `json:"regex,omitempty"`
+	AnyURL             bool          `json:"any_url,omitempty"`
+	InviteLink         bool          `json:"invite_link,omitempty"`
+	Domains            []string      `json:"domains,omitempty"`
+	ExceptDomains      []string      `json:"except_domains,omitempty"`
+	MinMentions        int           `json:"min_mentions,omitempty"`
+	MassMention        bool          `json:"mass_mention,omitempty"`
+	MessageTypes       []string      `json:"message_types,omitempty"`
+	MinCaptionLength   int           `json:"min_caption_length,omitempty"`
+	MinForwardingScore uint32        `json:"min_forwarding_score,omitempty"`
+	MaxMemberAge       time.Duration `json:"max_member_age,omitempty"`
+	Blacklisted        bool          `json:"blacklisted,omitempty"`
+	WarnLimit          bool          `json:"warn_limit,omitempty"`
+	Muted              bool          `json:"muted,omitempty"`
+}
+
+type ModerationRule struct {
+	ID           uint32         `gorm:"primaryKey;autoIncrement"`
+	GroupID      string         `gorm:"column:group_id;index;not null"`
+	Name         string         `gorm:"not null"`
+	Priority     int            `gorm:"default:0;not null"`
+	Conditions   RuleConditions `gorm:"serializer:json;not null"`
+	Actions      []string       `gorm:"serializer:json;not null"`
+	MuteDuration time.Duration  `gorm:"default:0;not null"`
+	ExemptAdmins bool           `gorm:"not null"`
+	ExemptRoles  []string       `gorm:"serializer:json"`
+	DryRun       bool           `gorm:"default:false;not null"`
+	Enabled      bool           `gorm:"not null"`
+
+	Group Group `gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type CaptchaChallenge struct {
+	GroupID   string    `gorm:"column:group_id;primaryKey"`
+	UserID    string    `gorm:"column:user_id;primaryKey"`
+	Answer    string    `gorm:"not null"`
+	Attempts  int       `gorm:"default:0;not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+
+	Group Group `gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+type CardBackground struct {
+	GroupID string `gorm:"column:group_id;primaryKey"`
+	Data    []byte `gorm:"not null"`
+
+	Group Group `gorm:"foreignKey:GroupID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+}
+
+// AnnouncementMedia is the media of scheduled announcements, kept apart from
+// the jobs so loading them stays cheap. Jobs refer to it by hash, so
+// announcements with the same media share it.
+type AnnouncementMedia struct {
+	Hash      string `gorm:"primaryKey"` // Hex SHA-256 of the data
+	Data      []byte `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Job is a unit of timed work run by the scheduler. Jobs with a Cron
+// expression are rescheduled after each run, the others are deleted.
+type Job struct {
+	ID          uint64    `gorm:"primaryKey;autoIncrement"`
+	Name        string    `gorm:"index;not null"`            // Handler that runs the job
+	Key         string    `gorm:"index;default:'';not null"` // Optional identity, scheduling a job with the same key replaces it
+	ChatID      string    `gorm:"index;default:'';not null"` // Chat the job belongs to, if any
+	CreatorID   string    `gorm:"default:'';not null"`       // User who created the job, if any
+	Payload     string    `gorm:"default:'';not null"`       // Handler specific data, usually JSON
+	Cron        string    `gorm:"default:'';not null"`       // Cron expression of recurring jobs
+	Timezone    string    `gorm:"default:'';not null"`       // Timezone of the cron expression, UTC if empty
+	Misfire     string    `gorm:"default:'';not null"`       // What to do with runs missed while the bot was offline
+	RunAt       time.Time `gorm:"index;not null"`
+	LockedUntil *time.Time
+	Attempts    int    `gorm:"default:0;not null"`
+	LastError   string `gorm:"default:'';not null"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,72 @@
+package database
+
+const (
+	ModActionKick        = "kick"
+	ModActionAdd         = "add"
+	ModActionPromote     = "promote"
+	ModActionDemote      = "demote"
+	ModActionWarn        = "warn"
+	ModActionUnwarn      = "unwarn"
+	ModActionBlacklist   = "blacklist"
+	ModActionUnblacklist = "unblacklist"
+	ModActionDelete      = "delete"
+	ModActionMute        = "mute"
+	ModActionUnmute      = "unmute"
+	ModActionApprove     = "approve"
+	ModActionReject      = "reject"
+)
+
+const (
+	ModReasonBlacklist   = "blacklist"
+	ModReasonWarnLimit   = "warnlimit"
+	ModReasonAntiLink    = "antilink"
+	ModReasonBlocklist   = "linkblocklist"
+	ModReasonAntiWALink  = "antiwalink"
+	ModReasonMassMention = "massmention"
+	ModReasonDDI         = "ddi"
+	ModReasonMuted       = "muted"
+	ModReasonCaptcha     = "captcha"
+)
+
+type ModerationLogFilter struct {
+	ActorID   string
+	TargetID  string
+	Action    string
+	Automatic *bool
+	Limit     int
+	Offset    int
+}
+
+func (d *DBInstance) AddModerationLog(entry *ModerationLog) error {
+	return d.db.Create(entry).Error
+}
+
+// GetModerationLogs returns the group's moderation log entries matching the
+// filter, newest first, along with the total number of matching entries.
+func (d *DBInstance) GetModerationLogs(groupID string, filter ModerationLogFilter) ([]ModerationLog, int64, error) {
+	query := d.db.Model(&ModerationLog{}).Where("group_id = ?", groupID)
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Automatic != nil {
+		query = query.Where("automatic = ?", *filter.Automatic)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var entries []ModerationLog
+	err := query.Offset(filter.Offset).Order("created_at DESC, id DESC").Find(&entries).Error
+	return entries, total, err
+}
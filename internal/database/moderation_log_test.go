@@ -0,0 +1,77 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddModerationLog(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group1"
+
+	entry := &ModerationLog{
+		GroupID:  groupID,
+		ActorID:  "admin",
+		TargetID: "user1",
+		Action:   ModActionWarn,
+		Reason:   "spam",
+	}
+	require.NoError(t, db.AddModerationLog(entry))
+	assert.NotZero(t, entry.ID)
+	assert.False(t, entry.CreatedAt.IsZero())
+
+	entries, total, err := db.GetModerationLogs(groupID, ModerationLogFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "user1", entries[0].TargetID)
+	assert.Equal(t, ModActionWarn, entries[0].Action)
+}
+
+func TestGetModerationLogsFilters(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group2"
+
+	logs := []*ModerationLog{
+		{GroupID: groupID, TargetID: "user1", Action: ModActionDelete, Reason: ModReasonAntiLink, Automatic: true},
+		{GroupID: groupID, TargetID: "user1", Action: ModActionKick, Reason: ModReasonAntiLink, Automatic: true},
+		{GroupID: groupID, ActorID: "admin", TargetID: "user2", Action: ModActionKick},
+		{GroupID: groupID, ActorID: "admin", TargetID: "user3", Action: ModActionWarn},
+		{GroupID: "other", TargetID: "user1", Action: ModActionKick},
+	}
+	for _, l := range logs {
+		require.NoError(t, db.AddModerationLog(l))
+	}
+
+	entries, total, err := db.GetModerationLogs(groupID, ModerationLogFilter{TargetID: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, entries, 2)
+
+	entries, total, err = db.GetModerationLogs(groupID, ModerationLogFilter{Action: ModActionKick})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, entries, 2)
+
+	automatic := false
+	entries, total, err = db.GetModerationLogs(groupID, ModerationLogFilter{Automatic: &automatic})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	for _, e := range entries {
+		assert.Equal(t, "admin", e.ActorID)
+	}
+
+	// Pagination keeps the total and returns the newest entries first
+	entries, total, err = db.GetModerationLogs(groupID, ModerationLogFilter{Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "user3", entries[0].TargetID)
+
+	entries, _, err = db.GetModerationLogs(groupID, ModerationLogFilter{Limit: 3, Offset: 3})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ModActionDelete, entries[0].Action)
+}
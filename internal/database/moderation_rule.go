@@ -0,0 +1,24 @@
+package database
+
+func (d *DBInstance) GetModerationRules(groupID string) ([]ModerationRule, error) {
+	var rules = []ModerationRule{}
+	err := d.db.Where("group_id = ?", groupID).Order("priority, id").Find(&rules).Error
+	return rules, err
+}
+
+func (d *DBInstance) GetModerationRule(groupID string, ruleID uint32) (*ModerationRule, error) {
+	var rule ModerationRule
+	err := d.db.Where("group_id = ? AND id = ?", groupID, ruleID).First(&rule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (d *DBInstance) SaveModerationRule(rule *ModerationRule) error {
+	return d.db.Save(rule).Error
+}
+
+func (d *DBInstance) DeleteModerationRule(rule *ModerationRule) error {
+	return d.db.Delete(rule).Error
+}
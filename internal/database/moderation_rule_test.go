@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSaveModerationRule(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group1"
+
+	rule := &ModerationRule{
+		GroupID:      groupID,
+		Name:         "pix",
+		Conditions:   RuleConditions{Regex: "(?i)pix", MessageTypes: []string{"image"}, MaxMemberAge: time.Hour},
+		Actions:      []string{"delete", "mute"},
+		MuteDuration: 10 * time.Minute,
+		ExemptAdmins: true,
+		ExemptRoles:  []string{"premium"},
+		Enabled:      true,
+	}
+	require.NoError(t, db.SaveModerationRule(rule))
+	require.NotZero(t, rule.ID)
+
+	saved, err := db.GetModerationRule(groupID, rule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, rule.Conditions, saved.Conditions)
+	assert.Equal(t, rule.Actions, saved.Actions)
+	assert.Equal(t, rule.ExemptRoles, saved.ExemptRoles)
+	assert.Equal(t, 10*time.Minute, saved.MuteDuration)
+	assert.True(t, saved.Enabled)
+
+	// Rules are scoped to their group
+	_, err = db.GetModerationRule("other", rule.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestGetModerationRulesOrder(t *testing.T) {
+	db := setupTestDB(t)
+	groupID := "group2"
+
+	for _, r := range []*ModerationRule{
+		{GroupID: groupID, Name: "low", Priority: 10, Actions: []string{"delete"}},
+		{GroupID: groupID, Name: "high", Priority: 1, Actions: []string{"kick"}},
+		{GroupID: "other", Name: "other", Actions: []string{"delete"}},
+	} {
+		require.NoError(t, db.SaveModerationRule(r))
+	}
+
+	rules, err := db.GetModerationRules(groupID)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "high", rules[0].Name)
+	assert.Equal(t, "low", rules[1].Name)
+
+	require.NoError(t, db.DeleteModerationRule(&rules[0]))
+	rules, err = db.GetModerationRules(groupID)
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "low", rules[0].Name)
+}
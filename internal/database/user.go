@@ -1,5 +1,7 @@
 package database
 
+import "time"
+
 func (d *DBInstance) GetUserInfo(userID string) (*User, error) {
 	var user User
 	result := d.db.Where(&User{ID: userID}).FirstOrCreate(&user)
@@ -14,3 +16,9 @@ func (d *DBInstance) GetUserInfo(userID string) (*User, error) {
 func (d *DBInstance) SaveUserInfo(userInfo *User) error {
 	return d.db.Save(userInfo).Error
 }
+
+// Location returns the timezone of the user, falling back to UTC when it
+// isn't a known timezone.
+func (u *User) Location() *time.Location {
+	return loadLocation(u.Timezone)
+}
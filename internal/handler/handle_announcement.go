@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// runAnnouncementJob posts a scheduled announcement in its group.
+func (i *EventHandler) runAnnouncementJob(_ context.Context, job *database.Job) error {
+	var payload command.AnnouncementPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	chat, err := types.ParseJID(job.ChatID)
+	if err != nil {
+		return err
+	}
+
+	i.UserDB.MU.Lock()
+	err = payload.LoadMedia(i.UserDB)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := i.newContext(GetLocalizer()).SendAnnouncement(chat, &payload); err != nil {
+		return err
+	}
+
+	// A job that doesn't repeat is deleted after this run, so its media can go
+	// too. This also removes the media of announcements cancelled by hand.
+	var ignore uint64
+	if job.Cron == "" {
+		ignore = job.ID
+	}
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+	if err := i.UserDB.DeleteUnusedAnnouncementMedia(ignore); err != nil {
+		i.Log.Error().Err(err).Msg("Error deleting unused announcement media")
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+	"meowabot/internal/tools/media"
+	"meowabot/internal/util"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+)
+
+// challengeNewcomer sends a captcha to a member who just joined the group.
+// The member is removed if it isn't answered before the group's timeout.
+func (i *EventHandler) challengeNewcomer(group types.JID, user types.JID, settings database.CaptchaSettings, language string) {
+	var question, answer string
+	var image []byte
+	if settings.Mode == moderation.CaptchaImage {
+		answer = moderation.NewTextCaptcha(settings.Difficulty)
+		var err error
+		if image, err = media.GenerateCaptchaImage(answer); err != nil {
+			i.Log.Error().Err(err).Str("GroupID", group.User).Msg("Error generating captcha image")
+			return
+		}
+	} else {
+		question, answer = moderation.NewMathCaptcha(settings.Difficulty)
+	}
+
+	i.UserDB.MU.Lock()
+	err := i.UserDB.SaveCaptchaChallenge(&database.CaptchaChallenge{
+		GroupID:   group.User,
+		UserID:    user.User,
+		Answer:    answer,
+		ExpiresAt: time.Now().Add(settings.Timeout()),
+	})
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Str("GroupID", group.User).Str("User", user.User).Msg("Error saving captcha challenge")
+		return
+	}
+
+	ctx := i.newContext(GetLocalizer(language))
+	mention := []string{user.ToNonAD().String()}
+	if image != nil {
+		ctx.SendImageMessage(group, image, &command.MessageOptions{
+			Caption: proto.String(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "captcha.image",
+					Other: "🤖 @{{.User}}, para confirmar que você não é um robô, envie o texto da imagem em até {{.Timeout}} ou será removido do grupo.",
+				},
+				TemplateData: map[string]any{
+					"User":    user.User,
+					"Timeout": util.FormatDuration(settings.Timeout()),
+				},
+			})),
+			MentionedJid: mention,
+		})
+		return
+	}
+	ctx.SendTextMessage(group, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "captcha.math",
+			Other: "🤖 @{{.User}}, para confirmar que você não é um robô, responda em até {{.Timeout}} quanto é *{{.Question}}* ou será removido do grupo.",
+		},
+		TemplateData: map[string]any{
+			"User":     user.User,
+			"Question": question,
+			"Timeout":  util.FormatDuration(settings.Timeout()),
+		},
+	}), &command.MessageOptions{MentionedJid: mention})
+}
+
+// handleCaptchaAnswer checks a message from a member with a pending captcha.
+// Messages other than the right answer are deleted and the member is removed
+// after too many wrong answers. It reports whether the message was consumed
+// by the captcha. Must be called without the database lock held.
+func (i *EventHandler) handleCaptchaAnswer(m *events.Message, groupMetadata *types.GroupInfo, groupInfo *database.Group, text string) bool {
+	i.UserDB.MU.Lock()
+	challenge, err := i.UserDB.GetCaptchaChallenge(m.Info.Chat.User, m.Info.Sender.User)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error getting captcha challenge from database")
+		}
+		return false
+	}
+
+	ctx := i.newContext(GetLocalizer(groupInfo.Language))
+	ctx.GroupMetadata = groupMetadata
+	mention := []string{m.Info.Sender.ToNonAD().String()}
+
+	if moderation.CheckCaptchaAnswer(challenge.Answer, text) {
+		i.UserDB.MU.Lock()
+		err := i.UserDB.DeleteCaptchaChallenge(challenge.GroupID, challenge.UserID)
+		i.UserDB.MU.Unlock()
+		if err != nil {
+			i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error deleting captcha challenge")
+		}
+		ctx.SendTextMessage(m.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: &i18n.Message{
+				ID:    "captcha.solved",
+				Other: "✅ @{{.User}} foi verificado. Seja bem-vindo!",
+			},
+			TemplateData: map[string]any{
+				"User": m.Info.Sender.User,
+			},
+		}), &command.MessageOptions{MentionedJid: mention})
+		return true
+	}
+
+	if _, err := i.Client.SendMessage(context.Background(), m.Info.Chat, i.Client.BuildRevoke(m.Info.Chat, m.Info.Sender, m.Info.ID)); err != nil {
+		i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("MessageID", m.Info.ID).Msg("Failed to delete message")
+	}
+
+	challenge.Attempts++
+	if challenge.Attempts >= moderation.MaxCaptchaAttempts {
+		i.removeUnverified(ctx, challenge, text)
+		return true
+	}
+	i.UserDB.MU.Lock()
+	err = i.UserDB.SaveCaptchaChallenge(challenge)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error saving captcha challenge")
+	}
+	ctx.SendTextMessage(m.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "captcha.wrong",
+			Other: "❌ @{{.User}}, resposta incorreta. Tentativas restantes: {{.Remaining}}",
+		},
+		TemplateData: map[string]any{
+			"User":      m.Info.Sender.User,
+			"Remaining": moderation.MaxCaptchaAttempts - challenge.Attempts,
+		},
+	}), &command.MessageOptions{MentionedJid: mention})
+	return true
+}
+
+// removeUnverified removes a member who failed their captcha. Must be called
+// without the database lock held.
+func (i *EventHandler) removeUnverified(ctx *command.CommandContext, challenge *database.CaptchaChallenge, excerpt string) {
+	i.UserDB.MU.Lock()
+	err := i.UserDB.DeleteCaptchaChallenge(challenge.GroupID, challenge.UserID)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Str("GroupID", challenge.GroupID).Str("User", challenge.UserID).Msg("Error deleting captcha challenge")
+	}
+	group := types.NewJID(challenge.GroupID, types.GroupServer)
+	user := types.NewJID(challenge.UserID, types.DefaultUserServer)
+	if _, err := i.Client.UpdateGroupParticipants(group, []types.JID{user}, whatsmeow.ParticipantChangeRemove); err != nil {
+		i.Log.Error().Err(err).Str("GroupID", challenge.GroupID).Str("User", challenge.UserID).Msg("Error removing unverified user")
+		return
+	}
+	i.UserDB.MU.Lock()
+	ctx.LogModeration(&database.ModerationLog{
+		GroupID:   challenge.GroupID,
+		TargetID:  challenge.UserID,
+		Action:    database.ModActionKick,
+		Reason:    database.ModReasonCaptcha,
+		Excerpt:   excerpt,
+		Automatic: true,
+	})
+	i.UserDB.MU.Unlock()
+}
+
+// sweepExpiredCaptchas removes the members who didn't answer their captcha in time.
+func (i *EventHandler) sweepExpiredCaptchas() {
+	i.UserDB.MU.Lock()
+	expired, err := i.UserDB.GetExpiredCaptchaChallenges(time.Now())
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Msg("Error retrieving expired captcha challenges from database")
+		return
+	}
+	for _, challenge := range expired {
+		i.UserDB.MU.Lock()
+		groupInfo, err := i.UserDB.GetGroupInfo(challenge.GroupID)
+		if err == nil && !groupInfo.Captcha.Enabled {
+			// The captcha was turned off after the challenge was sent
+			if err := i.UserDB.DeleteCaptchaChallenge(challenge.GroupID, challenge.UserID); err != nil {
+				i.Log.Error().Err(err).Str("GroupID", challenge.GroupID).Str("User", challenge.UserID).Msg("Error deleting captcha challenge")
+			}
+		}
+		i.UserDB.MU.Unlock()
+		if err != nil {
+			i.Log.Error().Err(err).Str("GroupID", challenge.GroupID).Msg("Error retrieving group info from database")
+			continue
+		}
+		if groupInfo.Captcha.Enabled {
+			i.removeUnverified(i.newContext(GetLocalizer(groupInfo.Language)), &challenge, "")
+		}
+	}
+}
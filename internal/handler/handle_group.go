@@ -1,9 +1,10 @@
 package handler
 
 import (
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
 	"slices"
 	"sort"
-	"strings"
 	"time"
 
 	"go.mau.fi/whatsmeow"
@@ -11,33 +12,165 @@ import (
 	"go.mau.fi/whatsmeow/types/events"
 )
 
+// groupChange is the state of a group after an event was applied to the
+// cached group info, for the work done once the cache lock is released.
+type groupChange struct {
+	// group is a copy of the cached info with the event applied
+	group           *types.GroupInfo
+	isBotGroupAdmin bool
+	addedByAdmin    bool
+	botLeft         bool
+	// left are the members who left and were in the cached group
+	left []types.JID
+}
+
+// handleGroupInfoChange updates the cached group info and applies the group's
+// settings to the members who joined or left. The database lock is only taken
+// after the cache lock is released, as handleMessage takes them in the
+// opposite order.
 func (i *EventHandler) handleGroupInfoChange(event *events.GroupInfo) {
+	if requests := joinRequests(event); len(requests) > 0 {
+		go i.handleJoinRequests(event.JID, requests)
+	}
+
+	change, ok := i.updateCachedGroup(event)
+	if !ok {
+		if change, ok = i.fetchGroupChange(event); !ok {
+			return
+		}
+	}
+
+	i.UserDB.MU.Lock()
+	groupInfo, err := i.UserDB.GetGroupInfo(event.JID.User)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Str("GroupID", event.JID.User).Msg("Error retrieving group info from database")
+		return
+	}
+
+	switch {
+	case change.botLeft:
+		i.UserDB.MU.Lock()
+		if err := i.UserDB.DeleteGroupInfo(groupInfo); err != nil {
+			i.Log.Error().Err(err).Str("GroupID", event.JID.String()).Msg("Error deleting row from group info")
+		}
+		i.UserDB.MU.Unlock()
+
+	case len(event.Leave) > 0:
+		i.UserDB.MU.Lock()
+		for _, user := range event.Leave {
+			if err := i.UserDB.DeleteCaptchaChallenge(event.JID.User, user.User); err != nil {
+				i.Log.Error().Err(err).Str("GroupID", event.JID.User).Str("User", user.User).Msg("Error deleting captcha challenge")
+			}
+		}
+		for _, user := range change.left {
+			userInfo, err := i.UserDB.GetParticipant(user.User, event.JID.User)
+			if err != nil {
+				i.Log.Error().Err(err).Str("GroupID", event.JID.User).Str("User", user.User).Msg("Error retrieving user from database")
+				continue
+			}
+			if userInfo.IsBlacklisted {
+				// Blacklisted members are kept so they can be removed again if they rejoin
+				continue
+			}
+			if err = i.UserDB.DeleteParticipant(userInfo); err != nil {
+				i.Log.Error().Err(err).Str("GroupID", event.JID.User).Str("User", user.User).Msg("Error deleting user from database")
+			}
+		}
+		i.UserDB.MU.Unlock()
+
+		// Members removed by someone else don't get a goodbye
+		if groupInfo.Goodbye.Enabled && (event.Sender == nil || slices.ContainsFunc(event.Leave, func(user types.JID) bool { return user.User == event.Sender.User })) {
+			go i.sendGreetings(change.group, &groupInfo.Goodbye, groupInfo.Language, event.Leave, true)
+		}
+
+	case len(event.Join) > 0:
+		modCtx := i.newContext(GetLocalizer(groupInfo.Language))
+		modCtx.GroupMetadata = change.group
+		var joined, challenged []types.JID
+		for _, user := range event.Join {
+			if change.isBotGroupAdmin && event.Sender == nil { // TODO: Change to isGroupAdmin == false
+				// Members whose number can't be resolved from their LID can't be
+				// checked, so they are removed as well when there are country rules
+				if number := modCtx.PhoneNumber(user); len(groupInfo.CountryRules) > 0 && (number == "" || !moderation.CountryAllowed(groupInfo.CountryRules, number)) {
+					if _, err := i.Client.UpdateGroupParticipants(event.JID, []types.JID{user}, whatsmeow.ParticipantChangeRemove); err != nil {
+						i.Log.Error().Str("ChatID", event.JID.String()).Str("UserID", user.String()).Msg("Error removing user")
+						continue
+					}
+					i.UserDB.MU.Lock()
+					modCtx.LogModeration(&database.ModerationLog{
+						GroupID:   event.JID.User,
+						TargetID:  user.User,
+						Action:    database.ModActionKick,
+						Reason:    database.ModReasonDDI,
+						Automatic: true,
+					})
+					i.UserDB.MU.Unlock()
+					continue
+				}
+			}
+			i.UserDB.MU.Lock()
+			userInfo, err := i.UserDB.GetParticipant(user.User, event.JID.User)
+			i.UserDB.MU.Unlock()
+			if err == nil && userInfo.IsBlacklisted {
+				if change.isBotGroupAdmin {
+					if _, err := i.Client.UpdateGroupParticipants(event.JID, []types.JID{user}, whatsmeow.ParticipantChangeRemove); err != nil {
+						i.Log.Error().Str("ChatID", event.JID.String()).Str("UserID", user.String()).Msg("Error removing blacklisted user")
+						continue
+					}
+					i.UserDB.MU.Lock()
+					modCtx.LogModeration(&database.ModerationLog{
+						GroupID:   event.JID.User,
+						TargetID:  user.User,
+						Action:    database.ModActionKick,
+						Reason:    database.ModReasonBlacklist,
+						Automatic: true,
+					})
+					i.UserDB.MU.Unlock()
+					continue
+				}
+			}
+			i.markParticipantJoined(event.JID.User, user.User)
+			joined = append(joined, user)
+			if groupInfo.Captcha.Enabled && change.isBotGroupAdmin && !change.addedByAdmin {
+				challenged = append(challenged, user)
+			}
+		}
+		go func() {
+			// The welcome comes first so the captcha isn't buried under it
+			if groupInfo.Welcome.Enabled && len(joined) > 0 {
+				i.sendGreetings(change.group, &groupInfo.Welcome, groupInfo.Language, joined, false)
+			}
+			for _, user := range challenged {
+				i.challengeNewcomer(event.JID, user, groupInfo.Captcha, groupInfo.Language)
+			}
+		}()
+	}
+}
+
+// updateCachedGroup applies a group event to the cached group info. It returns
+// false if the group isn't cached.
+func (i *EventHandler) updateCachedGroup(event *events.GroupInfo) (*groupChange, bool) {
 	i.groupCacheMutex.Lock()
 	defer i.groupCacheMutex.Unlock()
 
 	groupMetadata, ok := i.groupInfoCache[event.JID.User]
 	if !ok {
-		return
+		return nil, false
 	}
 	if time.Now().After(groupMetadata.expireAt) {
 		delete(i.groupInfoCache, event.JID.User)
-		return
+		return nil, false
 	}
 
-	var isBotGroupAdmin bool
+	change := &groupChange{}
 	for _, p := range groupMetadata.Info.Participants {
 		if p.JID.User == i.Client.Store.ID.User && (p.IsAdmin || p.IsSuperAdmin) {
-			isBotGroupAdmin = true
+			change.isBotGroupAdmin = true
 			break
 		}
 	}
 
-	groupInfo, err := i.UserDB.GetGroupInfo(event.JID.User)
-	if err != nil {
-		i.Log.Error().Err(err).Str("GroupID", event.JID.User).Msg("Error retrieving group info from database")
-		return
-	}
-
 	switch {
 	case event.Name != nil:
 		groupMetadata.Info.GroupName = *event.Name
@@ -52,6 +185,8 @@ func (i *EventHandler) handleGroupInfoChange(event *events.GroupInfo) {
 		groupMetadata.Info.GroupEphemeral = *event.Ephemeral
 	case event.MembershipApprovalMode != nil:
 		groupMetadata.Info.GroupMembershipApprovalMode = *event.MembershipApprovalMode
+	case event.NewInviteLink != nil:
+		groupMetadata.inviteCode = inviteCodeFromLink(*event.NewInviteLink)
 	}
 
 	participantMap := make(map[string]int, len(groupMetadata.Info.Participants))
@@ -66,56 +201,29 @@ func (i *EventHandler) handleGroupInfoChange(event *events.GroupInfo) {
 		for _, user := range event.Leave {
 			if index, found := participantMap[user.User]; found {
 				participantsToRemove = append(participantsToRemove, index)
+				change.left = append(change.left, user)
 			}
 			if user.User == i.Client.Store.ID.User {
 				delete(i.groupInfoCache, event.JID.User)
-				if err := i.UserDB.DeleteGroupInfo(groupInfo); err != nil {
-					i.Log.Error().Err(err).Str("GroupID", event.JID.String()).Msg("Error deleting row from group info")
-				}
-				return
+				change.botLeft = true
+				return change, true
 			}
 		}
 		sort.Sort(sort.Reverse(sort.IntSlice(participantsToRemove)))
-		i.UserDB.MU.Lock()
 		for _, u := range participantsToRemove {
-			userInfo, err := i.UserDB.GetParticipant(groupMetadata.Info.Participants[u].JID.User, groupMetadata.Info.JID.User)
-			if err != nil {
-				i.Log.Error().Err(err).Str("GroupID", groupMetadata.Info.JID.User).Str("User", groupMetadata.Info.Participants[u].JID.User).Msg("Error retrieving user from database")
-				continue
-			}
-			if err = i.UserDB.DeleteParticipant(userInfo); err != nil {
-				i.Log.Error().Err(err).Str("GroupID", groupMetadata.Info.JID.User).Str("User", groupMetadata.Info.Participants[u].JID.User).Msg("Error deleting user from database")
-			}
 			groupMetadata.Info.Participants = slices.Delete(groupMetadata.Info.Participants, u, u+1)
 		}
-		i.UserDB.MU.Unlock()
 
 	case len(event.Join) > 0:
-		for _, user := range event.Join {
-			if groupInfo.AllowedDDIS != "" && isBotGroupAdmin && event.Sender == nil { // TODO: Change to isGroupAdmin == false
-				var valid bool
-				ddis := strings.SplitSeq(groupInfo.AllowedDDIS, ",")
-				for ddi := range ddis {
-					if strings.HasPrefix(user.User, ddi) {
-						valid = true
-						break
-					}
-				}
-				if !valid {
-					if _, err := i.Client.UpdateGroupParticipants(event.JID, []types.JID{user}, whatsmeow.ParticipantChangeRemove); err != nil {
-						i.Log.Error().Str("ChatID", event.JID.String()).Str("UserID", user.String()).Msg("Error removing user")
-					}
-					continue
-				}
-			}
-			if userInfo, err := i.UserDB.GetParticipant(user.User, event.JID.User); err != nil && userInfo.IsBlacklisted {
-				if isBotGroupAdmin {
-					if _, err := i.Client.UpdateGroupParticipants(event.JID, []types.JID{user}, whatsmeow.ParticipantChangeRemove); err != nil {
-						i.Log.Error().Str("ChatID", event.JID.String()).Str("UserID", user.String()).Msg("Error removing blacklisted user")
-					}
-					continue
-				}
+		// Members added by an admin are trusted and don't need to solve a captcha.
+		// The bot itself only adds members when auto-approving join requests.
+		if event.Sender != nil && event.Sender.User != i.Client.Store.ID.User {
+			if index, found := participantMap[event.Sender.User]; found {
+				p := groupMetadata.Info.Participants[index]
+				change.addedByAdmin = p.IsAdmin || p.IsSuperAdmin
 			}
+		}
+		for _, user := range event.Join {
 			if _, found := participantMap[user.User]; !found {
 				groupMetadata.Info.Participants = append(groupMetadata.Info.Participants, types.GroupParticipant{JID: user, IsAdmin: false, IsSuperAdmin: false})
 			}
@@ -136,5 +244,85 @@ func (i *EventHandler) handleGroupInfoChange(event *events.GroupInfo) {
 		}
 	}
 
-	i.groupInfoCache[event.JID.User] = groupMetadata
+	change.group = snapshotGroupInfo(groupMetadata.Info)
+	return change, true
+}
+
+func (i *EventHandler) markParticipantJoined(groupID string, userID string) {
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	if _, err := i.UserDB.GetUserInfo(userID); err != nil {
+		i.Log.Error().Err(err).Str("User", userID).Msg("Error retrieving user from database")
+		return
+	}
+	participant, err := i.UserDB.GetParticipant(userID, groupID)
+	if err != nil {
+		i.Log.Error().Err(err).Str("GroupID", groupID).Str("User", userID).Msg("Error retrieving user from database")
+		return
+	}
+	now := time.Now()
+	participant.JoinedAt = &now
+	if err := i.UserDB.SaveParticipant(participant); err != nil {
+		i.Log.Error().Err(err).Str("GroupID", groupID).Str("User", userID).Msg("Error updating group participant info")
+	}
+}
+
+// sendGreetings sends the welcome or goodbye message of a group to each member.
+func (i *EventHandler) sendGreetings(group *types.GroupInfo, settings *database.GreetingSettings, language string, members []types.JID, leaving bool) {
+	ctx := i.newContext(GetLocalizer(language))
+	for _, member := range members {
+		ctx.SendGreeting(group, settings, member, i.userName(member.User), leaving)
+	}
+}
+
+// userName returns the last known push name of a user.
+func (i *EventHandler) userName(userID string) string {
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	userInfo, err := i.UserDB.GetUserInfo(userID)
+	if err != nil {
+		i.Log.Error().Err(err).Str("User", userID).Msg("Error retrieving user from database")
+		return ""
+	}
+	return userInfo.Name
+}
+
+// fetchGroupChange fetches a group that isn't cached when members join or
+// leave, so they are handled the same after a restart or once the cache
+// expires. The fetched info already has the event applied.
+func (i *EventHandler) fetchGroupChange(event *events.GroupInfo) (*groupChange, bool) {
+	if len(event.Join) == 0 && len(event.Leave) == 0 {
+		return nil, false
+	}
+	if slices.ContainsFunc(event.Leave, func(user types.JID) bool { return user.User == i.Client.Store.ID.User }) {
+		return &groupChange{botLeft: true}, true
+	}
+
+	group, err := i.Client.GetGroupInfo(event.JID)
+	if err != nil {
+		i.Log.Error().Err(err).Str("GroupID", event.JID.String()).Msg("Error getting group metadata")
+		return nil, false
+	}
+	i.SetCachedGroupInfo(group)
+
+	change := &groupChange{group: snapshotGroupInfo(group), left: event.Leave}
+	for _, p := range group.Participants {
+		isAdmin := p.IsAdmin || p.IsSuperAdmin
+		if p.JID.User == i.Client.Store.ID.User {
+			change.isBotGroupAdmin = isAdmin
+		} else if event.Sender != nil && p.JID.User == event.Sender.User {
+			change.addedByAdmin = isAdmin
+		}
+	}
+	return change, true
+}
+
+// snapshotGroupInfo copies the cached group metadata so it can be used after
+// the cache lock is released.
+func snapshotGroupInfo(info *types.GroupInfo) *types.GroupInfo {
+	group := *info
+	group.Participants = slices.Clone(info.Participants)
+	return &group
 }
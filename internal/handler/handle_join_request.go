@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// joinRequests returns the users who asked to join the group. whatsmeow
+// doesn't parse these notifications, so they arrive as unknown changes.
+func joinRequests(event *events.GroupInfo) []types.JID {
+	var users []types.JID
+	for _, node := range event.UnknownChanges {
+		if node.Tag != "created_membership_requests" {
+			continue
+		}
+		var found bool
+		for _, child := range node.GetChildren() {
+			if jid := child.AttrGetter().OptionalJIDOrEmpty("jid"); !jid.IsEmpty() {
+				users = append(users, jid)
+				found = true
+			}
+		}
+		// Requests for a single user may only carry the requester as sender
+		if !found && event.Sender != nil {
+			users = append(users, *event.Sender)
+		}
+	}
+	return users
+}
+
+// handleJoinRequests applies the join policy of the group to new requests.
+// Requests the policy doesn't decide are left pending for the admins.
+func (i *EventHandler) handleJoinRequests(group types.JID, users []types.JID) {
+	i.UserDB.MU.Lock()
+	groupInfo, err := i.UserDB.GetGroupInfo(group.User)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Str("GroupID", group.User).Msg("Error retrieving group info from database")
+		return
+	}
+
+	ctx := i.newContext(GetLocalizer(groupInfo.Language))
+	var approve, reject []types.JID
+	reasons := make(map[string]string, len(users))
+	for _, user := range users {
+		i.UserDB.MU.Lock()
+		blacklisted, err := i.UserDB.IsBlacklisted(user.User, group.User)
+		i.UserDB.MU.Unlock()
+		if err != nil {
+			i.Log.Error().Err(err).Str("GroupID", group.User).Str("User", user.User).Msg("Error retrieving user from database")
+			continue
+		}
+		action, reason := moderation.JoinRequestVerdict(groupInfo, ctx.PhoneNumber(user), blacklisted)
+		switch action {
+		case moderation.JoinApprove:
+			approve = append(approve, user)
+		case moderation.JoinReject:
+			reject = append(reject, user)
+			reasons[user.User] = reason
+		default:
+			i.Log.Info().Str("GroupID", group.User).Str("User", user.User).Msg("Pending join request")
+		}
+	}
+
+	for _, change := range []struct {
+		users  []types.JID
+		action whatsmeow.ParticipantRequestChange
+		log    string
+	}{
+		{approve, whatsmeow.ParticipantChangeApprove, database.ModActionApprove},
+		{reject, whatsmeow.ParticipantChangeReject, database.ModActionReject},
+	} {
+		if len(change.users) == 0 {
+			continue
+		}
+		updated, err := i.Client.UpdateGroupRequestParticipants(group, change.users, change.action)
+		if err != nil {
+			i.Log.Error().Err(err).Str("GroupID", group.User).Str("Action", string(change.action)).Msg("Error updating join requests")
+			continue
+		}
+		i.UserDB.MU.Lock()
+		for _, p := range updated {
+			if p.Error != 0 {
+				continue
+			}
+			ctx.LogModeration(&database.ModerationLog{
+				GroupID:   group.User,
+				TargetID:  p.JID.User,
+				Action:    change.log,
+				Reason:    reasons[p.JID.User],
+				Automatic: true,
+			})
+		}
+		i.UserDB.MU.Unlock()
+	}
+}
@@ -1,10 +1,10 @@
 package handler
 
 import (
-	"context"
 	"fmt"
 	"meowabot/internal/command"
 	"meowabot/internal/database"
+	"meowabot/internal/moderation"
 	tmsg "meowabot/internal/tools/messages"
 	"meowabot/internal/util"
 	"slices"
@@ -14,7 +14,6 @@ import (
 	"github.com/hbakhtiyor/strsim"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/rs/zerolog/log"
-	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
@@ -28,7 +27,6 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 	var isCommand bool = strings.HasPrefix(messageBody, i.Config.CommandPrefix)
 	var commandName string
 	var commandArgs string
-	fmt.Println(commandArgs)
 	if isCommand {
 		commandName = util.NormalizeString(strings.ToLower(strings.Split(strings.TrimSpace(strings.TrimPrefix(messageBody, prefix)), " ")[0]))
 		commandArgs = strings.TrimSpace(messageBody[len(commandName)+len(prefix):])
@@ -42,6 +40,17 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 	var groupInfo *database.Group
 	var participant *database.GroupParticipant
 
+	// The invite code is only needed to recognize the group's own invites. It
+	// can take a round trip, so it is fetched before taking the lock.
+	var inviteCode string
+	if m.Info.IsGroup && len(util.InviteCodes(messageBody)) > 0 && i.isBotAdmin(m.Info.Chat) {
+		var err error
+		if inviteCode, err = i.GetGroupInviteCode(m.Info.Chat); err != nil {
+			i.Log.Warn().Err(err).Str("Group", m.Info.Chat.String()).Msg("Error getting group invite link")
+		}
+	}
+
+	var rules []database.ModerationRule
 	err := func() error {
 		i.UserDB.MU.Lock()
 		defer i.UserDB.MU.Unlock()
@@ -84,13 +93,14 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 				i.SetCachedGroupInfo(groupMetadata)
 			}
 			for _, participant := range groupMetadata.Participants {
-				if participant.JID.User == m.Info.Sender.User && (participant.IsAdmin || participant.IsSuperAdmin) {
+				if !participant.IsAdmin && !participant.IsSuperAdmin {
+					continue
+				}
+				if participant.JID.User == m.Info.Sender.User {
 					isGroupAdmin = true
-					break
 				}
-				if participant.JID.User == i.Client.Store.ID.User && (participant.IsAdmin || participant.IsSuperAdmin) {
+				if participant.JID.User == i.Client.Store.ID.User {
 					isBotGroupAdmin = true
-					break
 				}
 			}
 
@@ -108,45 +118,67 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 				return err
 			}
 
-			if isBotGroupAdmin && !isOwner && !isGroupAdmin {
-				switch {
-				case
-					(participant.IsBlacklisted || participant.WarnCount >= 3),
-					groupInfo.IsAntiLink && util.MatchURL(messageBody),
-					groupInfo.IsAntiWALink && util.MatchWaUrl(messageBody),
-					len(tmsg.GetMentionedJIDS(m.Message)) >= len(groupMetadata.Participants)-1:
-					if groupInfo.RemoveUser {
-						_, err = i.Client.UpdateGroupParticipants(m.Info.Chat, []types.JID{m.Info.Sender}, whatsmeow.ParticipantChangeRemove)
-						if err != nil {
-							i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error removing group participant")
-							return err
-						}
-					}
-					i.Client.SendMessage(context.Background(), m.Info.Chat, i.Client.BuildRevoke(m.Info.Chat, m.Info.Sender, m.Info.ID))
+			if isBotGroupAdmin {
+				rules, err = i.UserDB.GetModerationRules(m.Info.Chat.User)
+				if err != nil {
+					i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Msg("Error getting moderation rules from database")
 					return err
 				}
 			}
+		}
+		return nil
+	}()
+	if err != nil {
+		return
+	}
 
-			if !isValid {
-				return err
+	// Moderation deletes messages, kicks members and replies, so it runs
+	// without the database lock and only takes it to save its changes
+	if m.Info.IsGroup {
+		if isBotGroupAdmin {
+			if groupInfo.Captcha.Enabled && !isGroupAdmin && i.handleCaptchaAnswer(m, groupMetadata, groupInfo, messageBody) {
+				return
 			}
 
-			if isCommand {
-				participant.CommandCount++
-			} else {
-				participant.MessageCount++
+			msg := &moderation.Message{
+				Text:            messageBody,
+				Type:            tmsg.GetMessageType(m.Message),
+				Mentions:        len(tmsg.GetMentionedJIDS(m.Message)),
+				ForwardingScore: tmsg.GetContextInfo(m.Message).GetForwardingScore(),
+				GroupSize:       len(groupMetadata.Participants),
+				InviteCode:      inviteCode,
+				IsAdmin:         isGroupAdmin,
+				IsOwner:         isOwner,
+				IsPremium:       userInfo.IsPremium,
+				Participant:     participant,
+			}
+			for _, rule := range moderation.Evaluate(append(moderation.BuiltinRules(groupInfo), rules...), msg) {
+				if i.applyModerationRule(rule, m, groupMetadata, groupInfo, messageBody) {
+					return
+				}
 			}
 
-			err = i.UserDB.SaveParticipant(participant)
-			if err != nil {
+			if groupInfo.Flood.Enabled && !isGroupAdmin && !isOwner {
+				isMedia := tmsg.GetMediaSHA256(m.Message) != nil
+				kind, strikes := i.floodDetector.Check(m.Info.Chat.User, m.Info.Sender.User, messageHash(m.Message, messageBody), isMedia, &groupInfo.Flood, time.Now())
+				if kind != "" && i.applyModerationRule(moderation.FloodPenalty(kind, strikes, &groupInfo.Flood), m, groupMetadata, groupInfo, messageBody) {
+					return
+				}
+			}
+		}
+
+		if isValid {
+			if _, err := i.updateParticipant(m.Info.Chat.User, m.Info.Sender.User, func(p *database.GroupParticipant) {
+				if isCommand {
+					p.CommandCount++
+				} else {
+					p.MessageCount++
+				}
+			}); err != nil {
 				i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error updating group participant info")
-				return err
+				return
 			}
 		}
-		return nil
-	}()
-	if err != nil {
-		return
 	}
 
 	// Log info to terminal
@@ -192,16 +224,25 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 
 	if isCommand {
 		ctx := &command.CommandContext{
-			Client:    i.Client,
-			Config:    i.Config,
-			Msg:       m,
-			DB:        i.UserDB,
-			Body:      messageBody,
-			Args:      commandArgs,
-			Prefix:    prefix,
-			Command:   commandName,
-			Localizer: localizer,
-			Log:       i.Log,
+			Client:     i.Client,
+			Config:     i.Config,
+			Msg:        m,
+			DB:         i.UserDB,
+			Body:       messageBody,
+			Args:       commandArgs,
+			Prefix:     prefix,
+			Command:    commandName,
+			Localizer:  localizer,
+			Log:        i.Log,
+			Scheduler:  i.Scheduler,
+			MediaCache: i.MediaCache,
+
+			IsOwner:         isOwner,
+			IsGroupAdmin:    isGroupAdmin,
+			IsBotGroupAdmin: isBotGroupAdmin,
+			UserInfo:        userInfo,
+			GroupInfo:       groupInfo,
+			GroupMetadata:   groupMetadata,
 		}
 		cmd, ok := i.cmd.Commands[commandName]
 		if ok {
@@ -212,33 +253,37 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 						Other: "❌ Esse comando só pode ser utilizado pelo meu dono",
 					},
 				}))
+				return
 			}
 
-			if cmd.Only.Admin && !isGroupAdmin {
+			if cmd.Only.Group && !m.Info.IsGroup {
 				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
 					DefaultMessage: &i18n.Message{
-						ID:    "only.admin",
-						Other: "❌ Esse comando só pode ser utilizado por administradores do grupo",
+						ID:    "only.group",
+						Other: "❌ Esse comando só pode ser utilizado em grupos",
 					},
 				}))
+				return
 			}
 
-			if cmd.Only.Group && !m.Info.IsGroup {
+			if cmd.Only.Admin && !isGroupAdmin {
 				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
 					DefaultMessage: &i18n.Message{
-						ID:    "only.group",
-						Other: "❌ Esse comando só pode ser utilizado em grupos",
+						ID:    "only.admin",
+						Other: "❌ Esse comando só pode ser utilizado por administradores do grupo",
 					},
 				}))
+				return
 			}
 
 			if cmd.Only.Premium && !userInfo.IsPremium {
 				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
 					DefaultMessage: &i18n.Message{
-						ID:    "only.admin",
-						Other: "❌ Esse comando só pode ser utilizado por administradores do grupo",
+						ID:    "only.premium",
+						Other: "❌ Esse comando só pode ser utilizado por usuários premium",
 					},
 				}))
+				return
 			}
 
 			if cmd.Need.BotAdmin && !isBotGroupAdmin {
@@ -248,20 +293,17 @@ func (i *EventHandler) handleMessage(m *events.Message) {
 						Other: "❌ O bot precisa ser administrador para executar esse comando",
 					},
 				}))
+				return
 			}
 
-			jid, err := tmsg.GetQuotedJid(m)
-			if err != nil {
-				i.Log.Error().Err(err).Msg("Error getting quoted jids")
-			}
-
-			if cmd.Need.Mention && len(tmsg.GetMentionedJIDS(m.Message)) == 0 && jid.IsEmpty() {
+			if cmd.Need.Mention && len(ctx.Targets()) == 0 {
 				ctx.Reply(ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
 					DefaultMessage: &i18n.Message{
 						ID:    "need.mention",
 						Other: "❌ Você precisa mencionar ou responder a mensagem de alguém",
 					},
 				}))
+				return
 			}
 
 			defer func() {
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+	tmsg "meowabot/internal/tools/messages"
+	"meowabot/internal/util"
+	"strings"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// applyModerationRule runs the actions of a matched rule against the message.
+// It reports whether the message was removed and should not be processed any
+// further. Must be called without the database lock held, as the actions take
+// round trips; it's only taken to save their changes.
+func (i *EventHandler) applyModerationRule(rule *database.ModerationRule, m *events.Message, groupMetadata *types.GroupInfo, groupInfo *database.Group, text string) bool {
+	if rule.DryRun {
+		i.Log.Info().
+			Str("Group", m.Info.Chat.String()).
+			Str("User", m.Info.Sender.User).
+			Str("Rule", rule.Name).
+			Strs("Actions", rule.Actions).
+			Str("Message", util.Truncate(text, 100)).
+			Msg("[DRY RUN] Moderation rule matched")
+		return false
+	}
+
+	ctx := i.newContext(GetLocalizer(groupInfo.Language))
+	ctx.GroupMetadata = groupMetadata
+	logAction := func(action string) {
+		i.UserDB.MU.Lock()
+		defer i.UserDB.MU.Unlock()
+		ctx.LogModeration(&database.ModerationLog{
+			GroupID:   m.Info.Chat.User,
+			TargetID:  m.Info.Sender.User,
+			Action:    action,
+			Reason:    rule.Name,
+			Excerpt:   text,
+			Automatic: true,
+		})
+	}
+	mention := []string{m.Info.Sender.ToNonAD().String()}
+
+	var removed, kicked bool
+	kick := func() {
+		if kicked {
+			return
+		}
+		kicked = true
+		if _, err := i.Client.UpdateGroupParticipants(m.Info.Chat, []types.JID{m.Info.Sender}, whatsmeow.ParticipantChangeRemove); err != nil {
+			i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error removing group participant")
+			return
+		}
+		logAction(database.ModActionKick)
+	}
+
+	for _, action := range rule.Actions {
+		switch action {
+		case moderation.ActionDelete:
+			if _, err := i.Client.SendMessage(context.Background(), m.Info.Chat, i.Client.BuildRevoke(m.Info.Chat, m.Info.Sender, m.Info.ID)); err != nil {
+				i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("MessageID", m.Info.ID).Msg("Failed to delete message")
+				continue
+			}
+			removed = true
+			// Deleting every message of a muted member would flood the log
+			if rule.Name != database.ModReasonMuted {
+				logAction(database.ModActionDelete)
+			}
+
+		case moderation.ActionKick:
+			kick()
+			removed = true
+
+		case moderation.ActionWarn:
+			participant, err := i.updateParticipant(m.Info.Chat.User, m.Info.Sender.User, func(p *database.GroupParticipant) {
+				p.WarnCount++
+			})
+			if err != nil {
+				i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error updating group participant info")
+				continue
+			}
+			logAction(database.ModActionWarn)
+			ctx.SendTextMessage(m.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "moderation.warn",
+					Other: "⚠️ @{{.User}} recebeu uma advertência ({{.Count}}/{{.Max}}) pela regra *{{.Rule}}*",
+				},
+				TemplateData: map[string]any{
+					"User":  m.Info.Sender.User,
+					"Count": participant.WarnCount,
+					"Max":   database.MaxWarnCount,
+					"Rule":  rule.Name,
+				},
+			}), &command.MessageOptions{MentionedJid: mention})
+			if participant.WarnCount >= database.MaxWarnCount {
+				kick()
+				removed = true
+			}
+
+		case moderation.ActionMute:
+			until := time.Now().Add(rule.MuteDuration)
+			if _, err := i.updateParticipant(m.Info.Chat.User, m.Info.Sender.User, func(p *database.GroupParticipant) {
+				p.MutedUntil = &until
+			}); err != nil {
+				i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error updating group participant info")
+				continue
+			}
+			i.UserDB.MU.Lock()
+			err := ctx.ScheduleUnmute(m.Info.Chat, m.Info.Sender.User, until)
+			i.UserDB.MU.Unlock()
+			if err != nil {
+				i.Log.Error().Err(err).Str("Group", m.Info.Chat.String()).Str("User", m.Info.Sender.User).Msg("Error scheduling unmute")
+			}
+			logAction(database.ModActionMute)
+			ctx.SendTextMessage(m.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "moderation.mute",
+					Other: "🔇 @{{.User}} foi silenciado por {{.Duration}} pela regra *{{.Rule}}*",
+				},
+				TemplateData: map[string]any{
+					"User":     m.Info.Sender.User,
+					"Duration": util.FormatDuration(rule.MuteDuration),
+					"Rule":     rule.Name,
+				},
+			}), &command.MessageOptions{MentionedJid: mention})
+
+		case moderation.ActionNotify:
+			var admins []string
+			for _, p := range groupMetadata.Participants {
+				if (p.IsAdmin || p.IsSuperAdmin) && p.JID.User != i.Client.Store.ID.User {
+					admins = append(admins, p.JID.String())
+				}
+			}
+			ctx.SendTextMessage(m.Info.Chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+				DefaultMessage: &i18n.Message{
+					ID:    "moderation.notify",
+					Other: "🚨 Atenção administradores! @{{.User}} acionou a regra *{{.Rule}}*",
+				},
+				TemplateData: map[string]any{
+					"User": m.Info.Sender.User,
+					"Rule": rule.Name,
+				},
+			}), &command.MessageOptions{
+				QuotedMessage: m,
+				MentionedJid:  append(admins, mention...),
+			})
+		}
+	}
+	return removed
+}
+
+// updateParticipant applies update to a group participant and saves it. The
+// participant is read again under the lock, as other messages from the member
+// may have changed it since it was loaded.
+func (i *EventHandler) updateParticipant(group, user string, update func(p *database.GroupParticipant)) (*database.GroupParticipant, error) {
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	participant, err := i.UserDB.GetParticipant(user, group)
+	if err != nil {
+		return nil, err
+	}
+	update(participant)
+	if err := i.UserDB.SaveParticipant(participant); err != nil {
+		return nil, err
+	}
+	return participant, nil
+}
+
+// messageHash identifies the content of a message to detect repeated messages.
+// Media is identified by the file hash and text by its normalized content.
+func messageHash(message *waE2E.Message, text string) string {
+	if sum := tmsg.GetMediaSHA256(message); sum != nil {
+		return hex.EncodeToString(sum)
+	}
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if text == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"meowabot/internal/util"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// applyNightMode closes the groups whose night started and reopens the ones
+// whose night ended. It only acts when the night starts or ends, so admins can
+// still open or close a group by hand in between. Disabling night mode in a
+// group the bot closed reopens it.
+func (i *EventHandler) applyNightMode() {
+	i.UserDB.MU.Lock()
+	groups, err := i.UserDB.GetNightModeGroups()
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		i.Log.Error().Err(err).Msg("Error retrieving night mode groups from database")
+		return
+	}
+	now := time.Now()
+	for _, groupInfo := range groups {
+		night := groupInfo.Night.Enabled && groupInfo.Night.IsNight(now)
+		if night == groupInfo.Night.Closed {
+			continue
+		}
+		group := types.NewJID(groupInfo.ID, types.GroupServer)
+		if !i.isBotAdmin(group) {
+			continue
+		}
+		if err := i.Client.SetGroupAnnounce(group, night); err != nil {
+			i.Log.Error().Err(err).Str("GroupID", groupInfo.ID).Bool("Night", night).Msg("Error applying night mode")
+			continue
+		}
+		if err := i.setNightClosed(groupInfo.ID, night); err != nil {
+			i.Log.Error().Err(err).Str("GroupID", groupInfo.ID).Msg("Error saving group info")
+		}
+
+		ctx := i.newContext(GetLocalizer(groupInfo.Language))
+		message := &i18n.Message{
+			ID:    "night.open",
+			Other: "☀️ Bom dia! O grupo foi aberto e todos podem voltar a enviar mensagens.",
+		}
+		if night {
+			message = &i18n.Message{
+				ID:    "night.close",
+				Other: "🌙 Modo noturno: o grupo foi fechado e será reaberto às {{.Open}}.",
+			}
+		}
+		ctx.SendTextMessage(group, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+			DefaultMessage: message,
+			TemplateData: map[string]any{
+				"Open": util.FormatClock(groupInfo.Night.OpenAt),
+			},
+		}), nil)
+	}
+}
+
+// setNightClosed records whether the bot closed the group for the night. The
+// group is read again because it may have changed while the lock was released.
+func (i *EventHandler) setNightClosed(groupID string, closed bool) error {
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	groupInfo, err := i.UserDB.GetGroupInfo(groupID)
+	if err != nil {
+		return err
+	}
+	groupInfo.Night.Closed = closed
+	return i.UserDB.SaveGroupInfo(groupInfo)
+}
+
+// isBotAdmin reports whether the bot is an admin of the group, using the
+// cached group info when there is one.
+func (i *EventHandler) isBotAdmin(group types.JID) bool {
+	info, ok := i.GetCachedGroupInfo(group)
+	if !ok {
+		var err error
+		if info, err = i.Client.GetGroupInfo(group); err != nil {
+			i.Log.Error().Err(err).Str("GroupID", group.User).Msg("Error getting group metadata")
+			return false
+		}
+		i.SetCachedGroupInfo(info)
+	}
+	for _, p := range info.Participants {
+		if p.JID.User == i.Client.Store.ID.User || p.PhoneNumber.User == i.Client.Store.ID.User {
+			return p.IsAdmin || p.IsSuperAdmin
+		}
+	}
+	return false
+}
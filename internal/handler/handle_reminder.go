@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// runReminderJob delivers a reminder to the chat it was created in, quoting
+// the message that created it.
+func (i *EventHandler) runReminderJob(_ context.Context, job *database.Job) error {
+	var payload command.ReminderPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	chat, err := types.ParseJID(job.ChatID)
+	if err != nil {
+		return err
+	}
+	target, err := types.ParseJID(payload.Target)
+	if err != nil {
+		return err
+	}
+
+	i.UserDB.MU.Lock()
+	languages, err := i.chatLanguages(chat, job.CreatorID)
+	i.UserDB.MU.Unlock()
+	if err != nil {
+		return err
+	}
+
+	ctx := i.newContext(GetLocalizer(languages...))
+	mentions := []string{target.String()}
+	creator := target.User
+	message := &i18n.Message{
+		ID:    "reminder.deliver",
+		Other: "⏰ @{{.User}}, lembrete: {{.Text}}",
+	}
+	if sender, err := types.ParseJID(payload.Sender); err == nil && sender.User != target.User {
+		message = &i18n.Message{
+			ID:    "reminder.deliver.from",
+			Other: "⏰ @{{.User}}, lembrete de @{{.Creator}}: {{.Text}}",
+		}
+		mentions = append(mentions, sender.String())
+		creator = sender.User
+	}
+	return ctx.SendTextMessage(chat, ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: message,
+		TemplateData: map[string]any{
+			"User":    target.User,
+			"Creator": creator,
+			"Text":    payload.Text,
+		},
+	}), &command.MessageOptions{
+		QuotedMessage: payload.QuotedMessage(chat),
+		MentionedJid:  mentions,
+	})
+}
+
+// chatLanguages returns the languages to talk to a user in a chat, the
+// user's own language first. Must be called with the database lock held.
+func (i *EventHandler) chatLanguages(chat types.JID, userID string) ([]string, error) {
+	user, err := i.UserDB.GetUserInfo(userID)
+	if err != nil {
+		return nil, err
+	}
+	if chat.Server != types.GroupServer {
+		return []string{user.Language}, nil
+	}
+	group, err := i.UserDB.GetGroupInfo(chat.User)
+	if err != nil {
+		return nil, err
+	}
+	return []string{user.Language, group.Language}, nil
+}
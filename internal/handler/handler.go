@@ -1,14 +1,19 @@
 package handler
 
 import (
+	"context"
 	"meowabot/internal/command"
 	"meowabot/internal/config"
 	"meowabot/internal/database"
+	"meowabot/internal/moderation"
+	"meowabot/internal/scheduler"
+	"meowabot/internal/tools/mediacache"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.mau.fi/whatsmeow"
@@ -24,6 +29,9 @@ type EventHandler struct {
 	UserDB    *database.DBInstance
 	Log       *zerolog.Logger
 	WaLogger  waLog.Logger
+	Scheduler *scheduler.Scheduler
+	// MediaCache caches the media of the commands, it can be nil
+	MediaCache *mediacache.Cache
 
 	cmd                 *command.CommandList
 	pairedChannel       []chan<- error
@@ -34,6 +42,9 @@ type EventHandler struct {
 	groupInfoCache      map[string]*cacheEntry
 	groupCacheMutex     sync.Mutex
 	userLastCommandTime map[string]time.Time
+	floodDetector       *moderation.FloodDetector
+	tasksCtx            context.Context
+	stopTasks           context.CancelFunc
 }
 
 type EventHandlerOptions struct {
@@ -43,22 +54,29 @@ type EventHandlerOptions struct {
 	UserDB    *database.DBInstance
 	Logger    *zerolog.Logger
 	WaLogger  waLog.Logger
+	// MediaCache caches the media of the commands, it can be nil
+	MediaCache *mediacache.Cache
 }
 
 func NewEventHandler(opts EventHandlerOptions) *EventHandler {
 	evt := &EventHandler{
-		Config:    opts.Config,
-		Client:    opts.Client,
-		Container: opts.Container,
-		UserDB:    opts.UserDB,
-		Log:       opts.Logger,
-		WaLogger:  opts.WaLogger,
+		Config:     opts.Config,
+		Client:     opts.Client,
+		Container:  opts.Container,
+		UserDB:     opts.UserDB,
+		Log:        opts.Logger,
+		WaLogger:   opts.WaLogger,
+		Scheduler:  scheduler.New(opts.UserDB, opts.Logger),
+		MediaCache: opts.MediaCache,
 
 		cmd:                 command.Default,
 		groupInfoCache:      make(map[string]*cacheEntry),
 		userLastCommandTime: make(map[string]time.Time),
+		floodDetector:       moderation.NewFloodDetector(),
 	}
+	evt.tasksCtx, evt.stopTasks = context.WithCancel(context.Background())
 	evt.receivedOldEvents.Store(true)
+	evt.registerJobs()
 	opts.Client.AddEventHandler(evt.handleEvent)
 	return evt
 }
@@ -116,3 +134,18 @@ func (i *EventHandler) handleEvent(evt any) {
 		clear(i.logoutChannel)
 	}
 }
+
+// newContext returns a command context that is not bound to a message, used to
+// reuse the message helpers outside of commands.
+func (i *EventHandler) newContext(localizer *i18n.Localizer) *command.CommandContext {
+	return &command.CommandContext{
+		Client:     i.Client,
+		Config:     i.Config,
+		DB:         i.UserDB,
+		Prefix:     i.Config.CommandPrefix,
+		Localizer:  localizer,
+		Log:        i.Log,
+		Scheduler:  i.Scheduler,
+		MediaCache: i.MediaCache,
+	}
+}
@@ -1,14 +1,16 @@
 package handler
 
 import (
+	"strings"
 	"time"
 
 	"go.mau.fi/whatsmeow/types"
 )
 
 type cacheEntry struct {
-	Info     *types.GroupInfo
-	expireAt time.Time
+	Info       *types.GroupInfo
+	inviteCode string
+	expireAt   time.Time
 }
 
 func (i *EventHandler) GetCachedGroupInfo(jid types.JID) (*types.GroupInfo, bool) {
@@ -43,3 +45,32 @@ func (i *EventHandler) SetCachedGroupInfo(group *types.GroupInfo) {
 	}
 	i.groupCacheMutex.Unlock()
 }
+
+// GetGroupInviteCode returns the invite code of a group the bot is admin of,
+// fetching it once and keeping it alongside the cached group info.
+func (i *EventHandler) GetGroupInviteCode(jid types.JID) (string, error) {
+	i.groupCacheMutex.Lock()
+	entry, ok := i.groupInfoCache[jid.User]
+	if ok && entry.inviteCode != "" {
+		i.groupCacheMutex.Unlock()
+		return entry.inviteCode, nil
+	}
+	i.groupCacheMutex.Unlock()
+
+	link, err := i.Client.GetGroupInviteLink(jid, false)
+	if err != nil {
+		return "", err
+	}
+	code := inviteCodeFromLink(link)
+
+	i.groupCacheMutex.Lock()
+	if entry, ok := i.groupInfoCache[jid.User]; ok {
+		entry.inviteCode = code
+	}
+	i.groupCacheMutex.Unlock()
+	return code, nil
+}
+
+func inviteCodeFromLink(link string) string {
+	return link[strings.LastIndex(link, "/")+1:]
+}
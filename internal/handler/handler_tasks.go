@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"meowabot/internal/command"
+	"meowabot/internal/database"
+	"meowabot/internal/scheduler"
+	"time"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// captchaSweepInterval is how often expired captchas are looked for. It is
+// finer than the one minute resolution of cron expressions, so the sweep runs
+// on its own ticker instead of being a scheduler job.
+const captchaSweepInterval = 15 * time.Second
+
+const (
+	jobNightMode     = "nightmode"
+	nightModeJobKey  = "nightmode"
+	nightModeJobCron = "* * * * *"
+)
+
+// StartBackgroundTasks starts the periodic jobs of the bot. They run until Stop is called.
+func (i *EventHandler) StartBackgroundTasks() {
+	i.scheduleLegacyMutes()
+	i.scheduleNightMode()
+	go i.Scheduler.Run(i.tasksCtx)
+	go i.runEvery(captchaSweepInterval, i.sweepExpiredCaptchas)
+}
+
+// Stop stops the background tasks.
+func (i *EventHandler) Stop() {
+	i.stopTasks()
+}
+
+func (i *EventHandler) runEvery(interval time.Duration, task func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-i.tasksCtx.Done():
+			return
+		case <-ticker.C:
+			task()
+		}
+	}
+}
+
+// registerJobs registers the handlers of the scheduler jobs.
+func (i *EventHandler) registerJobs() {
+	i.Scheduler.Handle(command.JobUnmute, i.runUnmuteJob)
+	i.Scheduler.Handle(command.JobReminder, i.runReminderJob)
+	i.Scheduler.Handle(command.JobAnnouncement, i.runAnnouncementJob)
+	i.Scheduler.Handle(jobNightMode, i.runNightModeJob)
+}
+
+// runNightModeJob opens and closes the groups with night mode every minute.
+func (i *EventHandler) runNightModeJob(_ context.Context, _ *database.Job) error {
+	i.applyNightMode()
+	return nil
+}
+
+// scheduleNightMode schedules the recurring night mode job. Scheduling
+// replaces the job left by the previous run, so there is only ever one. Runs
+// missed while the bot was offline are skipped, the next one catches up.
+func (i *EventHandler) scheduleNightMode() {
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	err := i.Scheduler.Schedule(&database.Job{
+		Name:    jobNightMode,
+		Key:     nightModeJobKey,
+		Cron:    nightModeJobCron,
+		Misfire: scheduler.MisfireSkip,
+	})
+	if err != nil {
+		i.Log.Error().Err(err).Msg("Error scheduling night mode")
+	}
+}
+
+// runUnmuteJob lifts an expired mute and lets the member know.
+func (i *EventHandler) runUnmuteJob(_ context.Context, job *database.Job) error {
+	var payload command.UnmutePayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	participant, err := i.UserDB.GetParticipant(payload.UserID, payload.GroupID)
+	if err != nil {
+		return err
+	}
+	// The mute was lifted by hand or extended by another job
+	if participant.MutedUntil == nil || participant.MutedUntil.After(time.Now()) {
+		return nil
+	}
+	participant.MutedUntil = nil
+	if err := i.UserDB.SaveParticipant(participant); err != nil {
+		return err
+	}
+	groupInfo, err := i.UserDB.GetGroupInfo(payload.GroupID)
+	if err != nil {
+		return err
+	}
+	user := types.NewJID(payload.UserID, types.DefaultUserServer)
+	ctx := i.newContext(GetLocalizer(groupInfo.Language))
+	ctx.SendTextMessage(types.NewJID(payload.GroupID, types.GroupServer), ctx.Localizer.MustLocalize(&i18n.LocalizeConfig{
+		DefaultMessage: &i18n.Message{
+			ID:    "moderation.unmute",
+			Other: "🔊 @{{.User}} pode voltar a enviar mensagens",
+		},
+		TemplateData: map[string]any{
+			"User": payload.UserID,
+		},
+	}), &command.MessageOptions{MentionedJid: []string{user.String()}})
+	return nil
+}
+
+// scheduleLegacyMutes schedules the end of the mutes set before mutes were
+// lifted by the scheduler. Scheduling replaces the job of the same member, so
+// mutes that already have one are left as they are.
+func (i *EventHandler) scheduleLegacyMutes() {
+	i.UserDB.MU.Lock()
+	defer i.UserDB.MU.Unlock()
+
+	muted, err := i.UserDB.GetMutedParticipants()
+	if err != nil {
+		i.Log.Error().Err(err).Msg("Error retrieving muted participants from database")
+		return
+	}
+	ctx := i.newContext(GetLocalizer(""))
+	for _, participant := range muted {
+		group := types.NewJID(participant.GroupID, types.GroupServer)
+		if err := ctx.ScheduleUnmute(group, participant.UserID, *participant.MutedUntil); err != nil {
+			i.Log.Error().Err(err).Str("GroupID", participant.GroupID).Str("User", participant.UserID).Msg("Error scheduling unmute")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package moderation
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	CaptchaMath  = "math"
+	CaptchaImage = "image"
+)
+
+// MaxCaptchaAttempts is the number of wrong answers after which a newcomer is removed.
+const MaxCaptchaAttempts = 3
+
+// captchaAlphabet leaves out characters that are easily confused, like O and 0.
+const captchaAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// NewMathCaptcha returns an arithmetic question and its answer. Higher
+// difficulties use larger numbers and more operations.
+func NewMathCaptcha(difficulty int) (string, string) {
+	switch {
+	case difficulty <= 1:
+		a, b := rand.IntN(10)+1, rand.IntN(10)+1
+		return fmt.Sprintf("%d + %d", a, b), fmt.Sprint(a + b)
+	case difficulty == 2:
+		if rand.IntN(2) == 0 {
+			a, b := rand.IntN(20)+10, rand.IntN(9)+1
+			return fmt.Sprintf("%d - %d", a, b), fmt.Sprint(a - b)
+		}
+		a, b := rand.IntN(8)+2, rand.IntN(8)+2
+		return fmt.Sprintf("%d × %d", a, b), fmt.Sprint(a * b)
+	default:
+		a, b, c := rand.IntN(8)+2, rand.IntN(8)+2, rand.IntN(20)+1
+		return fmt.Sprintf("%d × %d + %d", a, b, c), fmt.Sprint(a*b + c)
+	}
+}
+
+// NewTextCaptcha returns the random text drawn in an image captcha, from 4 to
+// 6 characters long depending on the difficulty.
+func NewTextCaptcha(difficulty int) string {
+	length := 3 + min(max(difficulty, 1), 3)
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = captchaAlphabet[rand.IntN(len(captchaAlphabet))]
+	}
+	return string(b)
+}
+
+// CheckCaptchaAnswer reports whether the message answers the challenge,
+// ignoring case and spaces.
+func CheckCaptchaAnswer(answer string, text string) bool {
+	text = strings.Join(strings.Fields(text), "")
+	return text != "" && strings.EqualFold(text, answer)
+}
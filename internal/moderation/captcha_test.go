@@ -0,0 +1,41 @@
+package moderation
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMathCaptcha(t *testing.T) {
+	for difficulty := 1; difficulty <= 3; difficulty++ {
+		for range 50 {
+			question, answer := NewMathCaptcha(difficulty)
+			require.NotEmpty(t, question)
+			n, err := strconv.Atoi(answer)
+			require.NoError(t, err)
+			assert.Positive(t, n, question)
+		}
+	}
+}
+
+func TestNewTextCaptcha(t *testing.T) {
+	assert.Len(t, NewTextCaptcha(0), 4)
+	assert.Len(t, NewTextCaptcha(1), 4)
+	assert.Len(t, NewTextCaptcha(3), 6)
+	assert.Len(t, NewTextCaptcha(10), 6)
+
+	for _, r := range NewTextCaptcha(3) {
+		assert.True(t, strings.ContainsRune(captchaAlphabet, r))
+	}
+}
+
+func TestCheckCaptchaAnswer(t *testing.T) {
+	assert.True(t, CheckCaptchaAnswer("AB3K", "ab3k"))
+	assert.True(t, CheckCaptchaAnswer("AB3K", " AB 3K "))
+	assert.True(t, CheckCaptchaAnswer("12", "12"))
+	assert.False(t, CheckCaptchaAnswer("12", "13"))
+	assert.False(t, CheckCaptchaAnswer("", ""))
+}
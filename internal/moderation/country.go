@@ -0,0 +1,116 @@
+package moderation
+
+import (
+	"fmt"
+	"meowabot/internal/database"
+	"meowabot/internal/phone"
+	"strings"
+)
+
+// CountryAllowed reports whether the phone number of a user passes the
+// country rules of a group. The most specific matching rule wins, area codes
+// over countries over calling codes, and deny wins between equally specific
+// rules. Numbers no rule matches are only allowed if every rule is a deny
+// rule.
+func CountryAllowed(rules []database.CountryRule, userID string) bool {
+	number, ok := phone.Parse(userID)
+	var hasAllow bool
+	best, allowed := -1, false
+	for _, rule := range rules {
+		hasAllow = hasAllow || rule.Allow
+		if !ok || !matchCountryRule(&rule, &number) {
+			continue
+		}
+		if s := ruleSpecificity(&rule); s > best || s == best && !rule.Allow {
+			best, allowed = s, rule.Allow
+		}
+	}
+	if best < 0 {
+		return !hasAllow
+	}
+	return allowed
+}
+
+func matchCountryRule(rule *database.CountryRule, number *phone.Number) bool {
+	if rule.Country != "" && rule.Country != number.Country.ISO {
+		return false
+	}
+	if rule.Code != "" && rule.Code != number.Country.CallingCode {
+		return false
+	}
+	return strings.HasPrefix(number.National, rule.Area)
+}
+
+func ruleSpecificity(rule *database.CountryRule) int {
+	var s int
+	if rule.Country != "" {
+		s = 1
+	}
+	if rule.Area != "" {
+		s = 2
+	}
+	return s
+}
+
+// ParseCountryRule parses the target of a country rule: a country name or
+// ISO code, a calling code like +1, optionally followed by an area code
+// after a colon, like BR:11 or +1:242.
+func ParseCountryRule(target string, allow bool) (database.CountryRule, error) {
+	rule := database.CountryRule{Allow: allow}
+	target, area, _ := strings.Cut(strings.TrimSpace(target), ":")
+	target, area = strings.TrimSpace(target), strings.TrimSpace(area)
+	if area != "" && strings.Trim(area, "0123456789") != "" {
+		return rule, fmt.Errorf("invalid area code %q", area)
+	}
+	rule.Area = area
+
+	if code, ok := strings.CutPrefix(target, "+"); ok || strings.Trim(target, "0123456789") == "" {
+		if !phone.IsCallingCode(code) {
+			return rule, fmt.Errorf("unknown calling code %q", code)
+		}
+		rule.Code = code
+		return rule, nil
+	}
+	country, ok := phone.FindCountry(target)
+	if !ok {
+		return rule, fmt.Errorf("unknown country %q", target)
+	}
+	rule.Country = country.ISO
+	return rule, nil
+}
+
+// FormatCountryRule renders the target of a rule back in the syntax
+// accepted by ParseCountryRule, with the country flag and name.
+func FormatCountryRule(rule *database.CountryRule) string {
+	var s string
+	if rule.Country != "" {
+		s = rule.Country
+		if country, ok := phone.CountryByISO(rule.Country); ok {
+			s = flag(country.ISO) + " " + country.ISO
+			if rule.Area != "" {
+				s += ":" + rule.Area
+			}
+			return s + " (" + country.Name + ")"
+		}
+	} else {
+		s = "+" + rule.Code
+	}
+	if rule.Area != "" {
+		s += ":" + rule.Area
+	}
+	return s
+}
+
+// SameCountryTarget reports whether two rules apply to the same numbers.
+func SameCountryTarget(a, b *database.CountryRule) bool {
+	return a.Country == b.Country && a.Code == b.Code && a.Area == b.Area
+}
+
+// flag returns the emoji flag of a country from its ISO code.
+func flag(iso string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(iso) {
+		b.WriteRune(0x1F1E6 + r - 'A')
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountryAllowed(t *testing.T) {
+	const (
+		saoPaulo = "5511987654321"
+		rio      = "5521987654321"
+		newYork  = "12125551234"
+		bahamas  = "12425551234"
+		lisbon   = "351912345678"
+	)
+
+	// No rules allow everyone
+	assert.True(t, CountryAllowed(nil, newYork))
+
+	// Allow rules turn the filter into an allowlist
+	rules := []database.CountryRule{{Allow: true, Country: "BR"}}
+	assert.True(t, CountryAllowed(rules, saoPaulo))
+	assert.False(t, CountryAllowed(rules, newYork))
+	assert.False(t, CountryAllowed(rules, "not a number"))
+
+	// "Brazil except São Paulo"
+	rules = append(rules, database.CountryRule{Country: "BR", Area: "11"})
+	assert.False(t, CountryAllowed(rules, saoPaulo))
+	assert.True(t, CountryAllowed(rules, rio))
+
+	// Overlapping calling codes are told apart
+	rules = []database.CountryRule{{Allow: true, Country: "US"}}
+	assert.True(t, CountryAllowed(rules, newYork))
+	assert.False(t, CountryAllowed(rules, bahamas))
+
+	// A calling code covers every country that shares it, unless a more
+	// specific rule says otherwise
+	rules = []database.CountryRule{{Allow: true, Code: "1"}, {Country: "BS"}}
+	assert.True(t, CountryAllowed(rules, newYork))
+	assert.False(t, CountryAllowed(rules, bahamas))
+
+	// Deny rules alone only block their targets
+	rules = []database.CountryRule{{Country: "PT"}}
+	assert.False(t, CountryAllowed(rules, lisbon))
+	assert.True(t, CountryAllowed(rules, newYork))
+
+	// Deny wins between equally specific rules
+	rules = []database.CountryRule{{Allow: true, Country: "PT"}, {Country: "PT"}}
+	assert.False(t, CountryAllowed(rules, lisbon))
+}
+
+func TestParseCountryRule(t *testing.T) {
+	for _, tt := range []struct {
+		target string
+		rule   database.CountryRule
+	}{
+		{"BR", database.CountryRule{Allow: true, Country: "BR"}},
+		{"brazil", database.CountryRule{Allow: true, Country: "BR"}},
+		{"United States", database.CountryRule{Allow: true, Country: "US"}},
+		{"br:11", database.CountryRule{Allow: true, Country: "BR", Area: "11"}},
+		{"+1", database.CountryRule{Allow: true, Code: "1"}},
+		{"55", database.CountryRule{Allow: true, Code: "55"}},
+		{"+1:242", database.CountryRule{Allow: true, Code: "1", Area: "242"}},
+	} {
+		rule, err := ParseCountryRule(tt.target, true)
+		require.NoError(t, err, tt.target)
+		assert.Equal(t, tt.rule, rule, tt.target)
+	}
+
+	for _, invalid := range []string{"", "Atlantis", "+999", "BR:abc"} {
+		_, err := ParseCountryRule(invalid, true)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestFormatCountryRule(t *testing.T) {
+	assert.Equal(t, "🇧🇷 BR (Brazil)", FormatCountryRule(&database.CountryRule{Country: "BR"}))
+	assert.Equal(t, "🇧🇷 BR:11 (Brazil)", FormatCountryRule(&database.CountryRule{Country: "BR", Area: "11"}))
+	assert.Equal(t, "+1:242", FormatCountryRule(&database.CountryRule{Code: "1", Area: "242"}))
+}
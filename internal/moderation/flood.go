@@ -0,0 +1,138 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+	"sync"
+	"time"
+)
+
+const (
+	FloodMessages = "flood"
+	FloodRepeated = "repeated"
+	FloodMedia    = "mediaflood"
+)
+
+// strikeDecay is how long a member must behave for their flood strikes to reset.
+const strikeDecay = time.Hour
+
+const cleanupInterval = 5 * time.Minute
+
+type floodEvent struct {
+	at      time.Time
+	hash    string
+	isMedia bool
+}
+
+type floodState struct {
+	events     []floodEvent
+	strikes    int
+	lastStrike time.Time
+}
+
+// FloodDetector keeps a sliding window of the recent messages of each group
+// member to detect message floods, repeated messages and media bursts.
+type FloodDetector struct {
+	mu          sync.Mutex
+	states      map[string]*floodState
+	lastCleanup time.Time
+}
+
+func NewFloodDetector() *FloodDetector {
+	return &FloodDetector{states: make(map[string]*floodState)}
+}
+
+// Check records a message and returns the kind of flood it completes, if any,
+// along with the number of strikes the member has accumulated. The window of
+// the member is cleared after a violation so a single burst is punished once.
+func (d *FloodDetector) Check(groupID string, userID string, hash string, isMedia bool, settings *database.FloodSettings, now time.Time) (string, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastCleanup) > cleanupInterval {
+		d.cleanup(now, settings.Window())
+	}
+
+	key := groupID + ":" + userID
+	state, ok := d.states[key]
+	if !ok {
+		state = &floodState{}
+		d.states[key] = state
+	}
+
+	cutoff := now.Add(-settings.Window())
+	events := state.events[:0]
+	for _, e := range state.events {
+		if e.at.After(cutoff) {
+			events = append(events, e)
+		}
+	}
+	state.events = append(events, floodEvent{at: now, hash: hash, isMedia: isMedia})
+
+	var kind string
+	var repeats, media int
+	for _, e := range state.events {
+		if hash != "" && e.hash == hash {
+			repeats++
+		}
+		if e.isMedia {
+			media++
+		}
+	}
+	switch {
+	case settings.Repeats > 0 && repeats >= settings.Repeats:
+		kind = FloodRepeated
+	case settings.Media > 0 && media >= settings.Media:
+		kind = FloodMedia
+	case settings.Messages > 0 && len(state.events) >= settings.Messages:
+		kind = FloodMessages
+	}
+	if kind == "" {
+		return "", state.strikes
+	}
+
+	if now.Sub(state.lastStrike) > strikeDecay {
+		state.strikes = 0
+	}
+	state.strikes++
+	state.lastStrike = now
+	state.events = nil
+	return kind, state.strikes
+}
+
+// Reset forgets the recent messages and strikes of a member.
+func (d *FloodDetector) Reset(groupID string, userID string) {
+	d.mu.Lock()
+	delete(d.states, groupID+":"+userID)
+	d.mu.Unlock()
+}
+
+func (d *FloodDetector) cleanup(now time.Time, window time.Duration) {
+	for key, state := range d.states {
+		if now.Sub(state.lastStrike) < strikeDecay {
+			continue
+		}
+		if len(state.events) == 0 || now.Sub(state.events[len(state.events)-1].at) > window {
+			delete(d.states, key)
+		}
+	}
+	d.lastCleanup = now
+}
+
+// FloodPenalty returns the rule applied to a member for their nth flood strike:
+// a warning, then a temporary mute and finally removal from the group.
+func FloodPenalty(kind string, strikes int, settings *database.FloodSettings) *database.ModerationRule {
+	rule := &database.ModerationRule{
+		Name:    kind,
+		Enabled: true,
+	}
+	switch {
+	case strikes <= 1:
+		rule.Actions = []string{ActionDelete, ActionWarn}
+	case strikes == 2:
+		rule.Actions = []string{ActionDelete, ActionMute}
+		rule.MuteDuration = settings.MuteDuration
+	default:
+		rule.Actions = []string{ActionDelete, ActionKick}
+	}
+	return rule
+}
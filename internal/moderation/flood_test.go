@@ -0,0 +1,65 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFloodDetector(t *testing.T) {
+	settings := &database.FloodSettings{Messages: 4, WindowSeconds: 10, Repeats: 3, Media: 2, MuteDuration: time.Minute}
+	d := NewFloodDetector()
+	now := time.Now()
+
+	// Messages spread over more than the window are fine
+	for i := range 6 {
+		kind, _ := d.Check("g", "u", string(rune('a'+i)), false, settings, now.Add(time.Duration(i)*5*time.Second))
+		assert.Empty(t, kind)
+	}
+
+	now = now.Add(time.Minute)
+	for i := range 3 {
+		kind, _ := d.Check("g", "u", string(rune('a'+i)), false, settings, now)
+		assert.Empty(t, kind)
+	}
+	kind, strikes := d.Check("g", "u", "d", false, settings, now)
+	assert.Equal(t, FloodMessages, kind)
+	assert.Equal(t, 1, strikes)
+
+	// The window is cleared after a violation
+	kind, _ = d.Check("g", "u", "x", false, settings, now)
+	assert.Empty(t, kind)
+
+	// Other members and groups are tracked separately
+	kind, _ = d.Check("g", "other", "x", false, settings, now)
+	assert.Empty(t, kind)
+	kind, _ = d.Check("g", "u", "x", false, settings, now)
+	assert.Empty(t, kind)
+	kind, strikes = d.Check("g", "u", "x", false, settings, now)
+	assert.Equal(t, FloodRepeated, kind)
+	assert.Equal(t, 2, strikes)
+
+	kind, _ = d.Check("g", "u", "s1", true, settings, now)
+	assert.Empty(t, kind)
+	kind, strikes = d.Check("g", "u", "s2", true, settings, now)
+	assert.Equal(t, FloodMedia, kind)
+	assert.Equal(t, 3, strikes)
+
+	// Strikes decay after a while
+	kind, strikes = d.Check("g", "u", "s3", true, settings, now.Add(2*time.Hour))
+	assert.Empty(t, kind)
+	kind, strikes = d.Check("g", "u", "s4", true, settings, now.Add(2*time.Hour))
+	assert.Equal(t, FloodMedia, kind)
+	assert.Equal(t, 1, strikes)
+}
+
+func TestFloodPenalty(t *testing.T) {
+	settings := &database.FloodSettings{MuteDuration: time.Minute}
+	assert.Equal(t, []string{ActionDelete, ActionWarn}, FloodPenalty(FloodMessages, 1, settings).Actions)
+	mute := FloodPenalty(FloodMessages, 2, settings)
+	assert.Equal(t, []string{ActionDelete, ActionMute}, mute.Actions)
+	assert.Equal(t, time.Minute, mute.MuteDuration)
+	assert.Equal(t, []string{ActionDelete, ActionKick}, FloodPenalty(FloodMessages, 3, settings).Actions)
+}
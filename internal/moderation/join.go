@@ -0,0 +1,44 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+)
+
+const (
+	JoinPolicyManual = ""       // Every request is left to the admins
+	JoinPolicyFilter = "filter" // Requests from blocked users are rejected, the rest is left to the admins
+	JoinPolicyAuto   = "auto"   // Requests from blocked users are rejected and the rest approved
+)
+
+var JoinPolicies = []string{JoinPolicyManual, JoinPolicyFilter, JoinPolicyAuto}
+
+const (
+	JoinApprove = "approve"
+	JoinReject  = "reject"
+)
+
+// JoinRequestVerdict decides a request to join the group according to its
+// join policy, reusing the country rules and the blacklist. When the group
+// has country rules and the phone number of the requester isn't known, the
+// request is left to the admins. It returns the action to take and the reason
+// of a rejection, or an empty action when the request is left to the admins.
+func JoinRequestVerdict(group *database.Group, number string, blacklisted bool) (string, string) {
+	if group.JoinPolicy == JoinPolicyManual {
+		return "", ""
+	}
+	if blacklisted {
+		return JoinReject, database.ModReasonBlacklist
+	}
+	if len(group.CountryRules) > 0 {
+		if number == "" {
+			return "", ""
+		}
+		if !CountryAllowed(group.CountryRules, number) {
+			return JoinReject, database.ModReasonDDI
+		}
+	}
+	if group.JoinPolicy == JoinPolicyAuto {
+		return JoinApprove, ""
+	}
+	return "", ""
+}
@@ -0,0 +1,40 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJoinRequestVerdict(t *testing.T) {
+	group := &database.Group{CountryRules: []database.CountryRule{{Allow: true, Country: "BR"}}}
+
+	// Manual groups leave everything to the admins
+	action, _ := JoinRequestVerdict(group, "15551234567", true)
+	assert.Empty(t, action)
+
+	group.JoinPolicy = JoinPolicyFilter
+	action, reason := JoinRequestVerdict(group, "5511987654321", true)
+	assert.Equal(t, JoinReject, action)
+	assert.Equal(t, database.ModReasonBlacklist, reason)
+	action, reason = JoinRequestVerdict(group, "15551234567", false)
+	assert.Equal(t, JoinReject, action)
+	assert.Equal(t, database.ModReasonDDI, reason)
+	action, _ = JoinRequestVerdict(group, "5511987654321", false)
+	assert.Empty(t, action)
+
+	group.JoinPolicy = JoinPolicyAuto
+	action, _ = JoinRequestVerdict(group, "5511987654321", false)
+	assert.Equal(t, JoinApprove, action)
+	action, _ = JoinRequestVerdict(group, "15551234567", false)
+	assert.Equal(t, JoinReject, action)
+	// Without a phone number, e.g. a LID that can't be resolved, the country
+	// can't be checked and the request stays pending
+	action, _ = JoinRequestVerdict(group, "", false)
+	assert.Empty(t, action)
+
+	group.CountryRules = nil
+	action, _ = JoinRequestVerdict(group, "", false)
+	assert.Equal(t, JoinApprove, action)
+}
@@ -0,0 +1,231 @@
+package moderation
+
+import (
+	"fmt"
+	"meowabot/internal/database"
+	"meowabot/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ParseRule builds a rule from the rule command syntax:
+//
+//	<name> [key=value | flag]...
+//
+// For example `pix regex="(?i)pix" types=image actions=delete,mute mute=10m`.
+func ParseRule(args string) (*database.ModerationRule, error) {
+	tokens, err := tokenize(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 || strings.Contains(tokens[0], "=") {
+		return nil, fmt.Errorf("missing rule name")
+	}
+
+	rule := &database.ModerationRule{
+		Name:         tokens[0],
+		Actions:      []string{ActionDelete},
+		ExemptAdmins: true,
+		ExemptRoles:  []string{RoleOwner},
+		Enabled:      true,
+	}
+	for _, token := range tokens[1:] {
+		key, value, _ := strings.Cut(token, "=")
+		if err := applyRuleOption(rule, strings.ToLower(key), value); err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasConditions(&rule.Conditions) {
+		return nil, fmt.Errorf("rule has no conditions")
+	}
+	if slices.Contains(rule.Actions, ActionMute) && rule.MuteDuration == 0 {
+		return nil, fmt.Errorf("mute action needs a mute duration")
+	}
+	return rule, nil
+}
+
+func applyRuleOption(rule *database.ModerationRule, key string, value string) error {
+	c := &rule.Conditions
+	var err error
+	switch key {
+	case "regex":
+		if _, err = compileRegex(value); err == nil {
+			c.Regex = value
+		}
+	case "url":
+		c.AnyURL = true
+	case "invite":
+		c.InviteLink = true
+	case "domains":
+		c.Domains = normalizeDomains(splitList(value))
+	case "except":
+		c.ExceptDomains = normalizeDomains(splitList(value))
+	case "mentions":
+		c.MinMentions, err = strconv.Atoi(value)
+	case "massmention":
+		c.MassMention = true
+	case "types":
+		c.MessageTypes = splitList(value)
+		for _, t := range c.MessageTypes {
+			if !slices.Contains(MessageTypes, t) {
+				return fmt.Errorf("unknown message type %q", t)
+			}
+		}
+	case "caption":
+		c.MinCaptionLength, err = strconv.Atoi(value)
+	case "forwarded":
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 32)
+		c.MinForwardingScore = uint32(n)
+	case "newmember":
+		c.MaxMemberAge, err = util.ParseDuration(value)
+	case "actions":
+		rule.Actions = splitList(value)
+		if len(rule.Actions) == 0 {
+			return fmt.Errorf("rule has no actions")
+		}
+		for _, a := range rule.Actions {
+			if !slices.Contains(Actions, a) {
+				return fmt.Errorf("unknown action %q", a)
+			}
+		}
+	case "mute":
+		rule.MuteDuration, err = util.ParseDuration(value)
+	case "exempt":
+		rule.ExemptAdmins = false
+		rule.ExemptRoles = nil
+		for _, role := range splitList(value) {
+			switch role {
+			case "none":
+			case "admins":
+				rule.ExemptAdmins = true
+			case RoleOwner, RolePremium:
+				rule.ExemptRoles = append(rule.ExemptRoles, role)
+			default:
+				return fmt.Errorf("unknown role %q", role)
+			}
+		}
+	case "priority":
+		rule.Priority, err = strconv.Atoi(value)
+	case "dryrun":
+		rule.DryRun = true
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return nil
+}
+
+func hasConditions(c *database.RuleConditions) bool {
+	return c.Regex != "" || c.AnyURL || c.InviteLink || len(c.Domains) > 0 || c.MinMentions > 0 ||
+		c.MassMention || len(c.MessageTypes) > 0 || c.MinCaptionLength > 0 || c.MinForwardingScore > 0 ||
+		c.MaxMemberAge > 0 || c.Blacklisted || c.WarnLimit || c.Muted
+}
+
+// FormatRule renders a rule back in the rule command syntax.
+func FormatRule(rule *database.ModerationRule) string {
+	c := &rule.Conditions
+	parts := []string{rule.Name}
+	if c.Regex != "" {
+		parts = append(parts, `regex="`+c.Regex+`"`)
+	}
+	if c.AnyURL {
+		parts = append(parts, "url")
+	}
+	if c.InviteLink {
+		parts = append(parts, "invite")
+	}
+	if len(c.Domains) > 0 {
+		parts = append(parts, "domains="+strings.Join(c.Domains, ","))
+	}
+	if len(c.ExceptDomains) > 0 {
+		parts = append(parts, "except="+strings.Join(c.ExceptDomains, ","))
+	}
+	if c.MinMentions > 0 {
+		parts = append(parts, "mentions="+strconv.Itoa(c.MinMentions))
+	}
+	if c.MassMention {
+		parts = append(parts, "massmention")
+	}
+	if len(c.MessageTypes) > 0 {
+		parts = append(parts, "types="+strings.Join(c.MessageTypes, ","))
+	}
+	if c.MinCaptionLength > 0 {
+		parts = append(parts, "caption="+strconv.Itoa(c.MinCaptionLength))
+	}
+	if c.MinForwardingScore > 0 {
+		parts = append(parts, "forwarded="+strconv.FormatUint(uint64(c.MinForwardingScore), 10))
+	}
+	if c.MaxMemberAge > 0 {
+		parts = append(parts, "newmember="+util.FormatDuration(c.MaxMemberAge))
+	}
+	parts = append(parts, "actions="+strings.Join(rule.Actions, ","))
+	if rule.MuteDuration > 0 {
+		parts = append(parts, "mute="+util.FormatDuration(rule.MuteDuration))
+	}
+	exempt := slices.Clone(rule.ExemptRoles)
+	if rule.ExemptAdmins {
+		exempt = append([]string{"admins"}, exempt...)
+	}
+	if len(exempt) == 0 {
+		exempt = []string{"none"}
+	}
+	parts = append(parts, "exempt="+strings.Join(exempt, ","))
+	if rule.Priority != 0 {
+		parts = append(parts, "priority="+strconv.Itoa(rule.Priority))
+	}
+	if rule.DryRun {
+		parts = append(parts, "dryrun")
+	}
+	return strings.Join(parts, " ")
+}
+
+func normalizeDomains(domains []string) []string {
+	for i, d := range domains {
+		if d != util.ShortenersEntry {
+			domains[i] = util.NormalizeDomain(d)
+		}
+	}
+	return domains
+}
+
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(strings.ToLower(s), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// tokenize splits on spaces, keeping double-quoted values together.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	var b strings.Builder
+	var quoted bool
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			quoted = !quoted
+		case (r == ' ' || r == '\n' || r == '\t') && !quoted:
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens, nil
+}
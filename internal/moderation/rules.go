@@ -0,0 +1,197 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+	"meowabot/internal/util"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ActionDelete = "delete"
+	ActionWarn   = "warn"
+	ActionKick   = "kick"
+	ActionMute   = "mute"
+	ActionNotify = "notify"
+)
+
+const (
+	RoleOwner   = "owner"
+	RolePremium = "premium"
+)
+
+var Actions = []string{ActionDelete, ActionWarn, ActionKick, ActionMute, ActionNotify}
+
+var MessageTypes = []string{"text", "image", "video", "gif", "ptv", "audio", "ptt", "sticker", "document", "contact", "location", "poll"}
+
+// Message is the information about a group message the rules are matched against.
+type Message struct {
+	Text            string
+	Type            string
+	Mentions        int
+	ForwardingScore uint32
+	GroupSize       int
+	InviteCode      string // The invite code of the group the message was sent to
+
+	IsAdmin     bool
+	IsOwner     bool
+	IsPremium   bool
+	Participant *database.GroupParticipant
+}
+
+// BuiltinRules returns the rules implied by the group settings. They always
+// run before the group's custom rules.
+func BuiltinRules(group *database.Group) []database.ModerationRule {
+	actions := []string{ActionDelete}
+	if group.RemoveUser {
+		actions = append(actions, ActionKick)
+	}
+	roles := []string{RoleOwner}
+	allowlist := DomainList(group.LinkAllowlist)
+	blocklist := DomainList(group.LinkBlocklist)
+
+	rules := []database.ModerationRule{
+		{Name: database.ModReasonMuted, Conditions: database.RuleConditions{Muted: true}, Actions: []string{ActionDelete}, ExemptAdmins: true, ExemptRoles: roles, Enabled: true},
+		{Name: database.ModReasonBlacklist, Conditions: database.RuleConditions{Blacklisted: true}, Actions: actions, ExemptAdmins: true, ExemptRoles: roles, Enabled: true},
+		{Name: database.ModReasonWarnLimit, Conditions: database.RuleConditions{WarnLimit: true}, Actions: actions, ExemptAdmins: true, ExemptRoles: roles, Enabled: true},
+		{Name: database.ModReasonBlocklist, Conditions: database.RuleConditions{Domains: blocklist}, Actions: actions, ExemptAdmins: true, ExemptRoles: roles, Enabled: len(blocklist) > 0},
+		{Name: database.ModReasonAntiLink, Conditions: database.RuleConditions{AnyURL: true, ExceptDomains: allowlist}, Actions: actions, ExemptAdmins: true, ExemptRoles: roles, Enabled: group.IsAntiLink},
+		{Name: database.ModReasonAntiWALink, Conditions: database.RuleConditions{InviteLink: true}, Actions: actions, ExemptAdmins: true, ExemptRoles: roles, Enabled: group.IsAntiWALink},
+		{Name: database.ModReasonMassMention, Conditions: database.RuleConditions{MassMention: true}, Actions: actions, ExemptAdmins: true, ExemptRoles: roles, Enabled: true},
+	}
+	return rules
+}
+
+// Evaluate returns the enabled rules that match the message and the sender
+// is not exempt from, in order. They are applied until one removes the
+// message, so a dry run or a rule that only warns doesn't hide the rules
+// after it.
+func Evaluate(rules []database.ModerationRule, msg *Message) []*database.ModerationRule {
+	var matched []*database.ModerationRule
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Enabled || IsExempt(rule, msg) {
+			continue
+		}
+		if Match(&rule.Conditions, msg) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched
+}
+
+func IsExempt(rule *database.ModerationRule, msg *Message) bool {
+	if rule.ExemptAdmins && msg.IsAdmin {
+		return true
+	}
+	for _, role := range rule.ExemptRoles {
+		switch role {
+		case RoleOwner:
+			if msg.IsOwner {
+				return true
+			}
+		case RolePremium:
+			if msg.IsPremium {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Match reports whether the message satisfies every condition that is set.
+// Conditions with no value set are ignored; a rule without any condition
+// never matches.
+func Match(c *database.RuleConditions, msg *Message) bool {
+	var checked bool
+	check := func(ok bool) bool {
+		checked = true
+		return ok
+	}
+	p := msg.Participant
+
+	if c.Blacklisted && !check(p != nil && p.IsBlacklisted) {
+		return false
+	}
+	if c.WarnLimit && !check(p != nil && p.WarnCount >= database.MaxWarnCount) {
+		return false
+	}
+	if c.Muted && !check(p != nil && p.MutedUntil != nil && time.Now().Before(*p.MutedUntil)) {
+		return false
+	}
+	if c.MaxMemberAge > 0 && !check(p != nil && p.JoinedAt != nil && time.Since(*p.JoinedAt) < c.MaxMemberAge) {
+		return false
+	}
+	if len(c.MessageTypes) > 0 && !check(slices.Contains(c.MessageTypes, msg.Type)) {
+		return false
+	}
+	if c.Regex != "" {
+		re, err := compileRegex(c.Regex)
+		if !check(err == nil && re.MatchString(msg.Text)) {
+			return false
+		}
+	}
+	if c.AnyURL || len(c.Domains) > 0 {
+		links := util.ExtractLinks(msg.Text)
+		if c.AnyURL && !check(slices.ContainsFunc(links, func(l util.Link) bool {
+			return !util.MatchAnyDomain(c.ExceptDomains, l.Host) && !isOwnInvite(l, msg.InviteCode)
+		})) {
+			return false
+		}
+		if len(c.Domains) > 0 && !check(slices.ContainsFunc(links, func(l util.Link) bool {
+			return util.MatchAnyDomain(c.Domains, l.Host)
+		})) {
+			return false
+		}
+	}
+	if c.InviteLink && !check(slices.ContainsFunc(util.InviteCodes(msg.Text), func(code string) bool {
+		return code != msg.InviteCode
+	})) {
+		return false
+	}
+	if c.MinMentions > 0 && !check(msg.Mentions >= c.MinMentions) {
+		return false
+	}
+	if c.MassMention && !check(msg.GroupSize > 2 && msg.Mentions >= msg.GroupSize-1) {
+		return false
+	}
+	if c.MinCaptionLength > 0 && !check(msg.Type != "text" && len([]rune(msg.Text)) >= c.MinCaptionLength) {
+		return false
+	}
+	if c.MinForwardingScore > 0 && !check(msg.ForwardingScore >= c.MinForwardingScore) {
+		return false
+	}
+	return checked
+}
+
+func isOwnInvite(link util.Link, inviteCode string) bool {
+	return inviteCode != "" && link.Host == "chat.whatsapp.com" && strings.Trim(link.Path, "/") == inviteCode
+}
+
+// DomainList splits a comma-separated list of domain patterns.
+func DomainList(s string) []string {
+	var domains []string
+	for d := range strings.SplitSeq(s, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return domains
+}
+
+var regexCache sync.Map
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
@@ -0,0 +1,139 @@
+package moderation
+
+import (
+	"meowabot/internal/database"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateBuiltinRules(t *testing.T) {
+	group := &database.Group{IsAntiLink: true, RemoveUser: true}
+	rules := BuiltinRules(group)
+
+	msg := &Message{Text: "look at https://example.com", Type: "text", GroupSize: 10, Participant: &database.GroupParticipant{}}
+	matched := Evaluate(rules, msg)
+	require.Len(t, matched, 1)
+	assert.Equal(t, database.ModReasonAntiLink, matched[0].Name)
+	assert.Equal(t, []string{ActionDelete, ActionKick}, matched[0].Actions)
+
+	// Admins are exempt from the builtin rules
+	msg.IsAdmin = true
+	assert.Empty(t, Evaluate(rules, msg))
+
+	// Disabled settings don't match
+	msg.IsAdmin = false
+	group.IsAntiLink = false
+	assert.Empty(t, Evaluate(BuiltinRules(group), msg))
+
+	until := time.Now().Add(time.Minute)
+	msg.Participant.MutedUntil = &until
+	matched = Evaluate(BuiltinRules(group), msg)
+	require.Len(t, matched, 1)
+	assert.Equal(t, database.ModReasonMuted, matched[0].Name)
+}
+
+func TestEvaluateDryRunFirst(t *testing.T) {
+	rules := []database.ModerationRule{
+		{Name: "test", Conditions: database.RuleConditions{Regex: "pix"}, Actions: []string{ActionDelete}, DryRun: true, Enabled: true},
+		{Name: "warn", Conditions: database.RuleConditions{Regex: "pix"}, Actions: []string{ActionWarn}, Enabled: true},
+		{Name: "scam", Conditions: database.RuleConditions{Regex: "pix"}, Actions: []string{ActionDelete}, Enabled: true},
+		{Name: "other", Conditions: database.RuleConditions{Regex: "promo"}, Actions: []string{ActionDelete}, Enabled: true},
+	}
+	msg := &Message{Text: "manda o pix", Type: "text", Participant: &database.GroupParticipant{}}
+
+	// The dry run and the warning don't hide the rule that deletes the message
+	matched := Evaluate(rules, msg)
+	require.Len(t, matched, 3)
+	assert.Equal(t, "test", matched[0].Name)
+	assert.Equal(t, "warn", matched[1].Name)
+	assert.Equal(t, "scam", matched[2].Name)
+}
+
+func TestMatchConditions(t *testing.T) {
+	joined := time.Now().Add(-time.Hour)
+	msg := &Message{
+		Text:            "promo em https://sub.bit.ly/abc",
+		Type:            "image",
+		Mentions:        3,
+		ForwardingScore: 5,
+		GroupSize:       20,
+		Participant:     &database.GroupParticipant{JoinedAt: &joined},
+	}
+
+	tests := []struct {
+		name  string
+		cond  database.RuleConditions
+		match bool
+	}{
+		{"empty", database.RuleConditions{}, false},
+		{"regex", database.RuleConditions{Regex: "(?i)PROMO"}, true},
+		{"regex no match", database.RuleConditions{Regex: "pix"}, false},
+		{"domain exact", database.RuleConditions{Domains: []string{"bit.ly"}}, false},
+		{"domain wildcard", database.RuleConditions{Domains: []string{"*.bit.ly"}}, true},
+		{"domain shorteners", database.RuleConditions{Domains: []string{"shorteners"}}, true},
+		{"url except", database.RuleConditions{AnyURL: true, ExceptDomains: []string{"*.bit.ly"}}, false},
+		{"domain other", database.RuleConditions{Domains: []string{"tinyurl.com"}}, false},
+		{"types", database.RuleConditions{MessageTypes: []string{"video", "image"}}, true},
+		{"mentions", database.RuleConditions{MinMentions: 3}, true},
+		{"mentions above", database.RuleConditions{MinMentions: 4}, false},
+		{"caption", database.RuleConditions{MinCaptionLength: 10}, true},
+		{"forwarded", database.RuleConditions{MinForwardingScore: 5}, true},
+		{"new member", database.RuleConditions{MaxMemberAge: 2 * time.Hour}, true},
+		{"old member", database.RuleConditions{MaxMemberAge: 30 * time.Minute}, false},
+		{"all must match", database.RuleConditions{Regex: "promo", MessageTypes: []string{"video"}}, false},
+		{"mass mention", database.RuleConditions{MassMention: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.match, Match(&tt.cond, msg))
+		})
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule(`pix regex="(?i)chave pix" types=image,video actions=delete,mute mute=10m exempt=admins,premium priority=2 dryrun`)
+	require.NoError(t, err)
+	assert.Equal(t, "pix", rule.Name)
+	assert.Equal(t, "(?i)chave pix", rule.Conditions.Regex)
+	assert.Equal(t, []string{"image", "video"}, rule.Conditions.MessageTypes)
+	assert.Equal(t, []string{ActionDelete, ActionMute}, rule.Actions)
+	assert.Equal(t, 10*time.Minute, rule.MuteDuration)
+	assert.True(t, rule.ExemptAdmins)
+	assert.Equal(t, []string{RolePremium}, rule.ExemptRoles)
+	assert.Equal(t, 2, rule.Priority)
+	assert.True(t, rule.DryRun)
+
+	// Formatting a rule yields the same rule again
+	again, err := ParseRule(FormatRule(rule))
+	require.NoError(t, err)
+	assert.Equal(t, rule, again)
+
+	for _, invalid := range []string{
+		"",
+		"noconditions",
+		"badregex regex=(",
+		"badaction url actions=explode",
+		"nomute url actions=mute",
+		`unterminated regex="abc`,
+		"badtype types=hologram",
+	} {
+		_, err := ParseRule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMatchOwnInvite(t *testing.T) {
+	inviteLink := database.RuleConditions{InviteLink: true}
+	anyURL := database.RuleConditions{AnyURL: true}
+
+	own := &Message{Text: "https://chat.whatsapp.com/AbCdEfGhIjKlMnOpQrStUv", InviteCode: "AbCdEfGhIjKlMnOpQrStUv"}
+	assert.False(t, Match(&inviteLink, own))
+	assert.False(t, Match(&anyURL, own))
+
+	foreign := &Message{Text: "https://chat.whatsapp.com/ZyXwVuTsRqPoNmLkJiHgFe", InviteCode: "AbCdEfGhIjKlMnOpQrStUv"}
+	assert.True(t, Match(&inviteLink, foreign))
+	assert.True(t, Match(&anyURL, foreign))
+}
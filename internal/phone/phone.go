@@ -0,0 +1,162 @@
+// Package phone resolves phone numbers to the country they belong to using
+// an embedded table of calling codes.
+package phone
+
+import (
+	_ "embed"
+	"fmt"
+	"meowabot/internal/util"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//go:embed countries.csv
+var countriesCSV string
+
+type Country struct {
+	ISO         string   // ISO 3166-1 alpha-2 code
+	Name        string   // English name
+	CallingCode string   // International calling code, without the plus sign
+	Prefixes    []string // Leading digits of the national numbers of the country, if it shares its calling code
+}
+
+// Number is a phone number split into its calling code and national number.
+type Number struct {
+	Country  *Country
+	National string
+}
+
+// maxPrefixLength is the length of the longest calling code and leading digits combination.
+const maxPrefixLength = 8
+
+type table struct {
+	countries []Country
+	byISO     map[string]*Country
+	byPrefix  map[string]*Country
+}
+
+var loadTable = sync.OnceValues(func() (*table, error) {
+	return parseTable(countriesCSV)
+})
+
+func parseTable(data string) (*table, error) {
+	t := &table{
+		byISO:    make(map[string]*Country),
+		byPrefix: make(map[string]*Country),
+	}
+	for line := range strings.SplitSeq(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid country line %q", line)
+		}
+		t.countries = append(t.countries, Country{
+			ISO:         fields[0],
+			CallingCode: fields[1],
+			Prefixes:    strings.Fields(fields[2]),
+			Name:        fields[3],
+		})
+	}
+	for i := range t.countries {
+		c := &t.countries[i]
+		if _, ok := t.byISO[c.ISO]; ok {
+			return nil, fmt.Errorf("duplicate country %s", c.ISO)
+		}
+		t.byISO[c.ISO] = c
+		keys := []string{c.CallingCode}
+		if len(c.Prefixes) > 0 {
+			keys = keys[:0]
+			for _, p := range c.Prefixes {
+				keys = append(keys, c.CallingCode+p)
+			}
+		}
+		for _, key := range keys {
+			if other, ok := t.byPrefix[key]; ok {
+				return nil, fmt.Errorf("prefix %s of %s is already used by %s", key, c.ISO, other.ISO)
+			}
+			if len(key) > maxPrefixLength {
+				return nil, fmt.Errorf("prefix %s of %s is too long", key, c.ISO)
+			}
+			t.byPrefix[key] = c
+		}
+	}
+	return t, nil
+}
+
+func mustLoadTable() *table {
+	t, err := loadTable()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Parse resolves a phone number in international format, ignoring any
+// character that isn't a digit. The longest matching prefix wins, so
+// +1 242 resolves to the Bahamas rather than the United States.
+func Parse(number string) (Number, bool) {
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, number)
+
+	t := mustLoadTable()
+	for l := min(len(digits), maxPrefixLength); l > 0; l-- {
+		if c, ok := t.byPrefix[digits[:l]]; ok {
+			return Number{Country: c, National: digits[len(c.CallingCode):]}, true
+		}
+	}
+	return Number{}, false
+}
+
+// CountryByISO returns the country with the given ISO 3166-1 alpha-2 code.
+func CountryByISO(iso string) (*Country, bool) {
+	c, ok := mustLoadTable().byISO[strings.ToUpper(iso)]
+	return c, ok
+}
+
+// FindCountry looks a country up by its ISO code or its name, ignoring case
+// and accents.
+func FindCountry(query string) (*Country, bool) {
+	query = strings.TrimSpace(query)
+	if c, ok := CountryByISO(query); ok {
+		return c, true
+	}
+	normalized := normalizeName(query)
+	if normalized == "" {
+		return nil, false
+	}
+	t := mustLoadTable()
+	for i := range t.countries {
+		if normalizeName(t.countries[i].Name) == normalized {
+			return &t.countries[i], true
+		}
+	}
+	return nil, false
+}
+
+// IsCallingCode reports whether code is the calling code of any country.
+func IsCallingCode(code string) bool {
+	t := mustLoadTable()
+	for i := range t.countries {
+		if t.countries[i].CallingCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, util.NormalizeString(name))
+}
@@ -0,0 +1,70 @@
+package phone
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTable(t *testing.T) {
+	// The embedded table must parse without duplicated codes or prefixes
+	_, err := loadTable()
+	require.NoError(t, err)
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		number   string
+		iso      string
+		national string
+	}{
+		{"5511987654321", "BR", "11987654321"},
+		{"+55 (11) 98765-4321", "BR", "11987654321"},
+		{"12125551234", "US", "2125551234"},
+		{"14165551234", "CA", "4165551234"},
+		{"12425551234", "BS", "2425551234"},
+		{"18095551234", "DO", "8095551234"},
+		{"79161234567", "RU", "9161234567"},
+		{"77011234567", "KZ", "7011234567"},
+		{"447400123456", "GB", "7400123456"},
+		{"441481123456", "GG", "1481123456"},
+		{"351912345678", "PT", "912345678"},
+		{"358181234567", "AX", "181234567"},
+		{"358401234567", "FI", "401234567"},
+	} {
+		n, ok := Parse(tt.number)
+		require.True(t, ok, tt.number)
+		assert.Equal(t, tt.iso, n.Country.ISO, tt.number)
+		assert.Equal(t, tt.national, n.National, tt.number)
+	}
+
+	_, ok := Parse("")
+	assert.False(t, ok)
+	_, ok = Parse("0800123456")
+	assert.False(t, ok)
+}
+
+func TestFindCountry(t *testing.T) {
+	c, ok := FindCountry("br")
+	require.True(t, ok)
+	assert.Equal(t, "Brazil", c.Name)
+
+	c, ok = FindCountry("united states")
+	require.True(t, ok)
+	assert.Equal(t, "US", c.ISO)
+
+	c, ok = FindCountry("Cote d'Ivoire")
+	require.True(t, ok)
+	assert.Equal(t, "CI", c.ISO)
+
+	_, ok = FindCountry("Atlantis")
+	assert.False(t, ok)
+}
+
+func TestIsCallingCode(t *testing.T) {
+	assert.True(t, IsCallingCode("55"))
+	assert.True(t, IsCallingCode("1"))
+	assert.False(t, IsCallingCode("1242"))
+	assert.False(t, IsCallingCode("999"))
+}
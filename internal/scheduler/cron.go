@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression with the five standard fields: minute,
+// hour, day of month, month and day of week. Like in most cron
+// implementations, when both day fields are restricted a time matches if
+// either of them does.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// maxCronSearch bounds the search for the next activation, so expressions
+// that never match like "0 0 30 2 *" don't loop forever.
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseCron parses a cron expression like "*/15 8-18 * * mon-fri" or one of
+// the @daily style macros.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var c Cron
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	// Both 0 and 7 are Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*") || fields[2] == "?"
+	c.dowStar = strings.HasPrefix(fields[4], "*") || fields[4] == "?"
+	return &c, nil
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps
+// into a bit set.
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in cron field %q", field)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			lo, hi = min, max
+		default:
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(from, names); err != nil {
+				return 0, fmt.Errorf("invalid value in cron field %q", field)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = parseCronValue(to, names); err != nil {
+					return 0, fmt.Errorf("invalid value in cron field %q", field)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("value out of range in cron field %q", field)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[s]; ok {
+		return v, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Next returns the first activation strictly after t, in the location of t.
+// It returns the zero time if the expression never matches.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		if c.month&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<t.Minute()) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
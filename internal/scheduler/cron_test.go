@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	base := time.Date(2025, 1, 15, 10, 30, 20, 0, time.UTC)
+	for _, tt := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"0 8-18/2 * * mon-fri", time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)},
+		{"0 0 * * sat,sun", time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 * *", time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Either day field matches when both are restricted
+		{"0 0 20 * fri", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+	} {
+		cron, err := ParseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, cron.Next(base), tt.expr)
+	}
+
+	never, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, never.Next(base).IsZero())
+}
+
+func TestCronNextTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	require.NoError(t, err)
+	cron, err := ParseCron("0 8 * * *")
+	require.NoError(t, err)
+
+	next := cron.Next(time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC).In(loc))
+	assert.Equal(t, time.Date(2025, 1, 16, 11, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"fmt"
+	"meowabot/internal/util"
+	"strconv"
+	"strings"
+)
+
+// ParseRecurrence parses a recurrence at the start of s and returns it as a
+// cron expression, with the rest of s. It understands "hourly", "daily 9:00",
+// "weekly monday 18h", "monthly 1 9:00", their Portuguese names, and raw cron
+// expressions between quotes or backticks.
+func ParseRecurrence(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 0 && (s[0] == '"' || s[0] == '`') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated cron expression")
+		}
+		expr := s[1 : end+1]
+		if _, err := ParseCron(expr); err != nil {
+			return "", "", err
+		}
+		return expr, strings.TrimSpace(s[end+2:]), nil
+	}
+
+	kind, rest, _ := strings.Cut(s, " ")
+	next := func() string {
+		var word string
+		word, rest, _ = strings.Cut(strings.TrimSpace(rest), " ")
+		return word
+	}
+	clock := func() (int, error) {
+		return util.ParseClock(next())
+	}
+
+	var expr string
+	switch strings.ToLower(kind) {
+	case "hourly", "horaria":
+		expr = "0 * * * *"
+	case "daily", "diario", "diariamente":
+		c, err := clock()
+		if err != nil {
+			return "", "", err
+		}
+		expr = fmt.Sprintf("%d %d * * *", c%60, c/60)
+	case "weekly", "semanal", "semanalmente":
+		word := next()
+		weekday, ok := util.ParseWeekday(word)
+		if !ok {
+			return "", "", fmt.Errorf("invalid weekday %q", word)
+		}
+		c, err := clock()
+		if err != nil {
+			return "", "", err
+		}
+		expr = fmt.Sprintf("%d %d * * %d", c%60, c/60, weekday)
+	case "monthly", "mensal", "mensalmente":
+		word := next()
+		day, err := strconv.Atoi(word)
+		if err != nil || day < 1 || day > 31 {
+			return "", "", fmt.Errorf("invalid day of month %q", word)
+		}
+		c, err := clock()
+		if err != nil {
+			return "", "", err
+		}
+		expr = fmt.Sprintf("%d %d %d * *", c%60, c/60, day)
+	default:
+		return "", "", fmt.Errorf("unknown recurrence %q", kind)
+	}
+	return expr, strings.TrimSpace(rest), nil
+}
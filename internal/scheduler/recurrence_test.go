@@ -0,0 +1,37 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		cron  string
+		rest  string
+	}{
+		{"hourly drink water", "0 * * * *", "drink water"},
+		{"daily 9:00 bom dia", "0 9 * * *", "bom dia"},
+		{"diario 21h30", "30 21 * * *", ""},
+		{"weekly monday 18h read the rules\nplease", "0 18 * * 1", "read the rules\nplease"},
+		{"semanal domingo 10:00 culto", "0 10 * * 0", "culto"},
+		{"monthly 1 9h pay rent", "0 9 1 * *", "pay rent"},
+		{"\"*/30 8-18 * * 1-5\" stretch", "*/30 8-18 * * 1-5", "stretch"},
+		{"`@daily` hi", "@daily", "hi"},
+	} {
+		cron, rest, err := ParseRecurrence(tt.input)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.cron, cron, tt.input)
+		assert.Equal(t, tt.rest, rest, tt.input)
+		_, err = ParseCron(cron)
+		assert.NoError(t, err, tt.input)
+	}
+
+	for _, input := range []string{"", "sometimes", "daily", "daily noon", "weekly someday 9h", "monthly 32 9h", "\"* * *\" x", "\"unterminated"} {
+		_, _, err := ParseRecurrence(input)
+		assert.Error(t, err, input)
+	}
+}
@@ -0,0 +1,230 @@
+// Package scheduler runs timed jobs persisted in the database. Jobs are run
+// by handlers registered by name, either once or following a cron expression,
+// and survive restarts of the bot.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"meowabot/internal/database"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Misfire policies decide what happens to the runs of a job that were missed
+// while the bot was offline.
+const (
+	MisfireOnce = ""     // Run once late, then follow the schedule from now
+	MisfireSkip = "skip" // Drop the missed runs
+	MisfireAll  = "all"  // Run every missed activation of recurring jobs
+)
+
+var MisfirePolicies = []string{MisfireOnce, MisfireSkip, MisfireAll}
+
+const (
+	// Lease is how long a job stays locked while it runs. It is also the
+	// timeout of the handlers.
+	Lease = 5 * time.Minute
+	// MaxAttempts is how many times a failing job runs before it is given up.
+	MaxAttempts = 5
+
+	pollInterval = 5 * time.Second
+	misfireGrace = time.Minute
+	claimLimit   = 20
+	retryDelay   = 30 * time.Second
+)
+
+var ErrNoHandler = errors.New("no handler registered for job")
+
+// Handler runs a job. Jobs that return an error are retried with backoff.
+type Handler func(ctx context.Context, job *database.Job) error
+
+type Scheduler struct {
+	db       *database.DBInstance
+	log      *zerolog.Logger
+	handlers map[string]Handler
+	mu       sync.RWMutex
+	now      func() time.Time
+}
+
+func New(db *database.DBInstance, log *zerolog.Logger) *Scheduler {
+	return &Scheduler{
+		db:       db,
+		log:      log,
+		handlers: make(map[string]Handler),
+		now:      time.Now,
+	}
+}
+
+// Handle registers the handler of the jobs with the given name.
+func (s *Scheduler) Handle(name string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.handlers[name]; ok {
+		panic(fmt.Sprintf("Duplicate job handler %s", name))
+	}
+	s.handlers[name] = handler
+}
+
+// Schedule persists a job. Recurring jobs without a RunAt run at the next
+// activation of their cron expression. Must be called with the database lock
+// held.
+func (s *Scheduler) Schedule(job *database.Job) error {
+	if !slices.Contains(MisfirePolicies, job.Misfire) {
+		return fmt.Errorf("unknown misfire policy %q", job.Misfire)
+	}
+	if job.Cron != "" {
+		next, err := NextRun(job, s.now())
+		if err != nil {
+			return err
+		}
+		if job.RunAt.IsZero() {
+			job.RunAt = next
+		}
+	}
+	if job.RunAt.IsZero() {
+		return errors.New("job has no run time")
+	}
+	return s.db.CreateJob(job)
+}
+
+// Cancel deletes a job. Must be called with the database lock held.
+func (s *Scheduler) Cancel(id uint64) error {
+	return s.db.DeleteJob(id)
+}
+
+// CancelKey deletes the job with the given key, if there is one. Must be
+// called with the database lock held.
+func (s *Scheduler) CancelKey(key string) error {
+	return s.db.DeleteJobByKey(key)
+}
+
+// NextRun returns the first activation of a recurring job after the given
+// time, in the job's timezone.
+func NextRun(job *database.Job, after time.Time) (time.Time, error) {
+	cron, err := ParseCron(job.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(job.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := cron.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never matches", job.Cron)
+	}
+	return next, nil
+}
+
+// Run runs the due jobs until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs the jobs that are due and returns how many were claimed.
+func (s *Scheduler) RunDue(ctx context.Context) int {
+	now := s.now()
+	s.db.MU.Lock()
+	jobs, err := s.db.ClaimDueJobs(now, Lease, claimLimit)
+	s.db.MU.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg("Error claiming due jobs")
+		return 0
+	}
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		s.runJob(ctx, &job, now)
+	}
+	return len(jobs)
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job *database.Job, now time.Time) {
+	if job.Misfire == MisfireSkip && now.Sub(job.RunAt) > misfireGrace {
+		s.log.Info().Uint64("JobID", job.ID).Str("Job", job.Name).Time("RunAt", job.RunAt).Msg("Skipping missed job")
+		s.finish(job, now, nil)
+		return
+	}
+
+	s.mu.RLock()
+	handler, ok := s.handlers[job.Name]
+	s.mu.RUnlock()
+	if !ok {
+		s.finish(job, now, ErrNoHandler)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, Lease)
+	defer cancel()
+	s.finish(job, now, call(jobCtx, handler, job))
+}
+
+// call runs a handler, turning panics into errors so a broken job doesn't
+// take the scheduler down.
+func call(ctx context.Context, handler Handler, job *database.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// finish releases a job after it ran: failed jobs are retried with backoff,
+// one-shot jobs are deleted and recurring jobs move to their next run.
+func (s *Scheduler) finish(job *database.Job, now time.Time, err error) {
+	s.db.MU.Lock()
+	defer s.db.MU.Unlock()
+
+	var lastError string
+	if err != nil {
+		lastError = err.Error()
+		attempts := job.Attempts + 1
+		if attempts < MaxAttempts {
+			s.log.Warn().Err(err).Uint64("JobID", job.ID).Str("Job", job.Name).Int("Attempt", attempts).Msg("Job failed, retrying")
+			if err := s.db.RescheduleJob(job.ID, now.Add(retryDelay<<(attempts-1)), attempts, lastError); err != nil {
+				s.log.Error().Err(err).Uint64("JobID", job.ID).Msg("Error rescheduling job")
+			}
+			return
+		}
+		s.log.Error().Err(err).Uint64("JobID", job.ID).Str("Job", job.Name).Msg("Job failed too many times, giving up")
+	}
+
+	if job.Cron == "" {
+		if err := s.db.DeleteJob(job.ID); err != nil {
+			s.log.Error().Err(err).Uint64("JobID", job.ID).Msg("Error deleting job")
+		}
+		return
+	}
+
+	after := now
+	if job.Misfire == MisfireAll && err == nil {
+		after = job.RunAt
+	}
+	next, err := NextRun(job, after)
+	if err != nil {
+		s.log.Error().Err(err).Uint64("JobID", job.ID).Str("Job", job.Name).Msg("Error computing next run, deleting job")
+		if err := s.db.DeleteJob(job.ID); err != nil {
+			s.log.Error().Err(err).Uint64("JobID", job.ID).Msg("Error deleting job")
+		}
+		return
+	}
+	if err := s.db.RescheduleJob(job.ID, next, 0, lastError); err != nil {
+		s.log.Error().Err(err).Uint64("JobID", job.ID).Msg("Error rescheduling job")
+	}
+}
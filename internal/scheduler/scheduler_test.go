@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"meowabot/internal/database"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func setupTestScheduler(t *testing.T) (*Scheduler, *time.Time) {
+	db, err := database.NewDB(sqlite.Open(":memory:"), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+	})
+	require.NoError(t, err)
+	log := zerolog.Nop()
+	s := New(db, &log)
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestSchedulerOneShot(t *testing.T) {
+	s, now := setupTestScheduler(t)
+	var ran []string
+	s.Handle("remind", func(ctx context.Context, job *database.Job) error {
+		ran = append(ran, job.Payload)
+		return nil
+	})
+
+	require.NoError(t, s.Schedule(&database.Job{Name: "remind", Payload: "a", RunAt: now.Add(time.Minute)}))
+	assert.Equal(t, 0, s.RunDue(context.Background()))
+
+	*now = now.Add(time.Minute)
+	assert.Equal(t, 1, s.RunDue(context.Background()))
+	assert.Equal(t, []string{"a"}, ran)
+
+	jobs, err := s.db.GetJobs("")
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestSchedulerKeyReplacesJob(t *testing.T) {
+	s, now := setupTestScheduler(t)
+
+	require.NoError(t, s.Schedule(&database.Job{Name: "unmute", Key: "unmute:1", RunAt: now.Add(time.Minute)}))
+	require.NoError(t, s.Schedule(&database.Job{Name: "unmute", Key: "unmute:1", RunAt: now.Add(time.Hour)}))
+	jobs, err := s.db.GetJobs("unmute")
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.True(t, jobs[0].RunAt.Equal(now.Add(time.Hour)))
+
+	require.NoError(t, s.CancelKey("unmute:1"))
+	jobs, err = s.db.GetJobs("unmute")
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
+
+func TestSchedulerRecurring(t *testing.T) {
+	s, now := setupTestScheduler(t)
+	var runs int
+	s.Handle("tick", func(ctx context.Context, job *database.Job) error {
+		runs++
+		return nil
+	})
+
+	job := &database.Job{Name: "tick", Cron: "*/10 * * * *"}
+	require.NoError(t, s.Schedule(job))
+	assert.True(t, job.RunAt.Equal(now.Add(10*time.Minute)))
+
+	*now = now.Add(10 * time.Minute)
+	s.RunDue(context.Background())
+	assert.Equal(t, 1, runs)
+
+	stored, err := s.db.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.RunAt.Equal(now.Add(10*time.Minute)))
+	assert.Nil(t, stored.LockedUntil)
+}
+
+func TestSchedulerRetries(t *testing.T) {
+	s, now := setupTestScheduler(t)
+	fail := true
+	s.Handle("flaky", func(ctx context.Context, job *database.Job) error {
+		if fail {
+			return errors.New("boom")
+		}
+		return nil
+	})
+
+	job := &database.Job{Name: "flaky", RunAt: *now}
+	require.NoError(t, s.Schedule(job))
+	s.RunDue(context.Background())
+
+	stored, err := s.db.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.Equal(t, "boom", stored.LastError)
+	assert.True(t, stored.RunAt.Equal(now.Add(retryDelay)))
+
+	// Not due until the backoff passes
+	assert.Equal(t, 0, s.RunDue(context.Background()))
+
+	fail = false
+	*now = now.Add(retryDelay)
+	assert.Equal(t, 1, s.RunDue(context.Background()))
+	_, err = s.db.GetJob(job.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSchedulerGivesUp(t *testing.T) {
+	s, now := setupTestScheduler(t)
+	s.Handle("broken", func(ctx context.Context, job *database.Job) error {
+		panic("oops")
+	})
+
+	job := &database.Job{Name: "broken", RunAt: *now}
+	require.NoError(t, s.Schedule(job))
+	for range MaxAttempts {
+		require.Equal(t, 1, s.RunDue(context.Background()))
+		*now = now.Add(time.Hour)
+	}
+	_, err := s.db.GetJob(job.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSchedulerMissingHandler(t *testing.T) {
+	s, now := setupTestScheduler(t)
+
+	job := &database.Job{Name: "unknown", RunAt: *now}
+	require.NoError(t, s.Schedule(job))
+	s.RunDue(context.Background())
+
+	stored, err := s.db.GetJob(job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ErrNoHandler.Error(), stored.LastError)
+}
+
+func TestSchedulerMisfire(t *testing.T) {
+	s, now := setupTestScheduler(t)
+	runs := map[string]int{}
+	s.Handle("tick", func(ctx context.Context, job *database.Job) error {
+		runs[job.Key]++
+		return nil
+	})
+
+	for _, policy := range MisfirePolicies {
+		require.NoError(t, s.Schedule(&database.Job{Name: "tick", Key: policy, Cron: "@hourly", Misfire: policy}))
+	}
+	require.NoError(t, s.Schedule(&database.Job{Name: "tick", Key: "late", RunAt: now.Add(time.Hour), Misfire: MisfireSkip}))
+
+	// The bot was offline for three hours and a half
+	*now = now.Add(3*time.Hour + 30*time.Minute)
+	for s.RunDue(context.Background()) > 0 {
+	}
+
+	assert.Equal(t, 1, runs[MisfireOnce])
+	assert.Equal(t, 0, runs[MisfireSkip])
+	assert.Equal(t, 3, runs[MisfireAll])
+	assert.Equal(t, 0, runs["late"])
+
+	jobs, err := s.db.GetJobs("tick")
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	for _, job := range jobs {
+		assert.True(t, job.RunAt.Equal(now.Truncate(time.Hour).Add(time.Hour)), job.Key)
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+	s, _ := setupTestScheduler(t)
+	assert.Error(t, s.Schedule(&database.Job{Name: "x"}))
+	assert.Error(t, s.Schedule(&database.Job{Name: "x", Cron: "not a cron"}))
+	assert.Error(t, s.Schedule(&database.Job{Name: "x", Cron: "@daily", Timezone: "Nowhere/Land"}))
+	assert.Error(t, s.Schedule(&database.Job{Name: "x", Cron: "@daily", Misfire: "sometimes"}))
+}
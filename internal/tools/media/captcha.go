@@ -0,0 +1,119 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"math/rand/v2"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	captchaHeight   = 120
+	captchaCharSize = 64
+	captchaGlyphBox = 96
+)
+
+// GenerateCaptchaImage renders the text as a PNG with every character rotated
+// and displaced, covered by noise lines and dots and warped by a sine wave.
+func GenerateCaptchaImage(text string) ([]byte, error) {
+	face, err := boldFace(captchaCharSize)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	runes := []rune(text)
+	width := len(runes)*captchaCharSize + captchaCharSize
+	canvas := image.NewRGBA(image.Rect(0, 0, width, captchaHeight))
+	background := color.RGBA{uint8(220 + rand.IntN(36)), uint8(220 + rand.IntN(36)), uint8(220 + rand.IntN(36)), 255}
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	for range 6 {
+		drawLine(canvas, randomDarkColor(), rand.IntN(width), rand.IntN(captchaHeight), rand.IntN(width), rand.IntN(captchaHeight), 2)
+	}
+
+	for i, r := range runes {
+		glyph := image.NewRGBA(image.Rect(0, 0, captchaGlyphBox, captchaGlyphBox))
+		d := &font.Drawer{
+			Dst:  glyph,
+			Src:  image.NewUniform(randomDarkColor()),
+			Face: face,
+		}
+		advance := d.MeasureString(string(r))
+		d.Dot = fixed.Point26_6{
+			X: fixed.I(captchaGlyphBox/2) - advance/2,
+			Y: fixed.I(captchaGlyphBox/2 + captchaCharSize/3),
+		}
+		d.DrawString(string(r))
+
+		angle := (rand.Float64() - 0.5) * math.Pi / 3
+		cx := float64(captchaCharSize/2 + i*captchaCharSize + captchaCharSize/2)
+		cy := float64(captchaHeight/2) + float64(rand.IntN(21)-10)
+		sin, cos := math.Sincos(angle)
+		half := float64(captchaGlyphBox) / 2
+		// Rotate around the glyph box center and move it to its slot
+		m := f64.Aff3{
+			cos, -sin, cx - half*cos + half*sin,
+			sin, cos, cy - half*sin - half*cos,
+		}
+		draw.BiLinear.Transform(canvas, m, glyph, glyph.Bounds(), draw.Over, nil)
+	}
+
+	for range width * captchaHeight / 25 {
+		canvas.Set(rand.IntN(width), rand.IntN(captchaHeight), randomDarkColor())
+	}
+	for range 3 {
+		drawLine(canvas, randomDarkColor(), 0, rand.IntN(captchaHeight), width, rand.IntN(captchaHeight), 1)
+	}
+
+	warped := image.NewRGBA(canvas.Bounds())
+	amplitude := 4 + rand.Float64()*4
+	period := 40 + rand.Float64()*40
+	phase := rand.Float64() * 2 * math.Pi
+	for y := range captchaHeight {
+		for x := range width {
+			sx := x + int(amplitude*math.Sin(float64(y)/period*2*math.Pi+phase))
+			sy := y + int(amplitude*math.Cos(float64(x)/period*2*math.Pi+phase))
+			if image.Pt(sx, sy).In(canvas.Bounds()) {
+				warped.Set(x, y, canvas.At(sx, sy))
+			} else {
+				warped.Set(x, y, background)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, warped); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func randomDarkColor() color.RGBA {
+	return color.RGBA{uint8(rand.IntN(120)), uint8(rand.IntN(120)), uint8(rand.IntN(120)), 255}
+}
+
+func drawLine(img *image.RGBA, c color.Color, x0, y0, x1, y1, thickness int) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		for t := range thickness {
+			img.Set(x, y+t, c)
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
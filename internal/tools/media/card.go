@@ -0,0 +1,256 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	cardWidth      = 1024
+	cardHeight     = 500
+	cardAvatarSize = 220
+	cardAvatarY    = 40
+	cardRingWidth  = 6
+	cardMargin     = 40
+)
+
+// GreetingCard describes a welcome or goodbye card.
+type GreetingCard struct {
+	Avatar     []byte     // Profile picture of the member, a placeholder is drawn when nil
+	Background []byte     // Background image, a gradient of Color is drawn when nil
+	Color      color.RGBA // Base color of the background gradient
+	Title      string
+	Name       string
+	Subtitle   string
+}
+
+// DefaultCardColor is the background color used when the group didn't pick one.
+var DefaultCardColor = color.RGBA{0x1e, 0x3a, 0x5f, 0xff}
+
+// GenerateGreetingCard renders the card as a JPEG: the circular profile
+// picture of the member over the background, followed by the title, the
+// name and the subtitle.
+func GenerateGreetingCard(card *GreetingCard) ([]byte, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+
+	var background image.Image
+	if card.Background != nil {
+		img, _, err := image.Decode(bytes.NewReader(card.Background))
+		if err != nil {
+			return nil, fmt.Errorf("decoding background: %w", err)
+		}
+		background = img
+	}
+	if background != nil {
+		drawCover(canvas, background)
+		// Darken the image so the text stays readable on any background
+		draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.RGBA{0, 0, 0, 130}), image.Point{}, draw.Over)
+	} else {
+		drawGradient(canvas, card.Color)
+	}
+
+	var avatar image.Image
+	if card.Avatar != nil {
+		img, _, err := image.Decode(bytes.NewReader(card.Avatar))
+		if err != nil {
+			return nil, fmt.Errorf("decoding avatar: %w", err)
+		}
+		avatar = img
+	} else {
+		avatar = placeholderAvatar(card.Name)
+	}
+	center := image.Pt(cardWidth/2, cardAvatarY+cardAvatarSize/2)
+	drawCircle(canvas, center, cardAvatarSize/2+cardRingWidth, color.White)
+	drawCircularImage(canvas, avatar, center, cardAvatarSize/2)
+
+	textTop := cardAvatarY + cardAvatarSize + cardRingWidth
+	lines := []struct {
+		text     string
+		size     float64
+		bold     bool
+		baseline int
+	}{
+		{card.Title, 52, true, textTop + 70},
+		{card.Name, 40, false, textTop + 125},
+		{card.Subtitle, 30, false, textTop + 175},
+	}
+	for _, line := range lines {
+		if line.text == "" {
+			continue
+		}
+		if err := drawCenteredText(canvas, line.text, line.size, line.bold, line.baseline); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, canvas, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawCover scales the image to fill the canvas, cropping what doesn't fit.
+func drawCover(dst *image.RGBA, src image.Image) {
+	b := src.Bounds()
+	scale := math.Max(float64(dst.Bounds().Dx())/float64(b.Dx()), float64(dst.Bounds().Dy())/float64(b.Dy()))
+	w := int(float64(dst.Bounds().Dx()) / scale)
+	h := int(float64(dst.Bounds().Dy()) / scale)
+	x := b.Min.X + (b.Dx()-w)/2
+	y := b.Min.Y + (b.Dy()-h)/2
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, image.Rect(x, y, x+w, y+h), draw.Src, nil)
+}
+
+// drawGradient fills the canvas with a diagonal gradient from c to a darker shade.
+func drawGradient(dst *image.RGBA, c color.RGBA) {
+	b := dst.Bounds()
+	maxDistance := float64(b.Dx() + b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			t := 1 - 0.6*float64(x+y)/maxDistance
+			dst.SetRGBA(x, y, color.RGBA{uint8(float64(c.R) * t), uint8(float64(c.G) * t), uint8(float64(c.B) * t), 255})
+		}
+	}
+}
+
+// circleMask is an antialiased circle used as a drawing mask.
+type circleMask struct {
+	center image.Point
+	radius int
+}
+
+func (m *circleMask) ColorModel() color.Model { return color.AlphaModel }
+
+func (m *circleMask) Bounds() image.Rectangle {
+	return image.Rect(m.center.X-m.radius, m.center.Y-m.radius, m.center.X+m.radius, m.center.Y+m.radius)
+}
+
+func (m *circleMask) At(x, y int) color.Color {
+	dx := float64(x-m.center.X) + 0.5
+	dy := float64(y-m.center.Y) + 0.5
+	coverage := float64(m.radius) - math.Hypot(dx, dy) + 0.5
+	return color.Alpha{uint8(255 * math.Max(0, math.Min(1, coverage)))}
+}
+
+func drawCircle(dst *image.RGBA, center image.Point, radius int, c color.Color) {
+	mask := &circleMask{center: center, radius: radius}
+	draw.DrawMask(dst, mask.Bounds(), image.NewUniform(c), image.Point{}, mask, mask.Bounds().Min, draw.Over)
+}
+
+// drawCircularImage crops the center square of the image to a circle of the
+// given radius.
+func drawCircularImage(dst *image.RGBA, src image.Image, center image.Point, radius int) {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2, 0, 0)
+	crop.Max = crop.Min.Add(image.Pt(side, side))
+
+	scaled := image.NewRGBA(image.Rect(0, 0, 2*radius, 2*radius))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, crop, draw.Src, nil)
+
+	mask := &circleMask{center: center, radius: radius}
+	draw.DrawMask(dst, mask.Bounds(), scaled, image.Point{}, mask, mask.Bounds().Min, draw.Over)
+}
+
+// placeholderAvatar draws the initial of the name over a color derived from it.
+func placeholderAvatar(name string) image.Image {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	sum := h.Sum32()
+	bg := color.RGBA{uint8(60 + sum%120), uint8(60 + (sum>>8)%120), uint8(60 + (sum>>16)%120), 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, cardAvatarSize, cardAvatarSize))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	initial := "?"
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			initial = strings.ToUpper(string(r))
+			break
+		}
+	}
+	face, err := boldFace(cardAvatarSize / 2)
+	if err != nil {
+		return img
+	}
+	defer face.Close()
+	d := &font.Drawer{Dst: img, Src: image.White, Face: face}
+	advance := d.MeasureString(initial)
+	d.Dot = fixed.Point26_6{
+		X: fixed.I(cardAvatarSize/2) - advance/2,
+		Y: fixed.I(cardAvatarSize/2) + face.Metrics().CapHeight/2,
+	}
+	d.DrawString(initial)
+	return img
+}
+
+// drawCenteredText draws a line of text centered on the canvas, shrinking
+// and then truncating it when it doesn't fit.
+func drawCenteredText(dst *image.RGBA, text string, size float64, bold bool, baseline int) error {
+	newFace := regularFace
+	if bold {
+		newFace = boldFace
+	}
+	maxWidth := fixed.I(dst.Bounds().Dx() - 2*cardMargin)
+	minSize := size * 0.7
+
+	var face font.Face
+	for {
+		var err error
+		if face, err = newFace(size); err != nil {
+			return err
+		}
+		if font.MeasureString(face, text) <= maxWidth || size <= minSize {
+			break
+		}
+		face.Close()
+		size -= 2
+	}
+	defer face.Close()
+
+	runes := []rune(text)
+	for font.MeasureString(face, text) > maxWidth && len(runes) > 1 {
+		runes = runes[:len(runes)-1]
+		text = strings.TrimSpace(string(runes)) + "…"
+	}
+
+	x := fixed.I(dst.Bounds().Dx()/2) - font.MeasureString(face, text)/2
+	// A soft shadow keeps light text readable over light backgrounds
+	shadow := &font.Drawer{Dst: dst, Src: image.NewUniform(color.RGBA{0, 0, 0, 140}), Face: face}
+	shadow.Dot = fixed.Point26_6{X: x + fixed.I(2), Y: fixed.I(baseline + 2)}
+	shadow.DrawString(text)
+
+	d := &font.Drawer{Dst: dst, Src: image.White, Face: face}
+	d.Dot = fixed.Point26_6{X: x, Y: fixed.I(baseline)}
+	d.DrawString(text)
+	return nil
+}
+
+// ParseHexColor parses colors in the #rrggbb or #rgb formats.
+func ParseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	var c color.RGBA
+	if len(s) != 6 {
+		return c, fmt.Errorf("invalid color %q", s)
+	}
+	if _, err := fmt.Sscanf(s, "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, fmt.Errorf("invalid color %q", s)
+	}
+	c.A = 255
+	return c, nil
+}
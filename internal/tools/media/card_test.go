@@ -0,0 +1,48 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHexColor(t *testing.T) {
+	c, err := ParseHexColor("#1e3a5f")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{0x1e, 0x3a, 0x5f, 0xff}, c)
+
+	c, err = ParseHexColor("f80")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{0xff, 0x88, 0x00, 0xff}, c)
+
+	for _, invalid := range []string{"", "#12345", "#gggggg", "blue"} {
+		_, err = ParseHexColor(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGenerateGreetingCard(t *testing.T) {
+	avatar := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, avatar))
+
+	for _, card := range []*GreetingCard{
+		{Color: DefaultCardColor, Title: "BEM-VINDO(A)!", Name: "Maria", Subtitle: "Membro nº 42"},
+		{Avatar: buf.Bytes(), Background: buf.Bytes(), Title: "ATÉ LOGO!", Name: "um nome longo demais para caber em uma única linha do cartão de boas-vindas"},
+	} {
+		data, err := GenerateGreetingCard(card)
+		require.NoError(t, err)
+		img, format, err := image.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+		assert.Equal(t, "jpeg", format)
+		assert.Equal(t, image.Rect(0, 0, cardWidth, cardHeight), img.Bounds())
+	}
+
+	_, err := GenerateGreetingCard(&GreetingCard{Avatar: []byte("not an image")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,362 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strings"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// MaxImagePixels is the most pixels an image can have to be edited. It is
+// checked before decoding, so a small file can't expand into a huge image.
+// An edit holds a few copies of the image at 4 bytes per pixel, so this keeps
+// it around 256MB, and phone cameras stay under it.
+const MaxImagePixels = 16_000_000
+
+// MaxImageSide is the largest width or height an edit can produce.
+const MaxImageSide = 4096
+
+var ErrImageTooLarge = errors.New("image is too large")
+var ErrInvalidSize = errors.New("invalid image size")
+
+// DecodeImage decodes a JPEG, PNG, GIF or WebP image with at most
+// MaxImagePixels pixels.
+func DecodeImage(data []byte) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	if int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	return img, nil
+}
+
+// EncodeImage encodes the result of an edit as a JPEG, or as a PNG when
+// transparent is set so the transparency is kept.
+func EncodeImage(img image.Image, transparent bool) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if transparent {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toRGBA copies the image into an RGBA image starting at the origin.
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// scaleImage scales src to the given size.
+func scaleImage(src image.Image, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+	return dst
+}
+
+// ResizeImage scales the image to width x height. When one of them is 0 it is
+// computed to keep the aspect ratio.
+func ResizeImage(img image.Image, width, height int) (*image.RGBA, error) {
+	b := img.Bounds()
+	switch {
+	case width < 0 || height < 0 || width == 0 && height == 0:
+		return nil, ErrInvalidSize
+	case width == 0:
+		width = max(1, int(math.Round(float64(height)*float64(b.Dx())/float64(b.Dy()))))
+	case height == 0:
+		height = max(1, int(math.Round(float64(width)*float64(b.Dy())/float64(b.Dx()))))
+	}
+	if width > MaxImageSide || height > MaxImageSide {
+		return nil, ErrImageTooLarge
+	}
+	return scaleImage(img, width, height), nil
+}
+
+// CropSquare crops the largest square from the center of the image.
+func CropSquare(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x, y := b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), img, image.Pt(x, y), draw.Src)
+	return dst
+}
+
+// CropCircle crops the largest circle from the center of the image, leaving
+// the corners transparent.
+func CropCircle(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	drawCircularImage(dst, img, image.Pt(side/2, side/2), side/2)
+	return dst
+}
+
+// Rotate rotates the image clockwise by a multiple of 90 degrees.
+func Rotate(img image.Image, degrees int) (*image.RGBA, error) {
+	if degrees%90 != 0 {
+		return nil, fmt.Errorf("can only rotate by multiples of 90 degrees, not %d", degrees)
+	}
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	switch (degrees%360 + 360) % 360 {
+	case 90:
+		dst := image.NewRGBA(image.Rect(0, 0, h, w))
+		for y := range h {
+			for x := range w {
+				dst.SetRGBA(h-1-y, x, src.RGBAAt(x, y))
+			}
+		}
+		return dst, nil
+	case 180:
+		dst := image.NewRGBA(src.Bounds())
+		for y := range h {
+			for x := range w {
+				dst.SetRGBA(w-1-x, h-1-y, src.RGBAAt(x, y))
+			}
+		}
+		return dst, nil
+	case 270:
+		dst := image.NewRGBA(image.Rect(0, 0, h, w))
+		for y := range h {
+			for x := range w {
+				dst.SetRGBA(y, w-1-x, src.RGBAAt(x, y))
+			}
+		}
+		return dst, nil
+	}
+	return src, nil
+}
+
+// Flip mirrors the image horizontally, or vertically if vertical is set.
+func Flip(img image.Image, vertical bool) *image.RGBA {
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(src.Bounds())
+	for y := range h {
+		for x := range w {
+			if vertical {
+				dst.SetRGBA(x, h-1-y, src.RGBAAt(x, y))
+			} else {
+				dst.SetRGBA(w-1-x, y, src.RGBAAt(x, y))
+			}
+		}
+	}
+	return dst
+}
+
+// Grayscale removes the colors of the image.
+func Grayscale(img image.Image) *image.RGBA {
+	dst := toRGBA(img)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		p := dst.Pix[i : i+3 : i+3]
+		// Rec. 601 luma, as color.GrayModel
+		y := uint8((19595*uint32(p[0]) + 38470*uint32(p[1]) + 7471*uint32(p[2]) + 1<<15) >> 16)
+		p[0], p[1], p[2] = y, y, y
+	}
+	return dst
+}
+
+// Invert inverts the colors of the image, keeping its transparency.
+func Invert(img image.Image) *image.RGBA {
+	dst := toRGBA(img)
+	for i := 0; i < len(dst.Pix); i += 4 {
+		// Premultiplied, so the inverse of each channel is alpha minus it
+		a := dst.Pix[i+3]
+		dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = a-dst.Pix[i], a-dst.Pix[i+1], a-dst.Pix[i+2]
+	}
+	return dst
+}
+
+// Pixelate replaces each block x block square of the image by its average
+// color.
+func Pixelate(img image.Image, block int) *image.RGBA {
+	dst := toRGBA(img)
+	if block < 2 {
+		return dst
+	}
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	for by := 0; by < h; by += block {
+		for bx := 0; bx < w; bx += block {
+			cell := image.Rect(bx, by, min(bx+block, w), min(by+block, h))
+			var sum [4]int
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					i := dst.PixOffset(x, y)
+					for c := range sum {
+						sum[c] += int(dst.Pix[i+c])
+					}
+				}
+			}
+			n := cell.Dx() * cell.Dy()
+			avg := color.RGBA{uint8(sum[0] / n), uint8(sum[1] / n), uint8(sum[2] / n), uint8(sum[3] / n)}
+			draw.Draw(dst, cell, image.NewUniform(avg), image.Point{}, draw.Src)
+		}
+	}
+	return dst
+}
+
+// Blur blurs the image with three box blurs of the given radius, which look
+// close to a gaussian blur.
+func Blur(img image.Image, radius int) *image.RGBA {
+	dst := toRGBA(img)
+	if radius < 1 {
+		return dst
+	}
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	tmp := make([]uint8, len(dst.Pix))
+	for range 3 {
+		// Horizontally from dst to tmp, then vertically back
+		boxBlur(dst.Pix, tmp, w, h, radius, 4, dst.Stride)
+		boxBlur(tmp, dst.Pix, h, w, radius, dst.Stride, 4)
+	}
+	return dst
+}
+
+// boxBlur averages each pixel of src with the radius pixels on each side of
+// it along lines of length n, writing to dst. step is the distance between
+// pixels of a line, and lineStep between the starts of the lines. Pixels
+// past the edges repeat the edge.
+func boxBlur(src, dst []uint8, n, lines, radius, step, lineStep int) {
+	window := 2*radius + 1
+	for line := range lines {
+		start := line * lineStep
+		at := func(i int) int {
+			return start + min(max(i, 0), n-1)*step
+		}
+		for c := range 4 {
+			var sum int
+			for i := -radius; i <= radius; i++ {
+				sum += int(src[at(i)+c])
+			}
+			for i := range n {
+				dst[start+i*step+c] = uint8((sum + window/2) / window)
+				sum += int(src[at(i+radius+1)+c]) - int(src[at(i-radius)+c])
+			}
+		}
+	}
+}
+
+// MemeCaption writes the top and bottom texts over the image in white
+// uppercase letters with a black outline, wrapping and shrinking them to fit.
+func MemeCaption(img image.Image, top, bottom string) (*image.RGBA, error) {
+	dst := toRGBA(img)
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	margin := max(w/40, 2)
+	size := max(float64(w)/9, 12)
+
+	for _, caption := range []struct {
+		text   string
+		bottom bool
+	}{{top, false}, {bottom, true}} {
+		text := strings.ToUpper(strings.Join(strings.Fields(caption.text), " "))
+		if text == "" {
+			continue
+		}
+		face, lines, err := fitCaption(text, size, w-2*margin, h/3)
+		if err != nil {
+			return nil, err
+		}
+		metrics := face.Metrics()
+		lineHeight := metrics.Height.Ceil()
+		y := margin + metrics.Ascent.Ceil()
+		if caption.bottom {
+			y = h - margin - metrics.Descent.Ceil() - (len(lines)-1)*lineHeight
+		}
+		outline := max(int(math.Round(size/16)), 1)
+		for i, line := range lines {
+			drawOutlinedText(dst, face, line, y+i*lineHeight, outline)
+		}
+		face.Close()
+	}
+	return dst, nil
+}
+
+// fitCaption picks the largest font size up to size at which the text,
+// wrapped to maxWidth, fits in maxHeight, and returns the face and the lines.
+// At the smallest size the text is wrapped even if it doesn't fit.
+func fitCaption(text string, size float64, maxWidth, maxHeight int) (font.Face, []string, error) {
+	for {
+		face, err := boldFace(size)
+		if err != nil {
+			return nil, nil, err
+		}
+		lines := wrapText(face, text, fixed.I(maxWidth))
+		if len(lines)*face.Metrics().Height.Ceil() <= maxHeight || size <= 10 {
+			return face, lines, nil
+		}
+		face.Close()
+		size *= 0.85
+	}
+}
+
+// wrapText breaks the text in lines no wider than maxWidth, breaking words
+// that don't fit in a line by themselves.
+func wrapText(face font.Face, text string, maxWidth fixed.Int26_6) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if font.MeasureString(face, candidate) <= maxWidth {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = ""
+		for _, r := range word {
+			if line != "" && font.MeasureString(face, line+string(r)) > maxWidth {
+				lines = append(lines, line)
+				line = ""
+			}
+			line += string(r)
+		}
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// drawOutlinedText draws a line of white text centered horizontally at the
+// baseline, over copies of it in black shifted by up to outline pixels.
+func drawOutlinedText(dst *image.RGBA, face font.Face, text string, baseline, outline int) {
+	x := fixed.I(dst.Bounds().Dx()/2) - font.MeasureString(face, text)/2
+	d := &font.Drawer{Dst: dst, Src: image.Black, Face: face}
+	for dy := -outline; dy <= outline; dy++ {
+		for dx := -outline; dx <= outline; dx++ {
+			if dx*dx+dy*dy > outline*outline {
+				continue
+			}
+			d.Dot = fixed.Point26_6{X: x + fixed.I(dx), Y: fixed.I(baseline + dy)}
+			d.DrawString(text)
+		}
+	}
+	d.Src = image.White
+	d.Dot = fixed.Point26_6{X: x, Y: fixed.I(baseline)}
+	d.DrawString(text)
+}
@@ -0,0 +1,183 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// testGrid returns a 3x2 image with a different color in each pixel.
+func testGrid() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := range 2 {
+		for x := range 3 {
+			img.SetRGBA(x, y, color.RGBA{uint8(x * 100), uint8(y * 100), 0, 255})
+		}
+	}
+	return img
+}
+
+func TestDecodeImageLimits(t *testing.T) {
+	img, err := DecodeImage(testPNG(t, 20, 10))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 10), img.Bounds())
+
+	// PNG headers claiming more pixels than allowed, rejected before decoding
+	_, err = DecodeImage(pngHeader(t, 10000, 10000))
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+	_, err = DecodeImage(pngHeader(t, 5000, 4000))
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+
+	_, err = DecodeImage([]byte("not an image"))
+	assert.Error(t, err)
+}
+
+// pngHeader returns a 1x1 PNG whose header claims the given size.
+func pngHeader(t *testing.T, width, height uint32) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestResizeImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	resized, err := ResizeImage(img, 100, 0)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), resized.Bounds())
+
+	resized, err = ResizeImage(img, 0, 100)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 200, 100), resized.Bounds())
+
+	_, err = ResizeImage(img, 0, 0)
+	assert.ErrorIs(t, err, ErrInvalidSize)
+	_, err = ResizeImage(img, MaxImageSide+1, 0)
+	assert.ErrorIs(t, err, ErrImageTooLarge)
+}
+
+func TestCrop(t *testing.T) {
+	square := CropSquare(testGrid())
+	assert.Equal(t, image.Rect(0, 0, 2, 2), square.Bounds())
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, square.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{100, 100, 0, 255}, square.RGBAAt(1, 1))
+
+	img := image.NewRGBA(image.Rect(0, 0, 100, 60))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	circle := CropCircle(img)
+	assert.Equal(t, image.Rect(0, 0, 60, 60), circle.Bounds())
+	assert.Zero(t, circle.RGBAAt(0, 0).A)
+	assert.Equal(t, uint8(255), circle.RGBAAt(30, 30).A)
+}
+
+func TestRotateAndFlip(t *testing.T) {
+	grid := testGrid()
+
+	rotated, err := Rotate(grid, 90)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 2, 3), rotated.Bounds())
+	// The bottom left corner goes to the top left
+	assert.Equal(t, grid.RGBAAt(0, 1), rotated.RGBAAt(0, 0))
+
+	rotated, err = Rotate(grid, -90)
+	require.NoError(t, err)
+	assert.Equal(t, grid.RGBAAt(2, 0), rotated.RGBAAt(0, 0))
+
+	rotated, err = Rotate(grid, 180)
+	require.NoError(t, err)
+	assert.Equal(t, grid.RGBAAt(2, 1), rotated.RGBAAt(0, 0))
+
+	_, err = Rotate(grid, 45)
+	assert.Error(t, err)
+
+	flipped := Flip(grid, false)
+	assert.Equal(t, grid.RGBAAt(2, 0), flipped.RGBAAt(0, 0))
+	flipped = Flip(grid, true)
+	assert.Equal(t, grid.RGBAAt(0, 1), flipped.RGBAAt(0, 0))
+}
+
+func TestColorFilters(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{255, 0, 0, 255})
+
+	gray := Grayscale(img).RGBAAt(0, 0)
+	assert.Equal(t, gray.R, gray.G)
+	assert.Equal(t, gray.R, gray.B)
+	assert.Equal(t, uint8(76), gray.R)
+
+	assert.Equal(t, color.RGBA{0, 255, 255, 255}, Invert(img).RGBAAt(0, 0))
+	// The original is left untouched
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, img.RGBAAt(0, 0))
+}
+
+func TestPixelateAndBlur(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	for x := range 4 {
+		img.SetRGBA(x, 0, color.RGBA{uint8(x * 40), 0, 0, 255})
+	}
+	pixelated := Pixelate(img, 2)
+	assert.Equal(t, color.RGBA{20, 0, 0, 255}, pixelated.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{20, 0, 0, 255}, pixelated.RGBAAt(1, 0))
+	assert.Equal(t, color.RGBA{100, 0, 0, 255}, pixelated.RGBAAt(3, 0))
+
+	// A single white pixel spreads to its neighbours
+	dot := image.NewRGBA(image.Rect(0, 0, 9, 9))
+	dot.SetRGBA(4, 4, color.RGBA{255, 255, 255, 255})
+	blurred := Blur(dot, 1)
+	assert.Less(t, blurred.RGBAAt(4, 4).A, uint8(255))
+	assert.NotZero(t, blurred.RGBAAt(5, 4).A)
+	assert.Zero(t, blurred.RGBAAt(0, 0).A)
+
+	uniform := image.NewRGBA(image.Rect(0, 0, 5, 5))
+	for i := range uniform.Pix {
+		uniform.Pix[i] = 200
+	}
+	assert.Equal(t, uniform.Pix, Blur(uniform, 3).Pix)
+}
+
+func TestMemeCaption(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 300))
+	meme, err := MemeCaption(img, "quando o código compila de primeira", "")
+	require.NoError(t, err)
+
+	var top, bottom int
+	for y := range 300 {
+		for x := range 300 {
+			if meme.RGBAAt(x, y).R == 255 {
+				if y < 150 {
+					top++
+				} else {
+					bottom++
+				}
+			}
+		}
+	}
+	assert.NotZero(t, top)
+	assert.Zero(t, bottom)
+
+	face, err := boldFace(20)
+	require.NoError(t, err)
+	defer face.Close()
+	text := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAA BB"
+	lines := wrapText(face, text, fixed.I(100))
+	assert.Greater(t, len(lines), 2)
+	for _, line := range lines {
+		assert.LessOrEqual(t, font.MeasureString(face, line), fixed.I(100), line)
+	}
+	assert.Equal(t, strings.ReplaceAll(text, " ", ""), strings.ReplaceAll(strings.Join(lines, ""), " ", ""))
+}
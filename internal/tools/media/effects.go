@@ -0,0 +1,128 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// MaxEffectInput is how much of the audio the effects process, the rest is
+// cut off.
+const MaxEffectInput = 5 * time.Minute
+
+var ErrEffectAmount = errors.New("effect amount out of range")
+
+// AudioEffect is one of the filters applied by ApplyAudioEffect. Effects
+// that take an amount, like the speed or the semitones of a pitch shift,
+// accept it between Min and Max.
+type AudioEffect struct {
+	Name     string
+	Default  float64
+	Min, Max float64
+	// Stereo effects are encoded with two channels, voice notes are mono
+	Stereo bool
+
+	filter func(amount float64) string
+}
+
+// TakesAmount reports whether the effect can be adjusted with an amount.
+func (e *AudioEffect) TakesAmount() bool {
+	return e.Min != e.Max
+}
+
+// Filter returns the ffmpeg filter graph of the effect, which expects audio
+// at 48 kHz.
+func (e *AudioEffect) Filter(amount float64) (string, error) {
+	if !e.TakesAmount() {
+		return e.filter(e.Default), nil
+	}
+	if math.IsNaN(amount) || amount < e.Min || amount > e.Max {
+		return "", fmt.Errorf("%w: %s takes %s to %s", ErrEffectAmount, e.Name, FormatAmount(e.Min), FormatAmount(e.Max))
+	}
+	return e.filter(amount), nil
+}
+
+// FormatAmount formats an effect amount without trailing zeros.
+func FormatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// pitchFilter shifts the pitch by a ratio keeping the tempo.
+func pitchFilter(ratio float64) string {
+	return fmt.Sprintf("asetrate=%s,aresample=48000,atempo=%s", FormatAmount(48000*ratio), FormatAmount(1/ratio))
+}
+
+var audioEffects = map[string]*AudioEffect{
+	"bass": {
+		Name: "bass", Default: 10, Min: 1, Max: 30,
+		filter: func(gain float64) string {
+			return fmt.Sprintf("bass=g=%s:f=110:w=0.6", FormatAmount(gain))
+		},
+	},
+	"speed": {
+		Name: "speed", Default: 1.5, Min: 0.5, Max: 2,
+		filter: func(factor float64) string {
+			return "atempo=" + FormatAmount(factor)
+		},
+	},
+	"slow": {
+		Name: "slow", Default: 0.75, Min: 0.5, Max: 2,
+		filter: func(factor float64) string {
+			return "atempo=" + FormatAmount(factor)
+		},
+	},
+	"pitch": {
+		Name: "pitch", Default: 4, Min: -12, Max: 12,
+		filter: func(semitones float64) string {
+			return pitchFilter(math.Pow(2, semitones/12))
+		},
+	},
+	"reverse": {
+		Name:   "reverse",
+		filter: func(float64) string { return "areverse" },
+	},
+	"nightcore": {
+		Name: "nightcore",
+		// Faster and higher, like a record played at the wrong speed
+		filter: func(float64) string { return "asetrate=60000,aresample=48000" },
+	},
+	"echo": {
+		Name:   "echo",
+		filter: func(float64) string { return "aecho=0.8:0.88:120|240:0.4|0.25" },
+	},
+	"robot": {
+		Name: "robot",
+		// Zeroes the phase of every frequency
+		filter: func(float64) string {
+			return "afftfilt=real='hypot(re,im)*sin(0)':imag='hypot(re,im)*cos(0)':win_size=512:overlap=0.75"
+		},
+	},
+	"8d": {
+		Name: "8d", Stereo: true,
+		// Pans the sound around the head
+		filter: func(float64) string { return "aformat=channel_layouts=stereo,apulsator=hz=0.125" },
+	},
+}
+
+// GetAudioEffect returns the effect with the given name.
+func GetAudioEffect(name string) (*AudioEffect, bool) {
+	e, ok := audioEffects[name]
+	return e, ok
+}
+
+// ApplyAudioEffect runs the first MaxEffectInput of the first audio stream
+// of data through the effect and returns it as a voice note.
+func ApplyAudioEffect(data []byte, effect *AudioEffect, amount float64, opts AudioOptions) (*VoiceNote, error) {
+	filter, err := effect.Filter(amount)
+	if err != nil {
+		return nil, err
+	}
+	channels := 1
+	if effect.Stereo {
+		channels = 2
+	}
+	trim := fmt.Sprintf("aresample=48000,atrim=end=%d,", int(MaxEffectInput.Seconds()))
+	return encodeVoiceNote(data, opts, trim+filter, channels)
+}
@@ -0,0 +1,48 @@
+package media
+
+import (
+	"math"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudioEffectFilter(t *testing.T) {
+	pitch, ok := GetAudioEffect("pitch")
+	require.True(t, ok)
+	filter, err := pitch.Filter(12)
+	require.NoError(t, err)
+	assert.Equal(t, "asetrate=96000,aresample=48000,atempo=0.5", filter)
+
+	_, err = pitch.Filter(13)
+	assert.ErrorIs(t, err, ErrEffectAmount)
+	_, err = pitch.Filter(math.NaN())
+	assert.ErrorIs(t, err, ErrEffectAmount)
+
+	reverse, ok := GetAudioEffect("reverse")
+	require.True(t, ok)
+	assert.False(t, reverse.TakesAmount())
+	filter, err = reverse.Filter(100)
+	require.NoError(t, err)
+	assert.Equal(t, "areverse", filter)
+
+	_, ok = GetAudioEffect("autotune")
+	assert.False(t, ok)
+}
+
+func TestApplyAudioEffect(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	for _, name := range []string{"bass", "speed", "slow", "pitch", "reverse", "nightcore", "echo", "robot", "8d"} {
+		t.Run(name, func(t *testing.T) {
+			effect, ok := GetAudioEffect(name)
+			require.True(t, ok)
+			note, err := ApplyAudioEffect(testWAV(1), effect, effect.Default, AudioOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "OggS", string(note.Data[:4]))
+		})
+	}
+}
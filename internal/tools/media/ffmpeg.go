@@ -0,0 +1,191 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var ErrFFmpegMissing = errors.New("ffmpeg is not installed")
+var ErrFFprobeMissing = errors.New("ffprobe is not installed")
+
+// ErrOutputTooLarge is returned when a tool is stopped for writing a file
+// larger than the OutputSize of its limits.
+var ErrOutputTooLarge = errors.New("output is too large")
+
+// maxStderr is how much of the end of the output of a failed run is kept in
+// its error.
+const maxStderr = 1 << 10
+
+// Limits bound the resources of an ffmpeg or ffprobe run.
+type Limits struct {
+	Timeout time.Duration
+	// Memory is the most address space the process can map, in bytes
+	Memory uint64
+	// OutputSize is the largest file the process can write, in bytes
+	OutputSize uint64
+}
+
+// DefaultLimits are the limits of the conversions that don't set their own.
+var DefaultLimits = Limits{
+	Timeout:    2 * time.Minute,
+	Memory:     2 << 30,
+	OutputSize: 256 << 20,
+}
+
+// Capabilities are the media tools found on the system.
+type Capabilities struct {
+	FFmpeg  bool
+	FFprobe bool
+	// Version is the first line of ffmpeg -version
+	Version  string
+	Encoders map[string]bool
+}
+
+// HasEncoder reports whether ffmpeg was built with the named encoder, like
+// libopus or libwebp.
+func (c *Capabilities) HasEncoder(name string) bool {
+	return c.Encoders[name]
+}
+
+var capabilities atomic.Pointer[Capabilities]
+
+// DetectCapabilities looks for ffmpeg, ffprobe and the encoders of ffmpeg.
+// Conversions fail fast when it found a tool missing, it should be called
+// once at startup.
+func DetectCapabilities(ctx context.Context) *Capabilities {
+	caps := &Capabilities{Encoders: map[string]bool{}}
+	if out, err := runTool(ctx, "ffmpeg", Limits{}, "-hide_banner", "-version"); err == nil {
+		caps.FFmpeg = true
+		caps.Version, _, _ = strings.Cut(string(out), "\n")
+		caps.Version = strings.TrimSpace(caps.Version)
+		if out, err := runTool(ctx, "ffmpeg", Limits{}, "-hide_banner", "-encoders"); err == nil {
+			caps.Encoders = parseEncoders(string(out))
+		}
+	}
+	_, err := runTool(ctx, "ffprobe", Limits{}, "-hide_banner", "-version")
+	caps.FFprobe = err == nil
+	capabilities.Store(caps)
+	return caps
+}
+
+// parseEncoders parses the list of ffmpeg -encoders, where each encoder is
+// a line with its flags, name and description after a "------" separator.
+func parseEncoders(out string) map[string]bool {
+	encoders := map[string]bool{}
+	_, list, ok := strings.Cut(out, "------")
+	if !ok {
+		return encoders
+	}
+	for line := range strings.Lines(list) {
+		if fields := strings.Fields(line); len(fields) >= 2 {
+			encoders[fields[1]] = true
+		}
+	}
+	return encoders
+}
+
+// runTool runs an ffmpeg tool within the limits and returns its standard
+// output. The error includes the end of its standard error.
+func runTool(ctx context.Context, name string, limits Limits, args ...string) ([]byte, error) {
+	if caps := capabilities.Load(); caps != nil {
+		if name == "ffmpeg" && !caps.FFmpeg {
+			return nil, ErrFFmpegMissing
+		}
+		if name == "ffprobe" && !caps.FFprobe {
+			return nil, ErrFFprobeMissing
+		}
+	}
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+
+	// Looked up first, as prlimit would only fail to run a missing tool
+	path, err := exec.LookPath(name)
+	if errors.Is(err, exec.ErrNotFound) && name == "ffprobe" {
+		return nil, ErrFFprobeMissing
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, ErrFFmpegMissing
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	limited := limitCommand(cmd, limits)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	cmd.WaitDelay = 5 * time.Second
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	if !limited {
+		if err := applyLimits(cmd.Process.Pid, limits); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, fmt.Errorf("%s: limiting resources: %w", name, err)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("%s: %w", name, ctx.Err())
+		}
+		if outputLimitExceeded(err) {
+			return nil, fmt.Errorf("%s: %w", name, ErrOutputTooLarge)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > maxStderr {
+			msg = "..." + msg[len(msg)-maxStderr:]
+		}
+		return nil, fmt.Errorf("%s: %w: %s", name, err, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// withInputFile writes input to a file in a new temporary directory and
+// calls f with the directory and the path of the file, removing both after.
+func withInputFile[T any](input []byte, f func(dir, path string) (T, error)) (T, error) {
+	var zero T
+	dir, err := os.MkdirTemp("", "meowabot-ffmpeg-")
+	if err != nil {
+		return zero, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "input")
+	if err := os.WriteFile(path, input, 0600); err != nil {
+		return zero, err
+	}
+	return f(dir, path)
+}
+
+// ffmpegConvert runs ffmpeg within DefaultLimits with the input written to a
+// temporary file and returns what it wrote to the output file. args are the
+// options between the input and the output, and outExt picks the output
+// format.
+func ffmpegConvert(input []byte, outExt string, args ...string) ([]byte, error) {
+	return ffmpegConvertLimits(input, outExt, DefaultLimits, args...)
+}
+
+// ffmpegConvertLimits is ffmpegConvert with other limits.
+func ffmpegConvertLimits(input []byte, outExt string, limits Limits, args ...string) ([]byte, error) {
+	return withInputFile(input, func(dir, inPath string) ([]byte, error) {
+		outPath := filepath.Join(dir, "output"+outExt)
+		cmdArgs := append([]string{"-hide_banner", "-loglevel", "error", "-nostdin", "-y", "-i", inPath}, args...)
+		if _, err := runTool(context.Background(), "ffmpeg", limits, append(cmdArgs, outPath)...); err != nil {
+			return nil, err
+		}
+		return os.ReadFile(outPath)
+	})
+}
@@ -0,0 +1,54 @@
+package media
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEncoders(t *testing.T) {
+	out := `Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (codec h264)
+ V....D libwebp              libwebp WebP image (codec webp)
+ A....D libopus              libopus Opus (codec opus)
+`
+	encoders := parseEncoders(out)
+	assert.Equal(t, map[string]bool{"libx264": true, "libwebp": true, "libopus": true}, encoders)
+	assert.Empty(t, parseEncoders("garbage"))
+}
+
+func TestRunToolMissing(t *testing.T) {
+	previous := capabilities.Load()
+	defer capabilities.Store(previous)
+
+	capabilities.Store(&Capabilities{})
+	_, err := ffmpegConvert([]byte("data"), ".mp3")
+	assert.ErrorIs(t, err, ErrFFmpegMissing)
+	_, err = Probe([]byte("data"))
+	assert.ErrorIs(t, err, ErrFFprobeMissing)
+}
+
+func TestRunToolErrors(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	caps := DetectCapabilities(context.Background())
+	assert.True(t, caps.FFmpeg)
+	assert.NotEmpty(t, caps.Version)
+
+	_, err := ffmpegConvert([]byte("not a media file"), ".mp3")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ffmpeg: exit status")
+	assert.Contains(t, err.Error(), "Invalid data")
+
+	_, err = runTool(context.Background(), "ffmpeg", Limits{Timeout: 100 * time.Millisecond},
+		"-hide_banner", "-f", "lavfi", "-re", "-i", "anullsrc", "-f", "null", "-")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,40 @@
+package media
+
+import (
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+var boldFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(gobold.TTF)
+})
+
+var regularFont = sync.OnceValues(func() (*opentype.Font, error) {
+	return opentype.Parse(goregular.TTF)
+})
+
+// boldFace returns the embedded bold font at the given size in points.
+func boldFace(size float64) (font.Face, error) {
+	return newFace(boldFont, size)
+}
+
+// regularFace returns the embedded regular font at the given size in points.
+func regularFace(size float64) (font.Face, error) {
+	return newFace(regularFont, size)
+}
+
+func newFace(parsed func() (*opentype.Font, error), size float64) (font.Face, error) {
+	f, err := parsed()
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
@@ -3,11 +3,8 @@ package media
 import (
 	"bytes"
 	"fmt"
-	"image"
 	"image/jpeg"
 	"math"
-
-	"golang.org/x/image/draw"
 )
 
 func ResizeImg(data []byte, maxWidth int, maxHeight int) ([]byte, error) {
@@ -15,9 +12,9 @@ func ResizeImg(data []byte, maxWidth int, maxHeight int) ([]byte, error) {
 		return nil, fmt.Errorf("maxWidth e maxHeight must be positive")
 	}
 
-	img, _, err := image.Decode(bytes.NewReader(data))
+	img, err := DecodeImage(data)
 	if err != nil {
-		return nil, fmt.Errorf("Error decoding image: %w", err)
+		return nil, err
 	}
 
 	originalWidth := img.Bounds().Dx()
@@ -34,8 +31,7 @@ func ResizeImg(data []byte, maxWidth int, maxHeight int) ([]byte, error) {
 	newWidth := int(float64(originalWidth) * scale)
 	newHeight := int(float64(originalHeight) * scale)
 
-	resizedImg := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
-	draw.BiLinear.Scale(resizedImg, resizedImg.Bounds(), img, img.Bounds(), draw.Over, nil)
+	resizedImg := scaleImage(img, max(newWidth, 1), max(newHeight, 1))
 
 	var buf bytes.Buffer
 	err = jpeg.Encode(&buf, resizedImg, &jpeg.Options{Quality: 90})
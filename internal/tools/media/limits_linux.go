@@ -0,0 +1,72 @@
+package media
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// prlimitPath is the path of the prlimit tool of util-linux, empty if it
+// isn't installed.
+var prlimitPath = sync.OnceValue(func() string {
+	path, _ := exec.LookPath("prlimit")
+	return path
+})
+
+// limitCommand makes cmd run within the memory and file size limits, which
+// prlimit sets before it runs the tool. It returns false if prlimit isn't
+// installed, the limits must then be set with applyLimits once the process
+// started.
+func limitCommand(cmd *exec.Cmd, limits Limits) bool {
+	if limits.Memory == 0 && limits.OutputSize == 0 {
+		return true
+	}
+	prlimit := prlimitPath()
+	if prlimit == "" {
+		return false
+	}
+	args := []string{"prlimit"}
+	if limits.Memory > 0 {
+		args = append(args, "--as="+strconv.FormatUint(limits.Memory, 10))
+	}
+	if limits.OutputSize > 0 {
+		args = append(args, "--fsize="+strconv.FormatUint(limits.OutputSize, 10))
+	}
+	cmd.Args = append(append(args, "--", cmd.Path), cmd.Args[1:]...)
+	cmd.Path = prlimit
+	return true
+}
+
+// applyLimits sets the memory and file size limits of a started process.
+// It runs unlimited until then, but it has only just started, so it can't
+// have used much of either yet.
+func applyLimits(pid int, limits Limits) error {
+	if limits.Memory > 0 {
+		rlimit := &unix.Rlimit{Cur: limits.Memory, Max: limits.Memory}
+		if err := unix.Prlimit(pid, unix.RLIMIT_AS, rlimit, nil); err != nil {
+			return err
+		}
+	}
+	if limits.OutputSize > 0 {
+		rlimit := &unix.Rlimit{Cur: limits.OutputSize, Max: limits.OutputSize}
+		if err := unix.Prlimit(pid, unix.RLIMIT_FSIZE, rlimit, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outputLimitExceeded reports whether the error of a run is the process
+// being killed with SIGXFSZ, for writing past the file size limit.
+func outputLimitExceeded(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGXFSZ
+}
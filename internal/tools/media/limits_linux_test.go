@@ -0,0 +1,35 @@
+package media
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunToolLimits(t *testing.T) {
+	if _, err := exec.LookPath("prlimit"); err != nil {
+		t.Skip("prlimit not installed")
+	}
+	// The limits are in place from the first instruction of the tool
+	out, err := runTool(context.Background(), "cat", Limits{Memory: 1 << 30, OutputSize: 1 << 20}, "/proc/self/limits")
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`Max file size\s+1048576\s+1048576\s+bytes`), string(out))
+	assert.Regexp(t, regexp.MustCompile(`Max address space\s+1073741824\s+1073741824\s+bytes`), string(out))
+}
+
+func TestRunToolOutputLimit(t *testing.T) {
+	if _, err := exec.LookPath("dd"); err != nil {
+		t.Skip("dd not installed")
+	}
+	out := "of=" + filepath.Join(t.TempDir(), "out")
+	_, err := runTool(context.Background(), "dd", Limits{OutputSize: 1 << 10}, "if=/dev/zero", out, "bs=4096", "count=1")
+	assert.ErrorIs(t, err, ErrOutputTooLarge)
+
+	_, err = runTool(context.Background(), "dd", Limits{OutputSize: 1 << 20}, "if=/dev/zero", out, "bs=4096", "count=1")
+	assert.NoError(t, err)
+}
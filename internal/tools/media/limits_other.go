@@ -0,0 +1,21 @@
+//go:build !linux
+
+package media
+
+import "os/exec"
+
+// limitCommand does nothing outside Linux, only the timeout bounds the run.
+func limitCommand(cmd *exec.Cmd, limits Limits) bool {
+	return true
+}
+
+// applyLimits does nothing outside Linux, only the timeout bounds the run.
+func applyLimits(pid int, limits Limits) error {
+	return nil
+}
+
+// outputLimitExceeded is always false outside Linux, where there is no file
+// size limit.
+func outputLimitExceeded(err error) bool {
+	return false
+}
@@ -1,3 +0,0 @@
-package media
-
-const tempDir = "./data"
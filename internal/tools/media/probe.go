@@ -0,0 +1,127 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ProbeInfo is what ffprobe found in a media file.
+type ProbeInfo struct {
+	// Format is the name of the container, e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Format   string
+	Duration time.Duration
+	Streams  []StreamInfo
+}
+
+// StreamInfo is a stream of a media file. Width and Height are only set for
+// video streams, and SampleRate and Channels for audio ones.
+type StreamInfo struct {
+	Index      int
+	Type       string // "video", "audio", "subtitle"...
+	Codec      string
+	Duration   time.Duration
+	Width      int
+	Height     int
+	SampleRate int
+	Channels   int
+	// AttachedPicture is set for the cover art of audio files
+	AttachedPicture bool
+}
+
+// Audio returns the first audio stream, or nil if there is none.
+func (p *ProbeInfo) Audio() *StreamInfo {
+	for i := range p.Streams {
+		if p.Streams[i].Type == "audio" {
+			return &p.Streams[i]
+		}
+	}
+	return nil
+}
+
+// Video returns the first video stream that isn't a cover art, or nil if
+// there is none.
+func (p *ProbeInfo) Video() *StreamInfo {
+	for i := range p.Streams {
+		if p.Streams[i].Type == "video" && !p.Streams[i].AttachedPicture {
+			return &p.Streams[i]
+		}
+	}
+	return nil
+}
+
+// Probe runs ffprobe on data.
+func Probe(data []byte) (*ProbeInfo, error) {
+	return withInputFile(data, func(_, path string) (*ProbeInfo, error) {
+		out, err := runTool(context.Background(), "ffprobe", DefaultLimits,
+			"-hide_banner", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+		if err != nil {
+			return nil, err
+		}
+		return parseProbe(out)
+	})
+}
+
+type probeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+	} `json:"format"`
+	Streams []struct {
+		Index       int    `json:"index"`
+		CodecType   string `json:"codec_type"`
+		CodecName   string `json:"codec_name"`
+		Duration    string `json:"duration"`
+		Width       int    `json:"width"`
+		Height      int    `json:"height"`
+		SampleRate  string `json:"sample_rate"`
+		Channels    int    `json:"channels"`
+		Disposition struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+}
+
+// parseProbe parses the JSON output of ffprobe. ffprobe prints durations and
+// sample rates as strings, and leaves out what it doesn't know.
+func parseProbe(out []byte) (*ProbeInfo, error) {
+	var parsed probeOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, err
+	}
+
+	info := &ProbeInfo{
+		Format:   parsed.Format.FormatName,
+		Duration: parseSeconds(parsed.Format.Duration),
+		Streams:  make([]StreamInfo, len(parsed.Streams)),
+	}
+	for i, s := range parsed.Streams {
+		rate, _ := strconv.Atoi(s.SampleRate)
+		info.Streams[i] = StreamInfo{
+			Index:           s.Index,
+			Type:            s.CodecType,
+			Codec:           s.CodecName,
+			Duration:        parseSeconds(s.Duration),
+			Width:           s.Width,
+			Height:          s.Height,
+			SampleRate:      rate,
+			Channels:        s.Channels,
+			AttachedPicture: s.Disposition.AttachedPic == 1,
+		}
+		// Some containers only know the duration of their streams
+		info.Duration = max(info.Duration, info.Streams[i].Duration)
+	}
+	return info, nil
+}
+
+// parseSeconds parses a duration in seconds like "12.345000", returning 0 if
+// it is missing or invalid.
+func parseSeconds(s string) time.Duration {
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil || !(seconds >= 0 && seconds < math.MaxInt64/float64(time.Second)) {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
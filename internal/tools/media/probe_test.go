@@ -0,0 +1,98 @@
+package media
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProbeJSON = `{
+    "streams": [
+        {
+            "index": 0,
+            "codec_name": "h264",
+            "codec_type": "video",
+            "width": 720,
+            "height": 1280,
+            "duration": "12.500000",
+            "disposition": {"default": 1, "attached_pic": 0}
+        },
+        {
+            "index": 1,
+            "codec_name": "aac",
+            "codec_type": "audio",
+            "sample_rate": "44100",
+            "channels": 2,
+            "duration": "12.480000"
+        },
+        {
+            "index": 2,
+            "codec_name": "mjpeg",
+            "codec_type": "video",
+            "width": 300,
+            "height": 300,
+            "disposition": {"default": 0, "attached_pic": 1}
+        }
+    ],
+    "format": {
+        "format_name": "mov,mp4,m4a,3gp,3g2,mj2",
+        "duration": "12.512000"
+    }
+}`
+
+func TestParseProbe(t *testing.T) {
+	info, err := parseProbe([]byte(testProbeJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "mov,mp4,m4a,3gp,3g2,mj2", info.Format)
+	assert.Equal(t, 12512*time.Millisecond, info.Duration)
+	require.Len(t, info.Streams, 3)
+
+	video := info.Video()
+	require.NotNil(t, video)
+	assert.Equal(t, StreamInfo{Index: 0, Type: "video", Codec: "h264", Duration: 12500 * time.Millisecond, Width: 720, Height: 1280}, *video)
+
+	audio := info.Audio()
+	require.NotNil(t, audio)
+	assert.Equal(t, "aac", audio.Codec)
+	assert.Equal(t, 44100, audio.SampleRate)
+	assert.Equal(t, 2, audio.Channels)
+
+	assert.True(t, info.Streams[2].AttachedPicture)
+
+	_, err = parseProbe([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestParseProbeStreamDuration(t *testing.T) {
+	info, err := parseProbe([]byte(`{"format": {"format_name": "ogg"}, "streams": [{"codec_type": "audio", "duration": "3.5"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, 3500*time.Millisecond, info.Duration)
+	assert.Nil(t, info.Video())
+}
+
+func TestParseSeconds(t *testing.T) {
+	assert.Equal(t, 1500*time.Millisecond, parseSeconds("1.500000"))
+	assert.Zero(t, parseSeconds(""))
+	assert.Zero(t, parseSeconds("N/A"))
+	assert.Zero(t, parseSeconds("-1"))
+	assert.Zero(t, parseSeconds("NaN"))
+	assert.Zero(t, parseSeconds("1e300"))
+}
+
+func TestGetAudioDuration(t *testing.T) {
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		t.Skip("ffprobe not installed")
+	}
+	seconds, err := GetAudioDuration(testWAV(3))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), seconds)
+
+	info, err := Probe(testWAV(1))
+	require.NoError(t, err)
+	require.NotNil(t, info.Audio())
+	assert.Equal(t, 8000, info.Audio().SampleRate)
+	assert.Nil(t, info.Video())
+}
@@ -0,0 +1,116 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sticker limits of WhatsApp.
+const (
+	StickerSize             = 512
+	MaxStickerBytes         = 100 << 10
+	MaxAnimatedStickerBytes = 500 << 10
+	MaxStickerDuration      = 10 * time.Second
+)
+
+var ErrStickerTooLarge = errors.New("sticker doesn't fit the size limit")
+
+// StickerMode is how images that aren't square become stickers.
+type StickerMode string
+
+const (
+	StickerFit     StickerMode = "fit"     // Scale down and pad with transparency
+	StickerCrop    StickerMode = "crop"    // Scale up and crop the center square
+	StickerStretch StickerMode = "stretch" // Scale to a square ignoring the aspect ratio
+)
+
+var stickerModeWords = map[string]StickerMode{
+	"fit": StickerFit, "ajustar": StickerFit,
+	"crop": StickerCrop, "cortar": StickerCrop, "quadrado": StickerCrop,
+	"stretch": StickerStretch, "esticar": StickerStretch,
+}
+
+// ParseStickerMode parses the English or Portuguese name of a sticker mode.
+func ParseStickerMode(word string) (StickerMode, bool) {
+	mode, ok := stickerModeWords[strings.ToLower(word)]
+	return mode, ok
+}
+
+// stickerAttempt is a set of encoder settings, tried from the best quality
+// down until the sticker fits the size limit.
+type stickerAttempt struct {
+	quality int
+	fps     int
+}
+
+var staticStickerAttempts = []stickerAttempt{{quality: 90}, {quality: 75}, {quality: 50}, {quality: 30}}
+
+var animatedStickerAttempts = []stickerAttempt{
+	{quality: 75, fps: 15},
+	{quality: 50, fps: 15},
+	{quality: 40, fps: 10},
+	{quality: 25, fps: 10},
+	{quality: 15, fps: 8},
+}
+
+// stickerFilter returns the ffmpeg filter graph that turns the input into a
+// StickerSize square. Animated stickers also get their frame rate limited.
+func stickerFilter(mode StickerMode, fps int) string {
+	size := strconv.Itoa(StickerSize)
+	var filters []string
+	if fps > 0 {
+		filters = append(filters, fmt.Sprintf("fps=%d", fps))
+	}
+	switch mode {
+	case StickerCrop:
+		filters = append(filters,
+			"scale="+size+":"+size+":force_original_aspect_ratio=increase",
+			"crop="+size+":"+size)
+	case StickerStretch:
+		filters = append(filters, "scale="+size+":"+size)
+	default:
+		filters = append(filters,
+			"scale="+size+":"+size+":force_original_aspect_ratio=decrease",
+			"format=rgba",
+			"pad="+size+":"+size+":(ow-iw)/2:(oh-ih)/2:color=0x00000000")
+	}
+	return strings.Join(filters, ",")
+}
+
+// CreateSticker converts an image, or the first seconds of a video or GIF
+// when animated is set, to a WebP sticker. The quality is lowered until the
+// sticker fits the WhatsApp size limits.
+func CreateSticker(data []byte, animated bool, mode StickerMode) ([]byte, error) {
+	attempts, limit := staticStickerAttempts, MaxStickerBytes
+	if animated {
+		attempts, limit = animatedStickerAttempts, MaxAnimatedStickerBytes
+	}
+
+	for _, attempt := range attempts {
+		args := []string{
+			"-vf", stickerFilter(mode, attempt.fps),
+			"-c:v", "libwebp",
+			"-quality", strconv.Itoa(attempt.quality),
+			"-an",
+		}
+		if animated {
+			args = append(args,
+				"-t", strconv.Itoa(int(MaxStickerDuration.Seconds())),
+				"-loop", "0",
+				"-fps_mode", "passthrough")
+		} else {
+			args = append(args, "-frames:v", "1")
+		}
+		sticker, err := ffmpegConvert(data, ".webp", append(args, "-f", "webp")...)
+		if err != nil {
+			return nil, err
+		}
+		if len(sticker) <= limit {
+			return sticker, nil
+		}
+	}
+	return nil, ErrStickerTooLarge
+}
@@ -0,0 +1,44 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStickerMode(t *testing.T) {
+	mode, ok := ParseStickerMode("Cortar")
+	assert.True(t, ok)
+	assert.Equal(t, StickerCrop, mode)
+
+	_, ok = ParseStickerMode("zoom")
+	assert.False(t, ok)
+}
+
+func TestStickerFilter(t *testing.T) {
+	assert.Equal(t, "scale=512:512:force_original_aspect_ratio=decrease,format=rgba,pad=512:512:(ow-iw)/2:(oh-ih)/2:color=0x00000000", stickerFilter(StickerFit, 0))
+	assert.Equal(t, "fps=15,scale=512:512:force_original_aspect_ratio=increase,crop=512:512", stickerFilter(StickerCrop, 15))
+	assert.Equal(t, "scale=512:512", stickerFilter(StickerStretch, 0))
+}
+
+func TestCreateSticker(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	sticker, err := CreateSticker(testPNG(t, 300, 200), false, StickerFit)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(sticker), MaxStickerBytes)
+	assert.Equal(t, "RIFF", string(sticker[:4]))
+	assert.Equal(t, "WEBP", string(sticker[8:12]))
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
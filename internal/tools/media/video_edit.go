@@ -0,0 +1,169 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MaxVideoDuration is the longest video the video edits accept.
+const MaxVideoDuration = 10 * time.Minute
+
+// MaxVideoOutput is the largest video the video edits produce.
+const MaxVideoOutput = 64 << 20
+
+// minVideoBitrate is the lowest video bitrate CompressVideo uses, in bits
+// per second. Below it the video is unwatchable.
+const minVideoBitrate = 100_000
+
+// compressAudioBitrate is the bitrate of the audio of compressed videos.
+const compressAudioBitrate = 64_000
+
+var ErrNoVideo = errors.New("no video stream")
+var ErrVideoTooLong = errors.New("video is too long")
+var ErrVideoTooLarge = errors.New("video is too large")
+var ErrInvalidRange = errors.New("invalid time range")
+var ErrTargetTooSmall = errors.New("target size is too small for the video")
+
+// videoLimits are the limits of the video edits, which take longer than the
+// other conversions.
+var videoLimits = Limits{
+	Timeout:    5 * time.Minute,
+	Memory:     DefaultLimits.Memory,
+	OutputSize: MaxVideoOutput + 1<<20,
+}
+
+// ProbeVideo checks that data is a video of at most MaxVideoDuration.
+func ProbeVideo(data []byte) (*ProbeInfo, error) {
+	info, err := Probe(data)
+	if err != nil {
+		return nil, err
+	}
+	if info.Video() == nil {
+		return nil, ErrNoVideo
+	}
+	if info.Duration > MaxVideoDuration {
+		return nil, ErrVideoTooLong
+	}
+	return info, nil
+}
+
+// h264Args are the output options of the H.264 MP4 videos WhatsApp plays,
+// with even dimensions no larger than maxSide.
+func h264Args(maxSide int) []string {
+	scale := fmt.Sprintf("scale='min(%d,iw)':'min(%d,ih)':force_original_aspect_ratio=decrease,scale=trunc(iw/2)*2:trunc(ih/2)*2", maxSide, maxSide)
+	return []string{"-vf", scale, "-c:v", "libx264", "-preset", "veryfast", "-pix_fmt", "yuv420p", "-movflags", "+faststart"}
+}
+
+// encodeVideo runs a video edit, checking the size of the output. ffmpeg is
+// killed as soon as the output grows past the limit.
+func encodeVideo(data []byte, args ...string) ([]byte, error) {
+	out, err := ffmpegConvertLimits(data, ".mp4", videoLimits, append(args, "-f", "mp4")...)
+	if errors.Is(err, ErrOutputTooLarge) {
+		return nil, ErrVideoTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > MaxVideoOutput {
+		return nil, ErrVideoTooLarge
+	}
+	return out, nil
+}
+
+// TrimVideo cuts the video between start and end. An end of 0 keeps the
+// video until its end.
+func TrimVideo(data []byte, start, end time.Duration) ([]byte, error) {
+	info, err := ProbeVideo(data)
+	if err != nil {
+		return nil, err
+	}
+	if end == 0 {
+		end = info.Duration
+	}
+	if start < 0 || start >= end || start >= info.Duration {
+		return nil, ErrInvalidRange
+	}
+	args := []string{"-ss", seconds(start), "-to", seconds(end), "-map", "0:v:0", "-map", "0:a:0?"}
+	args = append(args, h264Args(1280)...)
+	return encodeVideo(data, append(args, "-crf", "23", "-c:a", "aac", "-b:a", "128k")...)
+}
+
+// CompressVideo reencodes the video to fit in about target bytes, lowering
+// the resolution when the bitrate left for the video is low.
+func CompressVideo(data []byte, target int) ([]byte, error) {
+	info, err := ProbeVideo(data)
+	if err != nil {
+		return nil, err
+	}
+	if info.Duration <= 0 {
+		return nil, ErrNoVideo
+	}
+
+	// Leave some room for the container
+	bitrate := int(float64(target) * 8 * 0.95 / info.Duration.Seconds())
+	if info.Audio() != nil {
+		bitrate -= compressAudioBitrate
+	}
+	if bitrate < minVideoBitrate {
+		return nil, ErrTargetTooSmall
+	}
+	maxSide := 1280
+	switch {
+	case bitrate < 400_000:
+		maxSide = 640
+	case bitrate < 1_000_000:
+		maxSide = 854
+	}
+
+	args := append([]string{"-map", "0:v:0", "-map", "0:a:0?"}, h264Args(maxSide)...)
+	args = append(args,
+		"-b:v", strconv.Itoa(bitrate), "-maxrate", strconv.Itoa(bitrate*3/2), "-bufsize", strconv.Itoa(bitrate*2),
+		"-c:a", "aac", "-b:a", strconv.Itoa(compressAudioBitrate), "-ac", "2")
+	out, err := encodeVideo(data, args...)
+	if err != nil {
+		return nil, err
+	}
+	// The rate control can overshoot a little, not by a lot
+	if len(out) > target*11/10 {
+		return nil, ErrTargetTooSmall
+	}
+	return out, nil
+}
+
+// ToGIFVideo converts the video into a silent MP4 to be sent with GIF
+// playback, which WhatsApp loops like a GIF.
+func ToGIFVideo(data []byte) ([]byte, error) {
+	if _, err := ProbeVideo(data); err != nil {
+		return nil, err
+	}
+	args := append([]string{"-map", "0:v:0", "-an"}, h264Args(720)...)
+	return encodeVideo(data, append(args, "-crf", "26")...)
+}
+
+// StripAudio removes the audio of the video without reencoding it.
+func StripAudio(data []byte) ([]byte, error) {
+	if _, err := ProbeVideo(data); err != nil {
+		return nil, err
+	}
+	return encodeVideo(data, "-map", "0:v:0", "-c:v", "copy", "-an", "-movflags", "+faststart")
+}
+
+// ExtractFrame returns the frame of the video at the given time as a JPEG.
+func ExtractFrame(data []byte, at time.Duration) ([]byte, error) {
+	info, err := ProbeVideo(data)
+	if err != nil {
+		return nil, err
+	}
+	if at < 0 || at >= max(info.Duration, time.Millisecond) {
+		return nil, ErrInvalidRange
+	}
+	return ffmpegConvertLimits(data, ".jpg", videoLimits,
+		"-ss", seconds(at), "-map", "0:v:0", "-frames:v", "1", "-q:v", "2", "-f", "image2")
+}
+
+// seconds formats a duration as seconds for ffmpeg.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,80 @@
+package media
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeconds(t *testing.T) {
+	assert.Equal(t, "90.500", seconds(90500*time.Millisecond))
+	assert.Equal(t, "0.000", seconds(0))
+}
+
+// testVideo generates a 4 second 320x240 video with a tone, skipping the
+// test without ffmpeg.
+func testVideo(t *testing.T) []byte {
+	t.Helper()
+	for _, tool := range []string{"ffmpeg", "ffprobe"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skip(tool + " not installed")
+		}
+	}
+	path := filepath.Join(t.TempDir(), "test.mp4")
+	cmd := exec.Command("ffmpeg", "-hide_banner", "-loglevel", "error",
+		"-f", "lavfi", "-i", "testsrc=duration=4:size=320x240:rate=25",
+		"-f", "lavfi", "-i", "sine=duration=4",
+		"-c:v", "libx264", "-pix_fmt", "yuv420p", "-c:a", "aac", "-shortest", path)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func TestVideoEdits(t *testing.T) {
+	video := testVideo(t)
+
+	trimmed, err := TrimVideo(video, time.Second, 3*time.Second)
+	require.NoError(t, err)
+	info, err := Probe(trimmed)
+	require.NoError(t, err)
+	assert.InDelta(t, 2, info.Duration.Seconds(), 0.2)
+	assert.NotNil(t, info.Audio())
+
+	_, err = TrimVideo(video, 5*time.Second, 0)
+	assert.ErrorIs(t, err, ErrInvalidRange)
+
+	compressed, err := CompressVideo(video, 200<<10)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(compressed), 220<<10)
+	_, err = CompressVideo(video, 10<<10)
+	assert.ErrorIs(t, err, ErrTargetTooSmall)
+
+	gif, err := ToGIFVideo(video)
+	require.NoError(t, err)
+	info, err = Probe(gif)
+	require.NoError(t, err)
+	assert.Nil(t, info.Audio())
+
+	silent, err := StripAudio(video)
+	require.NoError(t, err)
+	info, err = Probe(silent)
+	require.NoError(t, err)
+	assert.Nil(t, info.Audio())
+	assert.NotNil(t, info.Video())
+
+	frame, err := ExtractFrame(video, 2*time.Second)
+	require.NoError(t, err)
+	img, err := DecodeImage(frame)
+	require.NoError(t, err)
+	assert.Equal(t, 320, img.Bounds().Dx())
+
+	_, err = ExtractFrame(testWAV(1), 0)
+	assert.ErrorIs(t, err, ErrNoVideo)
+}
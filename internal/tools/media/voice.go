@@ -0,0 +1,143 @@
+package media
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// VoiceNoteMimetype is the mimetype WhatsApp expects for voice notes.
+const VoiceNoteMimetype = "audio/ogg; codecs=opus"
+
+// MP3Mimetype is the mimetype of the MP3 conversions.
+const MP3Mimetype = "audio/mpeg"
+
+// WaveformBars is how many bars WhatsApp draws for a voice note.
+const WaveformBars = 64
+
+// waveformRate is the sample rate the audio is decoded at to draw its
+// waveform and measure its length.
+const waveformRate = 8000
+
+var ErrUnsupportedCodec = errors.New("voice notes must be encoded with libopus or opus")
+var ErrNoAudio = errors.New("no audio stream")
+
+// AudioOptions are the encoder settings of the audio conversions. Empty
+// fields use the defaults.
+type AudioOptions struct {
+	VoiceCodec   string // libopus (default) or opus, ffmpeg's own encoder
+	VoiceBitrate string // 48k by default
+	MP3Bitrate   string // 128k by default
+}
+
+// VoiceNote is an OGG/Opus audio ready to be sent as a voice note.
+type VoiceNote struct {
+	Data     []byte
+	Seconds  uint32
+	Waveform []byte
+}
+
+// ToVoiceNote converts the first audio stream of any audio or video into a
+// mono OGG/Opus voice note and computes its waveform.
+func ToVoiceNote(data []byte, opts AudioOptions) (*VoiceNote, error) {
+	return encodeVoiceNote(data, opts, "", 1)
+}
+
+// encodeVoiceNote encodes the first audio stream of data as a voice note with
+// the given number of channels, after running it through filter if it is not
+// empty. It is the pipeline shared by the voice note conversions and effects.
+func encodeVoiceNote(data []byte, opts AudioOptions, filter string, channels int) (*VoiceNote, error) {
+	codec := cmp.Or(opts.VoiceCodec, "libopus")
+	args := []string{"-map", "0:a:0", "-map_metadata", "-1"}
+	if filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args, "-ac", fmt.Sprint(channels), "-ar", "48000",
+		"-c:a", codec, "-b:a", cmp.Or(opts.VoiceBitrate, "48k"))
+	switch codec {
+	case "libopus":
+		args = append(args, "-application", "voip")
+	case "opus":
+		// ffmpeg's own encoder is still marked as experimental
+		args = append(args, "-strict", "experimental")
+	default:
+		return nil, fmt.Errorf("%w, not %q", ErrUnsupportedCodec, codec)
+	}
+
+	ogg, err := ffmpegConvert(data, ".ogg", append(args, "-f", "ogg")...)
+	if err != nil {
+		return nil, audioError(err)
+	}
+	samples, err := decodeSamples(ogg)
+	if err != nil {
+		return nil, err
+	}
+	return &VoiceNote{
+		Data:     ogg,
+		Seconds:  uint32((len(samples) + waveformRate - 1) / waveformRate),
+		Waveform: Waveform(samples, WaveformBars),
+	}, nil
+}
+
+// ToMP3 converts the first audio stream of any audio or video into MP3.
+func ToMP3(data []byte, opts AudioOptions) ([]byte, error) {
+	mp3, err := ffmpegConvert(data, ".mp3", "-map", "0:a:0", "-map_metadata", "-1",
+		"-c:a", "libmp3lame", "-b:a", cmp.Or(opts.MP3Bitrate, "128k"), "-f", "mp3")
+	if err != nil {
+		return nil, audioError(err)
+	}
+	return mp3, nil
+}
+
+// audioError turns the error of ffmpeg not finding the mapped audio stream
+// into ErrNoAudio.
+func audioError(err error) error {
+	if strings.Contains(err.Error(), "matches no streams") {
+		return ErrNoAudio
+	}
+	return err
+}
+
+// decodeSamples decodes audio into mono 16 bit samples at waveformRate.
+func decodeSamples(data []byte) ([]int16, error) {
+	raw, err := ffmpegConvert(data, ".raw", "-vn", "-ac", "1", "-ar", fmt.Sprint(waveformRate), "-f", "s16le")
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]int16, len(raw)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(raw[i*2:]))
+	}
+	return samples, nil
+}
+
+// Waveform splits the samples in bars and returns the loudness of each one
+// from 0 to 100, relative to the loudest, as WhatsApp draws voice notes.
+func Waveform(samples []int16, bars int) []byte {
+	levels := make([]float64, bars)
+	var loudest float64
+	for i := range levels {
+		start, end := i*len(samples)/bars, (i+1)*len(samples)/bars
+		if start == end {
+			continue
+		}
+		var sum float64
+		for _, s := range samples[start:end] {
+			sum += float64(s) * float64(s)
+		}
+		levels[i] = math.Sqrt(sum / float64(end-start))
+		loudest = max(loudest, levels[i])
+	}
+
+	waveform := make([]byte, bars)
+	if loudest == 0 {
+		return waveform
+	}
+	for i, level := range levels {
+		waveform[i] = byte(math.Round(level / loudest * 100))
+	}
+	return waveform
+}
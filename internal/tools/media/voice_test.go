@@ -0,0 +1,78 @@
+package media
+
+import (
+	"encoding/binary"
+	"math"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaveform(t *testing.T) {
+	samples := make([]int16, 400)
+	for i := range samples {
+		if i >= 200 {
+			samples[i] = 1000
+		}
+		if i >= 300 {
+			samples[i] = -2000
+		}
+	}
+	assert.Equal(t, []byte{0, 0, 50, 100}, Waveform(samples, 4))
+
+	assert.Equal(t, make([]byte, 4), Waveform(make([]int16, 10), 4))
+	assert.Equal(t, make([]byte, 4), Waveform(nil, 4))
+	assert.Equal(t, []byte{0, 100, 0, 100}, Waveform([]int16{5, 5}, 4))
+}
+
+func TestToVoiceNote(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	note, err := ToVoiceNote(testWAV(2), AudioOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "OggS", string(note.Data[:4]))
+	assert.Equal(t, uint32(2), note.Seconds)
+	assert.Len(t, note.Waveform, WaveformBars)
+
+	_, err = ToVoiceNote(testWAV(1), AudioOptions{VoiceCodec: "aac"})
+	assert.ErrorIs(t, err, ErrUnsupportedCodec)
+
+	_, err = ToVoiceNote(testPNG(t, 16, 16), AudioOptions{})
+	assert.ErrorIs(t, err, ErrNoAudio)
+}
+
+func TestToMP3(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	mp3, err := ToMP3(testWAV(1), AudioOptions{MP3Bitrate: "64k"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, mp3)
+}
+
+// testWAV returns a mono 16 bit WAV file with a 440 Hz tone.
+func testWAV(seconds int) []byte {
+	const rate = 8000
+	n := seconds * rate
+	wav := make([]byte, 44+n*2)
+	copy(wav, "RIFF")
+	binary.LittleEndian.PutUint32(wav[4:], uint32(36+n*2))
+	copy(wav[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(wav[16:], 16)
+	binary.LittleEndian.PutUint16(wav[20:], 1)
+	binary.LittleEndian.PutUint16(wav[22:], 1)
+	binary.LittleEndian.PutUint32(wav[24:], rate)
+	binary.LittleEndian.PutUint32(wav[28:], rate*2)
+	binary.LittleEndian.PutUint16(wav[32:], 2)
+	binary.LittleEndian.PutUint16(wav[34:], 16)
+	copy(wav[36:], "data")
+	binary.LittleEndian.PutUint32(wav[40:], uint32(n*2))
+	for i := range n {
+		s := int16(math.Sin(2*math.Pi*440*float64(i)/rate) * 10000)
+		binary.LittleEndian.PutUint16(wav[44+i*2:], uint16(s))
+	}
+	return wav
+}
@@ -1,172 +1,42 @@
 package media
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
-	"slices"
+	"fmt"
+	"meowabot/internal/tools/webp"
 )
 
-type webpChunk struct {
-	name [4]byte
-	data []byte
+// StickerMetadata is the sticker pack information WhatsApp keeps as JSON in
+// the EXIF chunk of stickers.
+type StickerMetadata struct {
+	PackID              string   `json:"sticker-pack-id,omitempty"`
+	Name                string   `json:"sticker-pack-name"`
+	Publisher           string   `json:"sticker-pack-publisher"`
+	Emojis              []string `json:"emojis,omitempty"`
+	AndroidAppStoreLink string   `json:"android-app-store-link,omitempty"`
+	IOSAppStoreLink     string   `json:"ios-app-store-link,omitempty"`
 }
 
-func newWebpChunk(name [4]byte, data []byte) (*webpChunk, error) {
-	for _, b := range name {
-		if b < 32 || b > 126 {
-			return nil, errors.New("invalid chunk name")
-		}
-	}
-	return &webpChunk{name: name, data: data}, nil
-}
-
-func (c *webpChunk) toBytes() []byte {
-	chunkSize := len(c.data)
-	totalSize := 8 + chunkSize
-	if chunkSize%2 != 0 {
-		totalSize++
-	}
-
-	buf := make([]byte, 0, totalSize)
-	buf = append(buf, c.name[:]...)
-	size := make([]byte, 4)
-	binary.LittleEndian.PutUint32(size, uint32(chunkSize))
-	buf = append(buf, size...)
-	buf = append(buf, c.data...)
-	if chunkSize%2 != 0 {
-		buf = append(buf, 0)
-	}
-	return buf
-}
-
-func parseWebp(data []byte) ([]*webpChunk, error) {
-	if len(data) < 12 {
-		return nil, errors.New("invalid file size")
-	}
-	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
-		return nil, errors.New("not webp file")
-	}
-
-	expectedSize := binary.LittleEndian.Uint32(data[4:8]) + 8
-	if uint32(len(data)) < expectedSize {
-		return nil, errors.New("corrupted file")
-	}
-
-	var chunks []*webpChunk
-	offset := 12
-
-	for offset+8 <= len(data) {
-		var name [4]byte
-		copy(name[:], data[offset:offset+4])
-		size := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
-		offset += 8
-
-		if offset+int(size) > len(data) {
-			return nil, errors.New("invalid chunk size")
-		}
-
-		chunkData := make([]byte, size)
-		copy(chunkData, data[offset:offset+int(size)])
-		offset += int(size)
-
-		if size%2 != 0 && offset < len(data) {
-			offset++
-		}
-
-		chunks = append(chunks, &webpChunk{name: name, data: chunkData})
-
-		if offset >= int(expectedSize) {
-			break
-		}
-	}
-
-	return chunks, nil
-}
-
-func chunksToWebp(chunks []*webpChunk) []byte {
-	vp8xChunk := []byte{
-		'V', 'P', '8', 'X',
-		0x0A, 0x00, 0x00, 0x00,
-		0x00,
-		0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00,
-		0x00, 0x00, 0x00,
-	}
-	var vp8xFlags *byte
-	var imageDimensions *[2]uint32
-
-	filtered := make([]*webpChunk, 0, len(chunks))
-	for _, c := range chunks {
-		if string(c.name[:]) == "VP8X" {
-			continue
-		}
-		switch string(c.name[:]) {
-		case "VP8 ":
-			width := uint32(binary.LittleEndian.Uint16(c.data[6:8]) & 0x3FFF)
-			height := uint32(binary.LittleEndian.Uint16(c.data[8:10]) & 0x3FFF)
-			imageDimensions = &[2]uint32{width, height}
-		case "VP8L":
-			bytes := binary.LittleEndian.Uint32(c.data[1:5])
-			if (bytes>>28)&1 == 1 {
-				setFlag(&vp8xFlags, 0b10000)
-			}
-			imageDimensions = &[2]uint32{(bytes & 0x3FFF) + 1, ((bytes >> 14) & 0x3FFF) + 1}
-		case "ANMF":
-			width := fromU24LE(c.data[7:10]) + 1
-			height := fromU24LE(c.data[10:13]) + 1
-			imageDimensions = &[2]uint32{width, height}
-			setFlag(&vp8xFlags, 0b10)
-		case "XMP ":
-			setFlag(&vp8xFlags, 0b100)
-		case "EXIF":
-			setFlag(&vp8xFlags, 0b1000)
-		case "ALPH":
-			setFlag(&vp8xFlags, 0b10000)
-		case "ICCP":
-			setFlag(&vp8xFlags, 0b100000)
-		}
-		filtered = append(filtered, c)
-	}
-
-	data := []byte{'R', 'I', 'F', 'F', 0, 0, 0, 0, 'W', 'E', 'B', 'P'}
+// stickerExifTag is the EXIF tag WhatsApp stores the sticker JSON in.
+const stickerExifTag = 0x5741
 
-	if vp8xFlags != nil && imageDimensions != nil {
-		vp8xChunk[8] = *vp8xFlags
-		copy(vp8xChunk[12:15], toU24LE(imageDimensions[0]-1))
-		copy(vp8xChunk[15:18], toU24LE(imageDimensions[1]-1))
-		data = append(data, vp8xChunk...)
-	}
-
-	for _, c := range filtered {
-		data = append(data, c.toBytes()...)
-	}
+var ErrNoStickerMetadata = errors.New("sticker has no metadata")
 
-	size := uint32(len(data) - 8)
-	binary.LittleEndian.PutUint32(data[4:8], size)
-	return data
+func AddExifToWebp(data []byte, title string, description string) ([]byte, error) {
+	return SetStickerMetadata(data, &StickerMetadata{Name: title, Publisher: description})
 }
 
-func toU24LE(n uint32) []byte {
-	return []byte{byte(n), byte(n >> 8), byte(n >> 16)}
-}
-func fromU24LE(b []byte) uint32 {
-	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
-}
-func setFlag(flags **byte, mask byte) {
-	if *flags == nil {
-		tmp := byte(0)
-		*flags = &tmp
-	}
-	**flags |= mask
-}
-
-func AddExifToWebp(webp []byte, title string, description string) ([]byte, error) {
-	stickerJson := map[string]any{
-		"sticker-pack-name":      title,
-		"sticker-pack-publisher": description,
+// SetStickerMetadata replaces the EXIF chunk of a WebP with the sticker
+// metadata.
+func SetStickerMetadata(data []byte, metadata *StickerMetadata) ([]byte, error) {
+	f, err := webp.Parse(data)
+	if err != nil {
+		return nil, err
 	}
-	jsonBytes, err := json.Marshal(stickerJson)
+	jsonBytes, err := json.Marshal(metadata)
 	if err != nil {
 		return nil, err
 	}
@@ -180,21 +50,81 @@ func AddExifToWebp(webp []byte, title string, description string) ([]byte, error
 	}
 	jsonLength := uint32(len(jsonBytes))
 	binary.LittleEndian.PutUint32(jsonBytes2[14:], jsonLength)
-	data := append(jsonBytes2, jsonBytes...)
-	exif, err := newWebpChunk([4]byte{'E', 'X', 'I', 'F'}, data)
+	f.SetChunk(webp.ChunkEXIF, append(jsonBytes2, jsonBytes...))
+	return f.Encode()
+}
+
+// GetStickerMetadata reads the sticker metadata from the EXIF chunk of a
+// WebP. It returns ErrNoStickerMetadata when there is none.
+func GetStickerMetadata(data []byte) (*StickerMetadata, error) {
+	f, err := webp.Parse(data)
 	if err != nil {
 		return nil, err
 	}
-	webpChunks, err := parseWebp(webp)
-	if err != nil {
-		return nil, err
+	exif := f.Chunk(webp.ChunkEXIF)
+	if exif == nil {
+		return nil, ErrNoStickerMetadata
 	}
 
-	webpChunks = slices.DeleteFunc(webpChunks, func(c *webpChunk) bool {
-		return c.name == [4]byte{'E', 'X', 'I', 'F'}
-	})
-
-	webpChunks = append(webpChunks, exif)
+	value := exifTagValue(exif, stickerExifTag)
+	if value == nil {
+		// Some tools write the JSON without a proper IFD entry
+		start := bytes.IndexByte(exif, '{')
+		if start < 0 {
+			return nil, ErrNoStickerMetadata
+		}
+		value = exif[start:]
+	}
+	var metadata StickerMetadata
+	if err := json.NewDecoder(bytes.NewReader(value)).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("invalid sticker metadata: %w", err)
+	}
+	return &metadata, nil
+}
 
-	return chunksToWebp(webpChunks), nil
+// exifTagValue returns the value of a tag in the first IFD of EXIF data, or
+// nil if it isn't there.
+func exifTagValue(exif []byte, tag uint16) []byte {
+	exif = bytes.TrimPrefix(exif, []byte("Exif\x00\x00"))
+	if len(exif) < 8 {
+		return nil
+	}
+	var order binary.ByteOrder
+	switch string(exif[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	ifd := int64(order.Uint32(exif[4:8]))
+	if ifd+2 > int64(len(exif)) {
+		return nil
+	}
+	count := int64(order.Uint16(exif[ifd:]))
+	for i := range count {
+		entry := ifd + 2 + i*12
+		if entry+12 > int64(len(exif)) {
+			return nil
+		}
+		if order.Uint16(exif[entry:]) != tag {
+			continue
+		}
+		// Only undefined and ASCII values hold the JSON
+		if kind := order.Uint16(exif[entry+2:]); kind != 7 && kind != 2 {
+			return nil
+		}
+		size := int64(order.Uint32(exif[entry+4:]))
+		if size <= 4 {
+			return exif[entry+8 : entry+8+size]
+		}
+		offset := int64(order.Uint32(exif[entry+8:]))
+		if offset+size > int64(len(exif)) {
+			return nil
+		}
+		return exif[offset : offset+size]
+	}
+	return nil
 }
@@ -0,0 +1,145 @@
+package media
+
+import (
+	"bytes"
+	"cmp"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"meowabot/internal/tools/webp"
+	"slices"
+	"time"
+
+	"golang.org/x/image/draw"
+)
+
+// WebpToImage converts a WebP to PNG, or to JPEG over a white background
+// when jpg is set.
+func WebpToImage(data []byte, jpg bool) ([]byte, error) {
+	img, err := webp.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if jpg {
+		err = jpeg.Encode(&buf, flatten(img, color.White), &jpeg.Options{Quality: 90})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WebpToGIF converts an animated WebP to a GIF. Pixels that are mostly
+// transparent become transparent.
+func WebpToGIF(data []byte) ([]byte, error) {
+	anim, err := webp.DecodeAnimation(data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeGIF(anim)
+}
+
+// WebpToMP4 converts an animated WebP to an MP4 over a white background.
+func WebpToMP4(data []byte) ([]byte, error) {
+	anim, err := webp.DecodeAnimation(data)
+	if err != nil {
+		return nil, err
+	}
+	for n := range anim.Frames {
+		anim.Frames[n].Image = flatten(anim.Frames[n].Image, color.White)
+	}
+	animGIF, err := encodeGIF(anim)
+	if err != nil {
+		return nil, err
+	}
+	return ffmpegConvert(animGIF, ".mp4",
+		"-movflags", "+faststart",
+		"-pix_fmt", "yuv420p",
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"-an")
+}
+
+// flatten draws the image over a solid background.
+func flatten(img image.Image, background color.Color) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+func encodeGIF(anim *webp.Animation) ([]byte, error) {
+	palette, lookup := gifPalette(anim.Frames)
+	// Both formats loop forever on zero, but GIFs count the repetitions
+	// after the first play and use -1 to play once
+	out := &gif.GIF{}
+	if anim.LoopCount > 0 {
+		out.LoopCount = cmp.Or(anim.LoopCount-1, -1)
+	}
+	for _, frame := range anim.Frames {
+		paletted := image.NewPaletted(frame.Image.Bounds(), palette)
+		for i := 0; i < len(frame.Image.Pix); i += 4 {
+			p := frame.Image.Pix[i : i+4 : i+4]
+			if p[3] >= 128 {
+				paletted.Pix[i/4] = lookup[rgb15(p[0], p[1], p[2])]
+			}
+		}
+		out.Image = append(out.Image, paletted)
+		out.Delay = append(out.Delay, max(int(frame.Duration/(10*time.Millisecond)), 2))
+		out.Disposal = append(out.Disposal, gif.DisposalBackground)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gifPalette picks the 255 most used colors of the frames, reduced to 15 bits,
+// after a transparent entry. lookup maps every 15 bit color to its closest
+// entry in the palette.
+func gifPalette(frames []webp.AnimationFrame) (color.Palette, *[1 << 15]uint8) {
+	var counts [1 << 15]int
+	for _, frame := range frames {
+		for i := 0; i < len(frame.Image.Pix); i += 4 {
+			p := frame.Image.Pix[i : i+4 : i+4]
+			if p[3] >= 128 {
+				counts[rgb15(p[0], p[1], p[2])]++
+			}
+		}
+	}
+	var used []int
+	for c, count := range counts {
+		if count > 0 {
+			used = append(used, c)
+		}
+	}
+	slices.SortFunc(used, func(a, b int) int { return cmp.Compare(counts[b], counts[a]) })
+	used = used[:min(len(used), 255)]
+
+	palette := color.Palette{color.Transparent}
+	for _, c := range used {
+		palette = append(palette, color.RGBA{expand5(c >> 10), expand5(c >> 5), expand5(c), 255})
+	}
+	lookup := new([1 << 15]uint8)
+	if len(used) == 0 {
+		return palette, lookup
+	}
+	for c := range lookup {
+		lookup[c] = uint8(1 + color.Palette(palette[1:]).Index(color.RGBA{expand5(c >> 10), expand5(c >> 5), expand5(c), 255}))
+	}
+	return palette, lookup
+}
+
+func rgb15(r, g, b uint8) int {
+	return int(r>>3)<<10 | int(g>>3)<<5 | int(b>>3)
+}
+
+func expand5(c int) uint8 {
+	c &= 0x1f
+	return uint8(c<<3 | c>>2)
+}
@@ -0,0 +1,86 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	_ "image/jpeg"
+	"meowabot/internal/tools/webp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testVP8L is a transparent 1x1 lossless image.
+var testVP8L = webp.Chunk{ID: webp.ChunkVP8L, Data: []byte{0x2f, 0x00, 0x00, 0x00, 0x10, 0x07, 0x10, 0x11, 0x11, 0x88, 0x88, 0xfe, 0x07}}
+
+func testWebp(t *testing.T, f *webp.File) []byte {
+	data, err := f.Encode()
+	require.NoError(t, err)
+	return data
+}
+
+func TestStickerMetadata(t *testing.T) {
+	still := testWebp(t, &webp.File{Width: 1, Height: 1, Chunks: []webp.Chunk{testVP8L}})
+	_, err := GetStickerMetadata(still)
+	assert.ErrorIs(t, err, ErrNoStickerMetadata)
+
+	sticker, err := AddExifToWebp(still, "Pacote", "Autor")
+	require.NoError(t, err)
+	metadata, err := GetStickerMetadata(sticker)
+	require.NoError(t, err)
+	assert.Equal(t, &StickerMetadata{Name: "Pacote", Publisher: "Autor"}, metadata)
+
+	metadata.Emojis = []string{"😺"}
+	metadata.AndroidAppStoreLink = "https://play.google.com/store/apps/details?id=example"
+	sticker, err = SetStickerMetadata(sticker, metadata)
+	require.NoError(t, err)
+	renamed, err := GetStickerMetadata(sticker)
+	require.NoError(t, err)
+	assert.Equal(t, metadata, renamed)
+
+	f, err := webp.Parse(sticker)
+	require.NoError(t, err)
+	var exifs int
+	for _, c := range f.Chunks {
+		if c.ID == webp.ChunkEXIF {
+			exifs++
+		}
+	}
+	assert.Equal(t, 1, exifs)
+}
+
+func TestExifTagValue(t *testing.T) {
+	// Big endian, with the value stored inline
+	exif := []byte{'M', 'M', 0, '*', 0, 0, 0, 8, 0, 1, 0x57, 0x41, 0, 7, 0, 0, 0, 2, '{', '}', 0, 0}
+	assert.Equal(t, []byte("{}"), exifTagValue(exif, stickerExifTag))
+
+	assert.Nil(t, exifTagValue(exif, 0x1234))
+	assert.Nil(t, exifTagValue(exif[:12], stickerExifTag))
+	assert.Nil(t, exifTagValue([]byte("garbage"), stickerExifTag))
+}
+
+func TestWebpConversions(t *testing.T) {
+	data := testWebp(t, &webp.File{Width: 4, Height: 4, LoopCount: 1, Chunks: []webp.Chunk{
+		webp.NewANMF(webp.Frame{Width: 1, Height: 1, Duration: 100 * time.Millisecond, Chunks: []webp.Chunk{testVP8L}}),
+		webp.NewANMF(webp.Frame{X: 2, Width: 1, Height: 1, Duration: 100 * time.Millisecond, Chunks: []webp.Chunk{testVP8L}}),
+	}})
+
+	animGIF, err := WebpToGIF(data)
+	require.NoError(t, err)
+	decoded, err := gif.DecodeAll(bytes.NewReader(animGIF))
+	require.NoError(t, err)
+	assert.Len(t, decoded.Image, 2)
+	assert.Equal(t, []int{10, 10}, decoded.Delay)
+	assert.Equal(t, -1, decoded.LoopCount)
+
+	for _, jpg := range []bool{false, true} {
+		still, err := WebpToImage(data, jpg)
+		require.NoError(t, err)
+		img, _, err := image.Decode(bytes.NewReader(still))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 4, 4), img.Bounds())
+	}
+}
@@ -0,0 +1,197 @@
+// Package mediacache keeps media on disk up to a total size, evicting the
+// least recently used files first.
+package mediacache
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrInvalidKey = errors.New("invalid cache key")
+
+// keyRegex matches the keys that are safe to use as file names.
+var keyRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,127}$`)
+
+// tempPrefix starts the names of files still being written.
+const tempPrefix = ".tmp-"
+
+// Cache is a least recently used cache of files in a directory. The order
+// of use is kept in the modification times of the files, so it survives
+// restarts. It is safe for concurrent use.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List // of *entry, most recently used first
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key  string
+	size int64
+}
+
+// New opens the cache in dir, creating it if needed, and evicts files until
+// it fits in maxBytes.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var existing []file
+	for _, f := range files {
+		info, err := f.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if !keyRegex.MatchString(f.Name()) {
+			// Left behind by a crash while writing
+			os.Remove(filepath.Join(dir, f.Name()))
+			continue
+		}
+		existing = append(existing, file{f.Name(), info.Size(), info.ModTime()})
+	}
+	slices.SortFunc(existing, func(a, b file) int {
+		return a.modTime.Compare(b.modTime)
+	})
+	for _, f := range existing {
+		c.entries[f.key] = c.order.PushFront(&entry{f.key, f.size})
+		c.size += f.size
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	return c, nil
+}
+
+// Get returns the cached data of key, marking it as recently used.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	if !keyRegex.MatchString(key) {
+		return nil, false
+	}
+	c.mu.Lock()
+	elem, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	// The file is read without the lock. Put renames whole files into place,
+	// and a file evicted meanwhile stays readable once opened.
+	path := filepath.Join(c.dir, key)
+	data, err := os.ReadFile(path)
+
+	c.mu.Lock()
+	// Put replaces the entry when it replaces the file
+	current := c.entries[key] == elem
+	if err != nil {
+		if current {
+			c.remove(elem)
+		}
+		c.mu.Unlock()
+		return nil, false
+	}
+	if current {
+		c.order.MoveToFront(elem)
+	}
+	c.mu.Unlock()
+
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// Put stores data under key, evicting the least recently used files if the
+// cache gets too large. Data larger than the whole cache is not stored.
+func (c *Cache) Put(key string, data []byte) error {
+	if !keyRegex.MatchString(key) {
+		return fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+	if int64(len(data)) > c.maxBytes {
+		return nil
+	}
+
+	// Written to a temporary file first, so readers never see half a file
+	tmp, err := os.CreateTemp(c.dir, tempPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, key)); err != nil {
+		return err
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.size -= elem.Value.(*entry).size
+		c.order.Remove(elem)
+	}
+	c.entries[key] = c.order.PushFront(&entry{key, int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+	return nil
+}
+
+// Size returns the total size of the cached files.
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Len returns how many files are cached.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// evict removes the least recently used files until the cache fits.
+// Must be called with the lock held.
+func (c *Cache) evict() {
+	for c.size > c.maxBytes && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove deletes an entry and its file. Must be called with the lock held.
+func (c *Cache) remove(elem *list.Element) {
+	e := elem.Value.(*entry)
+	os.Remove(filepath.Join(c.dir, e.key))
+	c.order.Remove(elem)
+	delete(c.entries, e.key)
+	c.size -= e.size
+}
@@ -0,0 +1,118 @@
+package mediacache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPut(t *testing.T) {
+	c, err := New(t.TempDir(), 100)
+	require.NoError(t, err)
+
+	_, ok := c.Get("missing")
+	assert.False(t, ok)
+
+	require.NoError(t, c.Put("abc", []byte("hello")))
+	data, ok := c.Get("abc")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("hello"), data)
+
+	require.NoError(t, c.Put("abc", []byte("hi")))
+	data, _ = c.Get("abc")
+	assert.Equal(t, []byte("hi"), data)
+	assert.Equal(t, int64(2), c.Size())
+	assert.Equal(t, 1, c.Len())
+
+	for _, key := range []string{"", "../escape", "a/b", "UPPER", ".hidden"} {
+		assert.ErrorIs(t, c.Put(key, []byte("x")), ErrInvalidKey, key)
+		_, ok := c.Get(key)
+		assert.False(t, ok, key)
+	}
+
+	// Too large for the whole cache, silently skipped
+	require.NoError(t, c.Put("big", make([]byte, 101)))
+	_, ok = c.Get("big")
+	assert.False(t, ok)
+}
+
+func TestEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 30)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Put("a", make([]byte, 10)))
+	require.NoError(t, c.Put("b", make([]byte, 10)))
+	require.NoError(t, c.Put("c", make([]byte, 10)))
+	// Using a makes b the least recently used
+	_, ok := c.Get("a")
+	require.True(t, ok)
+	require.NoError(t, c.Put("d", make([]byte, 10)))
+
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.NoFileExists(t, filepath.Join(dir, "b"))
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok := c.Get(key)
+		assert.True(t, ok, key)
+	}
+	assert.Equal(t, int64(30), c.Size())
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 100)
+	require.NoError(t, err)
+	require.NoError(t, c.Put("old", make([]byte, 10)))
+	require.NoError(t, c.Put("new", make([]byte, 10)))
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "old"), past, past))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, tempPrefix+"123"), []byte("partial"), 0600))
+
+	// Reopened smaller, the least recently used file goes
+	c, err = New(dir, 15)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Len())
+	_, ok := c.Get("new")
+	assert.True(t, ok)
+	assert.NoFileExists(t, filepath.Join(dir, "old"))
+	assert.NoFileExists(t, filepath.Join(dir, tempPrefix+"123"))
+}
+
+func TestMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(dir, 100)
+	require.NoError(t, err)
+	require.NoError(t, c.Put("gone", []byte("data")))
+	require.NoError(t, os.Remove(filepath.Join(dir, "gone")))
+
+	_, ok := c.Get("gone")
+	assert.False(t, ok)
+	assert.Zero(t, c.Size())
+}
+
+func TestConcurrentGetPut(t *testing.T) {
+	c, err := New(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for n := range 4 {
+		wg.Go(func() {
+			for i := range 200 {
+				key := fmt.Sprintf("k%d", (n+i)%5)
+				if data, ok := c.Get(key); ok {
+					assert.Equal(t, []byte(key), data)
+				}
+				assert.NoError(t, c.Put(key, []byte(key)))
+			}
+		})
+	}
+	wg.Wait()
+	assert.LessOrEqual(t, c.Size(), int64(10))
+}
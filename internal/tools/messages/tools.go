@@ -114,13 +114,12 @@ func GetMessageText(message *waE2E.Message) (text string, isValid bool) {
 }
 
 func GetQuotedJid(m *events.Message) (jid types.JID, err error) {
-	if m.Message.ExtendedTextMessage != nil {
-		if m.Message.ExtendedTextMessage.ContextInfo.Participant != nil {
-			jidString := *m.Message.ExtendedTextMessage.ContextInfo.Participant
-			jid, err = types.ParseJID(jidString)
-		} else if len(m.Message.ExtendedTextMessage.ContextInfo.MentionedJID) > 0 {
-			jidString := m.Message.ExtendedTextMessage.ContextInfo.MentionedJID[0]
-			jid, err = types.ParseJID(jidString)
+	contextInfo := m.Message.GetExtendedTextMessage().GetContextInfo()
+	if contextInfo != nil {
+		if contextInfo.Participant != nil {
+			jid, err = types.ParseJID(contextInfo.GetParticipant())
+		} else if len(contextInfo.MentionedJID) > 0 {
+			jid, err = types.ParseJID(contextInfo.MentionedJID[0])
 		}
 	}
 	return
@@ -155,3 +154,160 @@ func GetStickerMessage(m *events.Message) *waE2E.Message {
 	}
 	return nil
 }
+
+func GetAudioMessage(m *events.Message) *waE2E.Message {
+	if m.Message.AudioMessage != nil {
+		return m.Message
+	}
+	if m.Message.ExtendedTextMessage != nil && m.Message.ExtendedTextMessage.GetContextInfo().GetQuotedMessage().GetAudioMessage() != nil {
+		return m.Message.ExtendedTextMessage.GetContextInfo().GetQuotedMessage()
+	}
+	return nil
+}
+
+// UnwrapMessage returns the content of view once, document with caption and
+// ephemeral messages, or the message itself if it isn't wrapped.
+func UnwrapMessage(message *waE2E.Message) *waE2E.Message {
+	wrappers := []func() *waE2E.Message{
+		message.GetDocumentWithCaptionMessage().GetMessage,
+		message.GetEphemeralMessage().GetMessage,
+		message.GetViewOnceMessage().GetMessage,
+		message.GetViewOnceMessageV2().GetMessage,
+		message.GetViewOnceMessageV2Extension().GetMessage,
+	}
+	for _, getMsg := range wrappers {
+		if m := getMsg(); m != nil {
+			return UnwrapMessage(m)
+		}
+	}
+	return message
+}
+
+// GetContextInfo returns the context info of the message content, unwrapping
+// view once and document with caption messages.
+func GetContextInfo(message *waE2E.Message) *waE2E.ContextInfo {
+	if message == nil {
+		return nil
+	}
+
+	if m := message.DocumentWithCaptionMessage.GetMessage(); m != nil {
+		return GetContextInfo(m)
+	}
+	if m := message.ViewOnceMessage.GetMessage(); m != nil {
+		return GetContextInfo(m)
+	}
+	if m := message.ViewOnceMessageV2.GetMessage(); m != nil {
+		return GetContextInfo(m)
+	}
+	if m := message.ViewOnceMessageV2Extension.GetMessage(); m != nil {
+		return GetContextInfo(m)
+	}
+
+	contexts := []func() *waE2E.ContextInfo{
+		message.AudioMessage.GetContextInfo,
+		message.ContactMessage.GetContextInfo,
+		message.ContactsArrayMessage.GetContextInfo,
+		message.DocumentMessage.GetContextInfo,
+		message.ExtendedTextMessage.GetContextInfo,
+		message.ImageMessage.GetContextInfo,
+		message.LiveLocationMessage.GetContextInfo,
+		message.LocationMessage.GetContextInfo,
+		message.PollCreationMessage.GetContextInfo,
+		message.PtvMessage.GetContextInfo,
+		message.StickerMessage.GetContextInfo,
+		message.VideoMessage.GetContextInfo,
+	}
+
+	for _, f := range contexts {
+		if c := f(); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// GetMessageType returns a short name for the kind of content in the message.
+func GetMessageType(message *waE2E.Message) string {
+	if message == nil {
+		return ""
+	}
+
+	if m := message.DocumentWithCaptionMessage.GetMessage(); m != nil {
+		return GetMessageType(m)
+	}
+	if m := message.ViewOnceMessage.GetMessage(); m != nil {
+		return GetMessageType(m)
+	}
+	if m := message.ViewOnceMessageV2.GetMessage(); m != nil {
+		return GetMessageType(m)
+	}
+	if m := message.ViewOnceMessageV2Extension.GetMessage(); m != nil {
+		return GetMessageType(m)
+	}
+
+	switch {
+	case message.Conversation != nil, message.ExtendedTextMessage != nil:
+		return "text"
+	case message.ImageMessage != nil:
+		return "image"
+	case message.VideoMessage != nil:
+		if message.VideoMessage.GetGifPlayback() {
+			return "gif"
+		}
+		return "video"
+	case message.PtvMessage != nil:
+		return "ptv"
+	case message.AudioMessage != nil:
+		if message.AudioMessage.GetPTT() {
+			return "ptt"
+		}
+		return "audio"
+	case message.StickerMessage != nil:
+		return "sticker"
+	case message.DocumentMessage != nil:
+		return "document"
+	case message.ContactMessage != nil, message.ContactsArrayMessage != nil:
+		return "contact"
+	case message.LocationMessage != nil, message.LiveLocationMessage != nil:
+		return "location"
+	case message.PollCreationMessage != nil, message.PollCreationMessageV2 != nil, message.PollCreationMessageV3 != nil:
+		return "poll"
+	}
+	return "other"
+}
+
+// GetMediaSHA256 returns the SHA-256 of the media file in the message, if any.
+func GetMediaSHA256(message *waE2E.Message) []byte {
+	if message == nil {
+		return nil
+	}
+
+	if m := message.DocumentWithCaptionMessage.GetMessage(); m != nil {
+		return GetMediaSHA256(m)
+	}
+	if m := message.ViewOnceMessage.GetMessage(); m != nil {
+		return GetMediaSHA256(m)
+	}
+	if m := message.ViewOnceMessageV2.GetMessage(); m != nil {
+		return GetMediaSHA256(m)
+	}
+	if m := message.ViewOnceMessageV2Extension.GetMessage(); m != nil {
+		return GetMediaSHA256(m)
+	}
+
+	switch {
+	case message.ImageMessage != nil:
+		return message.ImageMessage.GetFileSHA256()
+	case message.VideoMessage != nil:
+		return message.VideoMessage.GetFileSHA256()
+	case message.PtvMessage != nil:
+		return message.PtvMessage.GetFileSHA256()
+	case message.AudioMessage != nil:
+		return message.AudioMessage.GetFileSHA256()
+	case message.StickerMessage != nil:
+		return message.StickerMessage.GetFileSHA256()
+	case message.DocumentMessage != nil:
+		return message.DocumentMessage.GetFileSHA256()
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package webp
+
+import "encoding/binary"
+
+// imageSize reads the size of the image in a VP8 or VP8L chunk, and whether
+// a VP8L image uses alpha.
+func imageSize(c Chunk) (width, height int, alpha bool, err error) {
+	switch c.ID {
+	case ChunkVP8:
+		// Frame tag, start code and the 14 bit dimensions of a key frame
+		if len(c.Data) < 10 {
+			return 0, 0, false, malformed("VP8 chunk too short")
+		}
+		if c.Data[0]&1 != 0 {
+			return 0, 0, false, malformed("VP8 image is not a key frame")
+		}
+		if c.Data[3] != 0x9d || c.Data[4] != 0x01 || c.Data[5] != 0x2a {
+			return 0, 0, false, malformed("invalid VP8 start code")
+		}
+		width = int(binary.LittleEndian.Uint16(c.Data[6:8]) & 0x3fff)
+		height = int(binary.LittleEndian.Uint16(c.Data[8:10]) & 0x3fff)
+		if width == 0 || height == 0 {
+			return 0, 0, false, malformed("VP8 image has no size")
+		}
+		return width, height, false, nil
+	case ChunkVP8L:
+		// Signature, then 14 bit dimensions, the alpha bit and the version
+		if len(c.Data) < 5 {
+			return 0, 0, false, malformed("VP8L chunk too short")
+		}
+		if c.Data[0] != 0x2f {
+			return 0, 0, false, malformed("invalid VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(c.Data[1:5])
+		if bits>>29 != 0 {
+			return 0, 0, false, malformed("unknown VP8L version %d", bits>>29)
+		}
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, bits>>28&1 == 1, nil
+	}
+	return 0, 0, false, malformed("%q is not an image chunk", c.ID)
+}
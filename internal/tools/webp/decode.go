@@ -0,0 +1,122 @@
+package webp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"time"
+
+	"golang.org/x/image/draw"
+	xwebp "golang.org/x/image/webp"
+)
+
+const (
+	// MaxDecodedPixels limits the pixels of all the frames of an animation
+	// together, so small files can't take all the memory.
+	MaxDecodedPixels = 1 << 26
+	// minFrameDuration is used for frames without a duration, like browsers do.
+	minFrameDuration = 20 * time.Millisecond
+)
+
+var ErrNotAnimated = errors.New("webp: not an animation")
+var ErrTooLarge = errors.New("webp: image is too large to decode")
+
+// Animation is a decoded animation.
+type Animation struct {
+	Width, Height int
+	LoopCount     int
+	Frames        []AnimationFrame
+}
+
+// AnimationFrame is a frame of an animation, already composited on the canvas.
+type AnimationFrame struct {
+	Image    *image.RGBA
+	Duration time.Duration
+}
+
+// Decode decodes a still WebP, or the first frame of an animation.
+func Decode(data []byte) (image.Image, error) {
+	f, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Animated() {
+		if uint64(f.Width)*uint64(f.Height) > MaxDecodedPixels {
+			return nil, ErrTooLarge
+		}
+		// x/image/webp rejects valid extended files, like VP8L images with
+		// the alpha flag, so only the image chunks are given to it
+		frames, err := f.Frames()
+		if err != nil {
+			return nil, err
+		}
+		return xwebp.Decode(bytes.NewReader(frames[0].Encode()))
+	}
+	anim, err := decodeAnimation(f, 1)
+	if err != nil {
+		return nil, err
+	}
+	return anim.Frames[0].Image, nil
+}
+
+// DecodeAnimation decodes every frame of an animation, which
+// golang.org/x/image/webp doesn't support. The frames are decoded one by one
+// and composited following their blending and disposal methods.
+func DecodeAnimation(data []byte) (*Animation, error) {
+	f, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Animated() {
+		return nil, ErrNotAnimated
+	}
+	return decodeAnimation(f, -1)
+}
+
+// decodeAnimation decodes up to limit frames, or all of them if limit is
+// negative.
+func decodeAnimation(f *File, limit int) (*Animation, error) {
+	frames, err := f.Frames()
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 {
+		frames = frames[:min(len(frames), limit)]
+	}
+	if uint64(f.Width)*uint64(f.Height)*uint64(len(frames)+1) > MaxDecodedPixels {
+		return nil, ErrTooLarge
+	}
+
+	anim := &Animation{Width: f.Width, Height: f.Height, LoopCount: f.LoopCount}
+	canvas := image.NewRGBA(image.Rect(0, 0, f.Width, f.Height))
+	var dispose *image.Rectangle
+	for n, frame := range frames {
+		img, err := xwebp.Decode(bytes.NewReader(frame.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", n, err)
+		}
+
+		if dispose != nil {
+			draw.Draw(canvas, *dispose, image.Transparent, image.Point{}, draw.Src)
+			dispose = nil
+		}
+		rect := image.Rect(frame.X, frame.Y, frame.X+frame.Width, frame.Y+frame.Height)
+		op := draw.Src
+		if frame.Blend {
+			op = draw.Over
+		}
+		draw.Draw(canvas, rect, img, img.Bounds().Min, op)
+		if frame.Dispose {
+			dispose = &rect
+		}
+
+		composited := image.NewRGBA(canvas.Bounds())
+		copy(composited.Pix, canvas.Pix)
+		anim.Frames = append(anim.Frames, AnimationFrame{
+			Image:    composited,
+			Duration: max(frame.Duration, minFrameDuration),
+		})
+	}
+	return anim, nil
+}
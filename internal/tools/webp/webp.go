@@ -0,0 +1,481 @@
+// Package webp reads and writes the RIFF container of WebP files: the
+// VP8/VP8L image chunks, the VP8X extended header, animations (ANIM and ANMF),
+// alpha (ALPH) and the ICCP, EXIF and XMP metadata chunks.
+//
+// Parse validates the structure of a file, so malformed input is reported as
+// an error instead of causing panics later. Encode writes the file back,
+// regenerating the VP8X and ANIM chunks from the File fields.
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+	"time"
+)
+
+// Chunk IDs, padded with spaces to four bytes.
+const (
+	ChunkVP8  = "VP8 "
+	ChunkVP8L = "VP8L"
+	ChunkVP8X = "VP8X"
+	ChunkALPH = "ALPH"
+	ChunkANIM = "ANIM"
+	ChunkANMF = "ANMF"
+	ChunkICCP = "ICCP"
+	ChunkEXIF = "EXIF"
+	ChunkXMP  = "XMP "
+)
+
+// Flags of the VP8X chunk.
+const (
+	FlagAnimation = 1 << 1
+	FlagXMP       = 1 << 2
+	FlagEXIF      = 1 << 3
+	FlagAlpha     = 1 << 4
+	FlagICC       = 1 << 5
+)
+
+const (
+	// MaxCanvasSize is the largest width or height of a canvas.
+	MaxCanvasSize = 1 << 24
+	// maxCanvasArea is the largest width times height of a canvas.
+	maxCanvasArea = 1<<32 - 1
+
+	vp8xSize = 10
+	animSize = 6
+	anmfSize = 16
+)
+
+var (
+	ErrNotWebP   = errors.New("webp: not a WebP file")
+	ErrMalformed = errors.New("webp: malformed file")
+)
+
+// malformed returns an ErrMalformed error with details.
+func malformed(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrMalformed, fmt.Sprintf(format, args...))
+}
+
+// Chunk is a RIFF chunk.
+type Chunk struct {
+	ID   string
+	Data []byte
+}
+
+// File is a parsed WebP file.
+type File struct {
+	// Width and Height are the size of the canvas.
+	Width, Height int
+	// Background and LoopCount come from the ANIM chunk of animations. A
+	// LoopCount of zero loops forever.
+	Background color.RGBA
+	LoopCount  int
+	// Chunks are the chunks of the file, except VP8X and ANIM.
+	Chunks []Chunk
+}
+
+// Frame describes a frame of an animation, or the image of a still file.
+type Frame struct {
+	X, Y, Width, Height int
+	Duration            time.Duration
+	// Blend is set when the frame is alpha-blended over the canvas instead
+	// of replacing it.
+	Blend bool
+	// Dispose is set when the frame is cleared to the background after it
+	// is shown.
+	Dispose bool
+	// Chunks are the ALPH and VP8 or VP8L chunks of the frame.
+	Chunks []Chunk
+}
+
+// Parse parses and validates a WebP file. The chunks of the file share
+// memory with data.
+func Parse(data []byte) (*File, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrNotWebP
+	}
+	size := uint64(binary.LittleEndian.Uint32(data[4:8])) + 8
+	if size < 12 || size > uint64(len(data)) {
+		return nil, malformed("RIFF size %d doesn't match the file size %d", size, len(data))
+	}
+	chunks, err := ReadChunks(data[12:size])
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, malformed("no chunks")
+	}
+
+	f := &File{}
+	switch chunks[0].ID {
+	case ChunkVP8, ChunkVP8L:
+		for _, c := range chunks[1:] {
+			switch c.ID {
+			case ChunkVP8, ChunkVP8L, ChunkVP8X, ChunkALPH, ChunkANIM, ChunkANMF:
+				return nil, malformed("simple file with a %q chunk", c.ID)
+			}
+		}
+		if f.Width, f.Height, _, err = imageSize(chunks[0]); err != nil {
+			return nil, err
+		}
+		f.Chunks = chunks
+		return f, nil
+	case ChunkVP8X:
+	default:
+		return nil, malformed("unexpected first chunk %q", chunks[0].ID)
+	}
+
+	header := chunks[0].Data
+	if len(header) < vp8xSize {
+		return nil, malformed("VP8X chunk too short")
+	}
+	flags := header[0]
+	f.Width = int(u24(header[4:7])) + 1
+	f.Height = int(u24(header[7:10])) + 1
+	if uint64(f.Width)*uint64(f.Height) > maxCanvasArea {
+		return nil, malformed("canvas %dx%d is too large", f.Width, f.Height)
+	}
+
+	var hasAnim, hasImage bool
+	for _, c := range chunks[1:] {
+		switch c.ID {
+		case ChunkVP8X:
+			return nil, malformed("duplicate VP8X chunk")
+		case ChunkANIM:
+			if hasAnim {
+				return nil, malformed("duplicate ANIM chunk")
+			}
+			if len(c.Data) < animSize {
+				return nil, malformed("ANIM chunk too short")
+			}
+			hasAnim = true
+			f.Background = color.RGBA{B: c.Data[0], G: c.Data[1], R: c.Data[2], A: c.Data[3]}
+			f.LoopCount = int(binary.LittleEndian.Uint16(c.Data[4:6]))
+		case ChunkVP8, ChunkVP8L:
+			hasImage = true
+			fallthrough
+		default:
+			f.Chunks = append(f.Chunks, c)
+		}
+	}
+
+	animated := flags&FlagAnimation != 0
+	hasFrames := slices.ContainsFunc(f.Chunks, func(c Chunk) bool { return c.ID == ChunkANMF })
+	switch {
+	case animated && (!hasAnim || !hasFrames):
+		return nil, malformed("animation flag without ANIM and ANMF chunks")
+	case !animated && (hasAnim || hasFrames):
+		return nil, malformed("animation chunks without the animation flag")
+	case animated && hasImage:
+		return nil, malformed("animation with a still image chunk")
+	case !animated && !hasImage:
+		return nil, malformed("no image chunk")
+	}
+
+	frames, err := f.Frames()
+	if err != nil {
+		return nil, err
+	}
+	if !animated && (frames[0].Width != f.Width || frames[0].Height != f.Height) {
+		return nil, malformed("canvas %dx%d doesn't match the image %dx%d", f.Width, f.Height, frames[0].Width, frames[0].Height)
+	}
+	return f, nil
+}
+
+// ReadChunks reads a sequence of chunks, like the contents of a RIFF file or
+// the frame data of an ANMF chunk.
+func ReadChunks(data []byte) ([]Chunk, error) {
+	var chunks []Chunk
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < 8 {
+			return nil, malformed("truncated chunk header at %d", offset)
+		}
+		id := string(data[offset : offset+4])
+		for _, b := range []byte(id) {
+			if b < 32 || b > 126 {
+				return nil, malformed("invalid chunk ID %q", id)
+			}
+		}
+		size := uint64(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		offset += 8
+		if size > uint64(len(data)-offset) {
+			return nil, malformed("%q chunk size %d exceeds the data", id, size)
+		}
+		chunks = append(chunks, Chunk{ID: id, Data: data[offset : offset+int(size)]})
+		offset += int(size)
+		// Chunks are padded to an even size, but the last padding byte is
+		// often missing
+		if size%2 != 0 && offset < len(data) {
+			offset++
+		}
+	}
+	return chunks, nil
+}
+
+// Animated reports whether the file is an animation.
+func (f *File) Animated() bool {
+	return slices.ContainsFunc(f.Chunks, func(c Chunk) bool { return c.ID == ChunkANMF })
+}
+
+// Chunk returns the data of the first chunk with the ID, or nil.
+func (f *File) Chunk(id string) []byte {
+	if idx := slices.IndexFunc(f.Chunks, func(c Chunk) bool { return c.ID == id }); idx >= 0 {
+		return f.Chunks[idx].Data
+	}
+	return nil
+}
+
+// SetChunk replaces the chunks with the ID by one with the data, which is
+// typically used for the ICCP, EXIF and XMP chunks.
+func (f *File) SetChunk(id string, data []byte) {
+	f.RemoveChunk(id)
+	f.Chunks = append(f.Chunks, Chunk{ID: id, Data: data})
+}
+
+// RemoveChunk removes the chunks with the ID.
+func (f *File) RemoveChunk(id string) {
+	f.Chunks = slices.DeleteFunc(f.Chunks, func(c Chunk) bool { return c.ID == id })
+}
+
+// Flags returns the VP8X flags that describe the chunks of the file.
+func (f *File) Flags() byte {
+	var flags byte
+	for _, c := range f.Chunks {
+		switch c.ID {
+		case ChunkANMF:
+			flags |= FlagAnimation
+			if frame, err := parseFrame(c); err == nil && frame.hasAlpha() {
+				flags |= FlagAlpha
+			}
+		case ChunkALPH:
+			flags |= FlagAlpha
+		case ChunkVP8L:
+			if _, _, alpha, err := imageSize(c); err == nil && alpha {
+				flags |= FlagAlpha
+			}
+		case ChunkICCP:
+			flags |= FlagICC
+		case ChunkEXIF:
+			flags |= FlagEXIF
+		case ChunkXMP:
+			flags |= FlagXMP
+		}
+	}
+	return flags
+}
+
+// Frames returns the frames of an animation, or a single frame with the
+// image of a still file.
+func (f *File) Frames() ([]Frame, error) {
+	if !f.Animated() {
+		var frame Frame
+		for _, c := range f.Chunks {
+			switch c.ID {
+			case ChunkALPH:
+				frame.Chunks = append(frame.Chunks, c)
+			case ChunkVP8, ChunkVP8L:
+				frame.Chunks = append(frame.Chunks, c)
+				w, h, _, err := imageSize(c)
+				if err != nil {
+					return nil, err
+				}
+				frame.Width, frame.Height = w, h
+				return []Frame{frame}, validateFrameChunks(frame.Chunks)
+			}
+		}
+		return nil, malformed("no image chunk")
+	}
+
+	var frames []Frame
+	for _, c := range f.Chunks {
+		if c.ID != ChunkANMF {
+			continue
+		}
+		frame, err := parseFrame(c)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", len(frames), err)
+		}
+		if frame.X+frame.Width > f.Width || frame.Y+frame.Height > f.Height {
+			return nil, malformed("frame %d at %d,%d with size %dx%d is outside of the %dx%d canvas", len(frames), frame.X, frame.Y, frame.Width, frame.Height, f.Width, f.Height)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+func parseFrame(c Chunk) (Frame, error) {
+	if len(c.Data) < anmfSize {
+		return Frame{}, malformed("ANMF chunk too short")
+	}
+	frame := Frame{
+		X:        int(u24(c.Data[0:3])) * 2,
+		Y:        int(u24(c.Data[3:6])) * 2,
+		Width:    int(u24(c.Data[6:9])) + 1,
+		Height:   int(u24(c.Data[9:12])) + 1,
+		Duration: time.Duration(u24(c.Data[12:15])) * time.Millisecond,
+		Blend:    c.Data[15]&0b10 == 0,
+		Dispose:  c.Data[15]&0b1 != 0,
+	}
+	chunks, err := ReadChunks(c.Data[anmfSize:])
+	if err != nil {
+		return Frame{}, err
+	}
+	for _, sub := range chunks {
+		switch sub.ID {
+		case ChunkALPH, ChunkVP8, ChunkVP8L:
+			frame.Chunks = append(frame.Chunks, sub)
+		}
+	}
+	if err := validateFrameChunks(frame.Chunks); err != nil {
+		return Frame{}, err
+	}
+	w, h, _, err := imageSize(frame.Chunks[len(frame.Chunks)-1])
+	if err != nil {
+		return Frame{}, err
+	}
+	if w != frame.Width || h != frame.Height {
+		return Frame{}, malformed("frame size %dx%d doesn't match the image %dx%d", frame.Width, frame.Height, w, h)
+	}
+	return frame, nil
+}
+
+// validateFrameChunks checks that the chunks of an image are an optional
+// ALPH followed by VP8, or a single VP8L.
+func validateFrameChunks(chunks []Chunk) error {
+	switch {
+	case len(chunks) == 1 && (chunks[0].ID == ChunkVP8 || chunks[0].ID == ChunkVP8L):
+		return nil
+	case len(chunks) == 2 && chunks[0].ID == ChunkALPH && chunks[1].ID == ChunkVP8:
+		return nil
+	}
+	ids := make([]string, len(chunks))
+	for i, c := range chunks {
+		ids[i] = c.ID
+	}
+	return malformed("invalid image chunks %q", ids)
+}
+
+func (fr *Frame) hasAlpha() bool {
+	for _, c := range fr.Chunks {
+		if c.ID == ChunkALPH {
+			return true
+		}
+		if _, _, alpha, err := imageSize(c); err == nil && alpha {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode returns the image of the frame as a still WebP file.
+func (fr *Frame) Encode() []byte {
+	chunks := fr.Chunks
+	if len(chunks) > 0 && chunks[0].ID == ChunkALPH {
+		header := make([]byte, vp8xSize)
+		header[0] = FlagAlpha
+		putU24(header[4:7], uint32(fr.Width-1))
+		putU24(header[7:10], uint32(fr.Height-1))
+		chunks = append([]Chunk{{ID: ChunkVP8X, Data: header}}, chunks...)
+	}
+	return writeRIFF(chunks)
+}
+
+// chunkOrder is the position of the chunks in the files written by Encode,
+// following the specification. Unknown chunks go after the image data.
+var chunkOrder = map[string]int{
+	ChunkVP8X: 0,
+	ChunkICCP: 1,
+	ChunkANIM: 2,
+	ChunkALPH: 3, ChunkVP8: 3, ChunkVP8L: 3, ChunkANMF: 3,
+	ChunkEXIF: 5,
+	ChunkXMP:  6,
+}
+
+func orderOf(id string) int {
+	if order, ok := chunkOrder[id]; ok {
+		return order
+	}
+	return 4
+}
+
+// Encode writes the file. The VP8X chunk is only written when the file uses
+// extended features, with flags computed from the chunks.
+func (f *File) Encode() ([]byte, error) {
+	if f.Width < 1 || f.Height < 1 || f.Width > MaxCanvasSize || f.Height > MaxCanvasSize || uint64(f.Width)*uint64(f.Height) > maxCanvasArea {
+		return nil, malformed("invalid canvas %dx%d", f.Width, f.Height)
+	}
+	chunks := slices.DeleteFunc(slices.Clone(f.Chunks), func(c Chunk) bool {
+		return c.ID == ChunkVP8X || c.ID == ChunkANIM
+	})
+	for _, c := range chunks {
+		if len(c.ID) != 4 {
+			return nil, malformed("invalid chunk ID %q", c.ID)
+		}
+	}
+
+	flags := f.Flags()
+	alphaOnly := flags == FlagAlpha && !slices.ContainsFunc(chunks, func(c Chunk) bool { return c.ID == ChunkALPH })
+	if flags != 0 && !alphaOnly {
+		header := make([]byte, vp8xSize)
+		header[0] = flags
+		putU24(header[4:7], uint32(f.Width-1))
+		putU24(header[7:10], uint32(f.Height-1))
+		chunks = append(chunks, Chunk{ID: ChunkVP8X, Data: header})
+	}
+	if flags&FlagAnimation != 0 {
+		anim := []byte{f.Background.B, f.Background.G, f.Background.R, f.Background.A, 0, 0}
+		binary.LittleEndian.PutUint16(anim[4:6], uint16(f.LoopCount))
+		chunks = append(chunks, Chunk{ID: ChunkANIM, Data: anim})
+	}
+	slices.SortStableFunc(chunks, func(a, b Chunk) int { return orderOf(a.ID) - orderOf(b.ID) })
+	return writeRIFF(chunks), nil
+}
+
+// writeRIFF writes the chunks in a RIFF WEBP container.
+func writeRIFF(chunks []Chunk) []byte {
+	size := 12
+	for _, c := range chunks {
+		size += 8 + len(c.Data) + len(c.Data)%2
+	}
+	data := make([]byte, 0, size)
+	data = append(data, "RIFF\x00\x00\x00\x00WEBP"...)
+	for _, c := range chunks {
+		data = append(data, c.ID...)
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(c.Data)))
+		data = append(data, c.Data...)
+		if len(c.Data)%2 != 0 {
+			data = append(data, 0)
+		}
+	}
+	binary.LittleEndian.PutUint32(data[4:8], uint32(len(data)-8))
+	return data
+}
+
+// NewANMF builds an ANMF chunk for a frame.
+func NewANMF(frame Frame) Chunk {
+	data := make([]byte, anmfSize)
+	putU24(data[0:3], uint32(frame.X/2))
+	putU24(data[3:6], uint32(frame.Y/2))
+	putU24(data[6:9], uint32(frame.Width-1))
+	putU24(data[9:12], uint32(frame.Height-1))
+	putU24(data[12:15], uint32(frame.Duration/time.Millisecond))
+	if !frame.Blend {
+		data[15] |= 0b10
+	}
+	if frame.Dispose {
+		data[15] |= 0b1
+	}
+	data = append(data, writeRIFF(frame.Chunks)[12:]...)
+	return Chunk{ID: ChunkANMF, Data: data}
+}
+
+func u24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+func putU24(b []byte, n uint32) {
+	b[0], b[1], b[2] = byte(n), byte(n>>8), byte(n>>16)
+}
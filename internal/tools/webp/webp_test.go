@@ -0,0 +1,201 @@
+package webp
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// describe dumps what Parse understood of a file, or the error it returned.
+func describe(data []byte) string {
+	f, err := Parse(data)
+	if err != nil {
+		return "error: " + err.Error() + "\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "canvas %dx%d flags %06b", f.Width, f.Height, f.Flags())
+	if f.Animated() {
+		fmt.Fprintf(&b, " loop %d background %v", f.LoopCount, f.Background)
+	}
+	b.WriteString("\n")
+	for _, c := range f.Chunks {
+		fmt.Fprintf(&b, "%q %d\n", c.ID, len(c.Data))
+	}
+	frames, err := f.Frames()
+	if err != nil {
+		return b.String() + "frames error: " + err.Error() + "\n"
+	}
+	for n, frame := range frames {
+		fmt.Fprintf(&b, "frame %d: %dx%d at %d,%d %v blend=%t dispose=%t", n, frame.Width, frame.Height, frame.X, frame.Y, frame.Duration, frame.Blend, frame.Dispose)
+		for _, c := range frame.Chunks {
+			fmt.Fprintf(&b, " %q", c.ID)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.webp")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+			got := describe(data)
+
+			golden := strings.TrimSuffix(file, ".webp") + ".golden"
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(got), 0644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), got)
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, name := range []string{"lossless", "lossy", "lossy_alpha", "metadata", "animated"} {
+		data, err := os.ReadFile("testdata/" + name + ".webp")
+		require.NoError(t, err)
+		f, err := Parse(data)
+		require.NoError(t, err)
+		encoded, err := f.Encode()
+		require.NoError(t, err)
+		assert.Equal(t, data, encoded, name)
+	}
+}
+
+func TestChunks(t *testing.T) {
+	data, err := os.ReadFile("testdata/lossless.webp")
+	require.NoError(t, err)
+	f, err := Parse(data)
+	require.NoError(t, err)
+	assert.Nil(t, f.Chunk(ChunkEXIF))
+	assert.Equal(t, byte(FlagAlpha), f.Flags())
+
+	f.SetChunk(ChunkEXIF, []byte("exif"))
+	f.SetChunk(ChunkEXIF, []byte("new exif"))
+	assert.Equal(t, []byte("new exif"), f.Chunk(ChunkEXIF))
+	assert.Equal(t, byte(FlagEXIF|FlagAlpha), f.Flags())
+
+	encoded, err := f.Encode()
+	require.NoError(t, err)
+	parsed, err := Parse(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("new exif"), parsed.Chunk(ChunkEXIF))
+	assert.Equal(t, ChunkVP8X, string(encoded[12:16]))
+
+	parsed.RemoveChunk(ChunkEXIF)
+	encoded, err = parsed.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, data, encoded)
+
+	_, err = (&File{Chunks: f.Chunks}).Encode()
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestDecode(t *testing.T) {
+	for _, name := range []string{"lossless", "lossy", "lossy_alpha", "metadata", "animated"} {
+		data, err := os.ReadFile("testdata/" + name + ".webp")
+		require.NoError(t, err)
+		img, err := Decode(data)
+		require.NoError(t, err, name)
+		f, err := Parse(data)
+		require.NoError(t, err)
+		assert.Equal(t, f.Width, img.Bounds().Dx(), name)
+		assert.Equal(t, f.Height, img.Bounds().Dy(), name)
+	}
+
+	_, err := DecodeAnimation(mustRead(t, "testdata/lossless.webp"))
+	assert.ErrorIs(t, err, ErrNotAnimated)
+	_, err = Decode(mustRead(t, "testdata/short_vp8.webp"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestDecodeAnimation(t *testing.T) {
+	anim, err := DecodeAnimation(mustRead(t, "testdata/animated.webp"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, anim.Width)
+	assert.Equal(t, 4, anim.Height)
+	assert.Equal(t, 3, anim.LoopCount)
+	require.Len(t, anim.Frames, 3)
+	assert.Equal(t, minFrameDuration, anim.Frames[1].Duration)
+
+	assert.Equal(t, uint8(255), anim.Frames[0].Image.RGBAAt(0, 0).A)
+	// The first frame is disposed before the second is drawn
+	assert.Equal(t, uint8(0), anim.Frames[1].Image.RGBAAt(0, 0).A)
+	assert.Equal(t, uint8(255), anim.Frames[1].Image.RGBAAt(2, 2).A)
+	// The third frame replaces the pixel instead of blending
+	assert.Equal(t, uint8(0), anim.Frames[2].Image.RGBAAt(2, 2).A)
+}
+
+func mustRead(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(name)
+	require.NoError(t, err)
+	return data
+}
+
+// addSeeds adds the test files to the seed corpus of a fuzz test.
+func addSeeds(f *testing.F) {
+	files, err := filepath.Glob("testdata/*.webp")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		file, err := Parse(data)
+		if err != nil {
+			return
+		}
+		frames, err := file.Frames()
+		require.NoError(t, err)
+
+		// Whatever parses must survive a round trip
+		encoded, err := file.Encode()
+		require.NoError(t, err)
+		parsed, err := Parse(encoded)
+		require.NoError(t, err)
+		assert.Equal(t, file.Width, parsed.Width)
+		assert.Equal(t, file.Height, parsed.Height)
+		reparsed, err := parsed.Frames()
+		require.NoError(t, err)
+		assert.Equal(t, len(frames), len(reparsed))
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	addSeeds(f)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		img, err := Decode(data)
+		if err == nil {
+			assert.False(t, img.Bounds().Empty())
+		}
+		if anim, err := DecodeAnimation(data); err == nil {
+			assert.NotEmpty(t, anim.Frames)
+		}
+	})
+}
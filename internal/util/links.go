@@ -0,0 +1,106 @@
+package util
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"mvdan.cc/xurls/v2"
+)
+
+// ShortenersEntry is a special domain list entry matching every known URL
+// shortener, whose real destination can't be checked.
+const ShortenersEntry = "shorteners"
+
+var shorteners = []string{
+	"bit.ly", "bitly.com", "cutt.ly", "encurtador.com.br", "goo.gl", "is.gd", "ow.ly", "rb.gy",
+	"rebrand.ly", "shorturl.at", "s.id", "t.co", "t.ly", "tiny.cc", "tinyurl.com", "v.gd",
+}
+
+var inviteCodeRegex = regexp.MustCompile(`(?i)(?:https?:\/\/)?chat\.whatsapp\.com\/+(?:invite\/)?(\w{20,24})`)
+
+type Link struct {
+	Raw  string
+	Host string
+	Path string
+}
+
+// ExtractLinks returns every non-email URL in s with its host normalized:
+// lowercased, converted to punycode and without the "www." prefix. Links to
+// a WhatsApp chat (api.whatsapp.com/send, whatsapp.com/send) are reported
+// with the wa.me host.
+func ExtractLinks(s string) []Link {
+	rx := xurls.Relaxed()
+	idxEmail := rx.SubexpIndex("relaxedEmail")
+	var links []Link
+	for _, match := range rx.FindAllStringSubmatch(s, -1) {
+		if match[idxEmail] != "" {
+			continue
+		}
+		raw := match[0]
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		host := NormalizeDomain(u.Hostname())
+		if (host == "api.whatsapp.com" || host == "whatsapp.com") && strings.HasPrefix(u.Path, "/send") {
+			host = "wa.me"
+		}
+		links = append(links, Link{Raw: match[0], Host: host, Path: u.Path})
+	}
+	return links
+}
+
+// NormalizeDomain returns the canonical form of a domain or domain pattern.
+func NormalizeDomain(domain string) string {
+	domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+	wildcard := strings.HasPrefix(domain, "*.")
+	domain = strings.TrimPrefix(domain, "*.")
+	domain = strings.TrimPrefix(domain, "www.")
+	if ascii, err := idna.Lookup.ToASCII(domain); err == nil {
+		domain = ascii
+	}
+	if wildcard {
+		return "*." + domain
+	}
+	return domain
+}
+
+// IsShortener reports whether host belongs to a known URL shortener.
+func IsShortener(host string) bool {
+	return slices.ContainsFunc(shorteners, func(s string) bool {
+		return host == s || strings.HasSuffix(host, "."+s)
+	})
+}
+
+// MatchDomain reports whether a normalized host matches a domain pattern.
+// "example.com" only matches the domain itself, while "*.example.com" also
+// matches any of its subdomains.
+func MatchDomain(pattern string, host string) bool {
+	if pattern == ShortenersEntry {
+		return IsShortener(host)
+	}
+	if rest, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == rest || strings.HasSuffix(host, "."+rest)
+	}
+	return host == pattern
+}
+
+// MatchAnyDomain reports whether host matches any of the domain patterns.
+func MatchAnyDomain(patterns []string, host string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return MatchDomain(p, host) })
+}
+
+// InviteCodes returns the codes of the WhatsApp group invite links in s.
+func InviteCodes(s string) []string {
+	var codes []string
+	for _, match := range inviteCodeRegex.FindAllStringSubmatch(s, -1) {
+		codes = append(codes, match[1])
+	}
+	return codes
+}
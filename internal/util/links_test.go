@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractLinks(t *testing.T) {
+	links := ExtractLinks("veja WWW.YouTube.com/watch?v=1, https://api.whatsapp.com/send?phone=55 e bücher.de ou mail@example.com")
+	hosts := make([]string, len(links))
+	for i, l := range links {
+		hosts[i] = l.Host
+	}
+	assert.Equal(t, []string{"youtube.com", "wa.me", "xn--bcher-kva.de"}, hosts)
+}
+
+func TestMatchDomain(t *testing.T) {
+	assert.True(t, MatchDomain("youtube.com", "youtube.com"))
+	assert.False(t, MatchDomain("youtube.com", "m.youtube.com"))
+	assert.True(t, MatchDomain("*.youtube.com", "m.youtube.com"))
+	assert.True(t, MatchDomain("*.youtube.com", "youtube.com"))
+	assert.False(t, MatchDomain("*.youtube.com", "notyoutube.com"))
+	assert.True(t, MatchDomain(ShortenersEntry, "bit.ly"))
+	assert.False(t, MatchDomain(ShortenersEntry, "youtube.com"))
+	assert.Equal(t, "*.xn--bcher-kva.de", NormalizeDomain("*.Bücher.de."))
+}
+
+func TestInviteCodes(t *testing.T) {
+	codes := InviteCodes("entra ai https://chat.whatsapp.com/AbCdEfGhIjKlMnOpQrStUv e chat.whatsapp.com/invite/ZyXwVuTsRqPoNmLkJiHgFe")
+	assert.Equal(t, []string{"AbCdEfGhIjKlMnOpQrStUv", "ZyXwVuTsRqPoNmLkJiHgFe"}, codes)
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"regexp"
+	"time"
+)
+
+var placeholderRegex = regexp.MustCompile(`\$([a-zA-Z]+)`)
+
+// ExpandPlaceholders replaces every $key in s with its value. Placeholders
+// without a value are left untouched.
+func ExpandPlaceholders(s string, values map[string]string) string {
+	return placeholderRegex.ReplaceAllStringFunc(s, func(match string) string {
+		if value, ok := values[match[1:]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// TimePlaceholders returns the $hour and $date placeholders for the given time.
+func TimePlaceholders(now time.Time) map[string]string {
+	return map[string]string{
+		"hour": now.Format(time.Kitchen),
+		"date": now.Format("02/01/2006"),
+	}
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandPlaceholders(t *testing.T) {
+	values := map[string]string{
+		"name":  "Maria",
+		"group": "Amigos",
+		"count": "42",
+	}
+	assert.Equal(t, "Olá Maria, bem-vinda ao Amigos (42)", ExpandPlaceholders("Olá $name, bem-vinda ao $group ($count)", values))
+	assert.Equal(t, "Custa $5 e $unknown", ExpandPlaceholders("Custa $5 e $unknown", values))
+	assert.Equal(t, "Maria!", ExpandPlaceholders("$name!", values))
+	assert.Equal(t, "sem placeholders", ExpandPlaceholders("sem placeholders", nil))
+}
+
+func TestTimePlaceholders(t *testing.T) {
+	values := TimePlaceholders(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC))
+	assert.Equal(t, "3:04PM", values["hour"])
+	assert.Equal(t, "02/01/2006", values["date"])
+}
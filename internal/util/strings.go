@@ -39,3 +39,26 @@ func NormalizeString(s string) string {
 	}
 	return b.String()
 }
+
+// ParseToggle interprets an on/off argument. An empty argument flips the
+// current value. The second return value is false if the argument is invalid.
+func ParseToggle(arg string, current bool) (bool, bool) {
+	switch NormalizeString(strings.ToLower(strings.TrimSpace(arg))) {
+	case "":
+		return !current, true
+	case "on", "1", "true", "enable", "ativar", "ligar", "sim":
+		return true, true
+	case "off", "0", "false", "disable", "desativar", "desligar", "nao":
+		return false, true
+	}
+	return current, false
+}
+
+// Truncate shortens s to at most n runes, appending an ellipsis when cut.
+func Truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
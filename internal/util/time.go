@@ -0,0 +1,113 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationRegex = regexp.MustCompile(`^(?:(\d+)(w|d|h|m|s))+$`)
+var durationPartRegex = regexp.MustCompile(`(\d+)(w|d|h|m|s)`)
+var clockRegex = regexp.MustCompile(`^(\d{1,2})(?:[:h](\d{2})?)?$`)
+var timestampRegex = regexp.MustCompile(`^(?:(?:(\d+):)?(\d{1,2}):)?(\d+(?:[.,]\d{1,3})?)$`)
+
+var durationUnits = map[string]time.Duration{
+	"w": 7 * 24 * time.Hour,
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+// ParseDuration parses durations like "10m", "2h30m" or "1w2d", accepting
+// days and weeks in addition to the units of time.ParseDuration.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if !durationRegex.MatchString(s) {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for _, part := range durationPartRegex.FindAllStringSubmatch(s, -1) {
+		n, err := strconv.Atoi(part[1])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * durationUnits[part[2]]
+	}
+	return d, nil
+}
+
+// FormatDuration formats a duration using the largest units, e.g. "1d2h".
+func FormatDuration(d time.Duration) string {
+	if d < time.Second {
+		return "0s"
+	}
+	var b strings.Builder
+	for _, unit := range []string{"w", "d", "h", "m", "s"} {
+		if n := d / durationUnits[unit]; n > 0 {
+			b.WriteString(strconv.FormatInt(int64(n), 10))
+			b.WriteString(unit)
+			d -= n * durationUnits[unit]
+		}
+	}
+	return b.String()
+}
+
+// ParseClock parses a time of day like "22:00", "7h" or "07h30" into minutes
+// after midnight.
+func ParseClock(s string) (int, error) {
+	m := clockRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	var minute int
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if hour > 23 || minute > 59 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return hour*60 + minute, nil
+}
+
+// FormatClock formats minutes after midnight as "HH:MM".
+func FormatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// ParseTimestamp parses a position in a media file like "90", "1:30",
+// "1:02:03", "12.5" or "1m30s". Seconds can't reach 60 after a colon.
+func ParseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if d, err := ParseDuration(s); err == nil {
+		return d, nil
+	}
+	m := timestampRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	hours, _ := strconv.Atoi(m[1])
+	minutes, _ := strconv.Atoi(m[2])
+	seconds, err := strconv.ParseFloat(strings.Replace(m[3], ",", ".", 1), 64)
+	if err != nil || m[2] != "" && (seconds >= 60 || m[1] != "" && minutes >= 60) {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds*float64(time.Second))
+	if d < 0 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	return d, nil
+}
+
+// FormatTimestamp formats a position in a media file as "m:ss" or "h:mm:ss".
+func FormatTimestamp(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d/time.Hour), int(d/time.Minute%60), int(d/time.Second%60)
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
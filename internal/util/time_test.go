@@ -0,0 +1,55 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseClock(t *testing.T) {
+	for input, want := range map[string]int{
+		"22:00": 22 * 60,
+		"7:30":  7*60 + 30,
+		"07h30": 7*60 + 30,
+		"7h":    7 * 60,
+		"0":     0,
+		"23:59": 23*60 + 59,
+	} {
+		got, err := ParseClock(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "24:00", "12:60", "7:5", "noon", "-1"} {
+		_, err := ParseClock(input)
+		assert.Error(t, err, input)
+	}
+
+	assert.Equal(t, "07:05", FormatClock(7*60+5))
+}
+
+func TestParseTimestamp(t *testing.T) {
+	for input, want := range map[string]time.Duration{
+		"90":       90 * time.Second,
+		"1:30":     90 * time.Second,
+		"01:02:03": time.Hour + 2*time.Minute + 3*time.Second,
+		"12.5":     12500 * time.Millisecond,
+		"0:07,25":  7250 * time.Millisecond,
+		"1m30s":    90 * time.Second,
+		"0":        0,
+	} {
+		got, err := ParseTimestamp(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"", "1:60", "1:60:00", "-5", "abc", "1:2:3:4", "1.2345"} {
+		_, err := ParseTimestamp(input)
+		assert.Error(t, err, input)
+	}
+
+	assert.Equal(t, "1:05", FormatTimestamp(65*time.Second))
+	assert.Equal(t, "1:00:00", FormatTimestamp(time.Hour))
+	assert.Equal(t, "0:00", FormatTimestamp(0))
+}
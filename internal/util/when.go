@@ -0,0 +1,293 @@
+package util
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNoTime = errors.New("no time found")
+var ErrPastTime = errors.New("time is in the past")
+
+var fieldRegex = regexp.MustCompile(`\S+`)
+var clock12Regex = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+var dateRegex = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{2}|\d{4}))?$`)
+var isoDateRegex = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+
+// defaultHour is the time of day used when only a date is given.
+const defaultHour = 9
+
+var durationWords = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"segundo": time.Second, "segundos": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"minuto": time.Minute, "minutos": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"hora": time.Hour, "horas": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour, "dia": 24 * time.Hour, "dias": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+	"semana": 7 * 24 * time.Hour, "semanas": 7 * 24 * time.Hour,
+}
+
+var weekdayWords = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday, "domingo": time.Sunday, "dom": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday, "segunda": time.Monday, "segunda-feira": time.Monday, "seg": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "terça": time.Tuesday, "terca": time.Tuesday, "terça-feira": time.Tuesday, "terca-feira": time.Tuesday, "ter": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "quarta": time.Wednesday, "quarta-feira": time.Wednesday, "qua": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "quinta": time.Thursday, "quinta-feira": time.Thursday, "qui": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday, "sexta": time.Friday, "sexta-feira": time.Friday, "sex": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday, "sábado": time.Saturday, "sabado": time.Saturday, "sab": time.Saturday,
+}
+
+var dayOffsetWords = map[string]int{
+	"today": 0, "hoje": 0,
+	"tomorrow": 1, "amanhã": 1, "amanha": 1,
+}
+
+var namedClocks = map[string]int{
+	"noon": 12 * 60, "meio-dia": 12 * 60, "midnight": 0, "meia-noite": 0,
+}
+
+// fillerWords are skipped between the parts of a time, e.g. "next monday at 9am".
+var fillerWords = map[string]bool{
+	"at": true, "on": true, "next": true, "às": true, "as": true, "na": true, "no": true,
+	"próxima": true, "proxima": true, "próximo": true, "proximo": true, "de": true,
+}
+
+// ParseWhen parses the time at the start of s and returns it with the rest of
+// s. It understands relative times like "2h", "1h30m", "in 2 hours" or "em 10
+// minutos", and absolute ones like "tomorrow 9am", "amanhã 14h", "friday
+// 18:00", "25/12 10:00" or "2025-12-25". Absolute times are interpreted in the
+// location of now.
+func ParseWhen(s string, now time.Time) (time.Time, string, error) {
+	fields := fieldRegex.FindAllStringIndex(s, -1)
+	words := make([]string, len(fields))
+	for i, f := range fields {
+		words[i] = strings.ToLower(s[f[0]:f[1]])
+	}
+	rest := func(n int) string {
+		if n >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(s[fields[n][0]:])
+	}
+
+	if d, n := parseRelative(words); n > 0 {
+		return now.Add(d), rest(n), nil
+	}
+	t, n, err := parseAbsolute(words, now)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return t, rest(n), nil
+}
+
+// parseRelative parses a duration at the start of words, returning the
+// duration and how many words it used.
+func parseRelative(words []string) (time.Duration, int) {
+	var d time.Duration
+	var n int
+	start := 0
+	if len(words) > 0 && (words[0] == "in" || words[0] == "em" || words[0] == "daqui") {
+		start = 1
+		if words[0] == "daqui" && len(words) > 1 && words[1] == "a" {
+			start = 2
+		}
+	}
+	for i := start; i < len(words); {
+		if parsed, err := ParseDuration(words[i]); err == nil {
+			d += parsed
+			i++
+			n = i
+			continue
+		}
+		count, err := strconv.Atoi(words[i])
+		if err != nil || count <= 0 || i+1 >= len(words) {
+			break
+		}
+		unit, ok := durationWords[strings.TrimRight(words[i+1], ".,")]
+		if !ok {
+			break
+		}
+		d += time.Duration(count) * unit
+		i += 2
+		n = i
+		// "2 hours and 30 minutes", "2 horas e 30 minutos"
+		if i+1 < len(words) && (words[i] == "and" || words[i] == "e") {
+			if _, err := strconv.Atoi(words[i+1]); err == nil {
+				i++
+			}
+		}
+	}
+	if d <= 0 {
+		return 0, 0
+	}
+	return d, n
+}
+
+// parseAbsolute parses a date and time of day at the start of words.
+func parseAbsolute(words []string, now time.Time) (time.Time, int, error) {
+	loc := now.Location()
+	var date time.Time
+	var weekday *time.Weekday
+	clock := -1
+	var n int
+
+	for i := 0; i < len(words); i++ {
+		word := strings.TrimRight(words[i], ".,")
+		offset, isOffset := dayOffsetWords[word]
+		wd, isWeekday := ParseWeekday(word)
+		hasDate := !date.IsZero() || weekday != nil
+		switch {
+		case fillerWords[word]:
+			continue
+		case !hasDate && isOffset:
+			date = time.Date(now.Year(), now.Month(), now.Day()+offset, 0, 0, 0, 0, loc)
+		case !hasDate && isWeekday:
+			weekday = &wd
+		case !hasDate && parseDate(word, now) != nil:
+			date = *parseDate(word, now)
+		case clock < 0:
+			c, used := parseClockWords(words[i:])
+			if used == 0 {
+				return finishAbsolute(date, weekday, clock, n, now)
+			}
+			clock = c
+			i += used - 1
+		default:
+			return finishAbsolute(date, weekday, clock, n, now)
+		}
+		n = i + 1
+	}
+	return finishAbsolute(date, weekday, clock, n, now)
+}
+
+func finishAbsolute(date time.Time, weekday *time.Weekday, clock int, n int, now time.Time) (time.Time, int, error) {
+	loc := now.Location()
+	if date.IsZero() && weekday == nil && clock < 0 {
+		return time.Time{}, 0, ErrNoTime
+	}
+	if clock < 0 {
+		clock = defaultHour * 60
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), clock/60, clock%60, 0, 0, loc)
+	}
+
+	switch {
+	case weekday != nil:
+		days := (int(*weekday) - int(now.Weekday()) + 7) % 7
+		t := at(now.AddDate(0, 0, days))
+		if !t.After(now) {
+			t = at(now.AddDate(0, 0, days+7))
+		}
+		return t, n, nil
+	case !date.IsZero():
+		t := at(date)
+		if !t.After(now) {
+			return time.Time{}, 0, ErrPastTime
+		}
+		return t, n, nil
+	}
+	// Only a time of day: the next time the clock shows it
+	t := at(now)
+	if !t.After(now) {
+		t = at(now.AddDate(0, 0, 1))
+	}
+	return t, n, nil
+}
+
+// ParseWeekday parses the English or Portuguese name of a day of the week,
+// like "monday", "mon" or "segunda".
+func ParseWeekday(word string) (time.Weekday, bool) {
+	wd, ok := weekdayWords[strings.ToLower(word)]
+	return wd, ok
+}
+
+// parseDate parses dates like "25/12", "25/12/2025" or "2025-12-25". Dates
+// without a year that already passed are moved to the next year.
+func parseDate(word string, now time.Time) *time.Time {
+	var year, month, day int
+	if m := isoDateRegex.FindStringSubmatch(word); m != nil {
+		year, _ = strconv.Atoi(m[1])
+		month, _ = strconv.Atoi(m[2])
+		day, _ = strconv.Atoi(m[3])
+	} else if m := dateRegex.FindStringSubmatch(word); m != nil {
+		day, _ = strconv.Atoi(m[1])
+		month, _ = strconv.Atoi(m[2])
+		if m[3] != "" {
+			year, _ = strconv.Atoi(m[3])
+			if year < 100 {
+				year += 2000
+			}
+		}
+	} else {
+		return nil
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return nil
+	}
+
+	explicitYear := year != 0
+	if !explicitYear {
+		year = now.Year()
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+	if date.Day() != day {
+		return nil // 31/02
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !explicitYear && date.Before(today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return &date
+}
+
+// parseClockWords parses a time of day like "9am", "9 pm", "21:30", "9h30" or
+// "noon" at the start of words, returning minutes after midnight and how many
+// words it used.
+func parseClockWords(words []string) (int, int) {
+	word := strings.TrimRight(words[0], ".,")
+	if c, ok := namedClocks[word]; ok {
+		return c, 1
+	}
+	if len(words) > 1 {
+		if next := strings.TrimRight(words[1], ".,"); next == "am" || next == "pm" {
+			if c, ok := parseClock12(word + next); ok {
+				return c, 2
+			}
+		}
+	}
+	if c, ok := parseClock12(word); ok {
+		return c, 1
+	}
+	// A bare number is too ambiguous to be a time, "9h" and "9:00" are not
+	if strings.ContainsAny(word, ":h") {
+		if c, err := ParseClock(word); err == nil {
+			return c, 1
+		}
+	}
+	return 0, 0
+}
+
+func parseClock12(word string) (int, bool) {
+	m := clock12Regex.FindStringSubmatch(word)
+	if m == nil {
+		return 0, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	var minute int
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+	if hour < 1 || hour > 12 || minute > 59 {
+		return 0, false
+	}
+	hour %= 12
+	if m[3] == "pm" {
+		hour += 12
+	}
+	return hour*60 + minute, true
+}
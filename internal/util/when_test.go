@@ -0,0 +1,67 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWhen(t *testing.T) {
+	// Wednesday, 10:30
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	for _, tt := range []struct {
+		input string
+		want  time.Time
+		rest  string
+	}{
+		{"2h take out pizza", now.Add(2 * time.Hour), "take out pizza"},
+		{"1h30m", now.Add(90 * time.Minute), ""},
+		{"in 2 hours call mom", now.Add(2 * time.Hour), "call mom"},
+		{"em 10 minutos tirar o bolo", now.Add(10 * time.Minute), "tirar o bolo"},
+		{"daqui a 1 hora e 30 minutos sair", now.Add(90 * time.Minute), "sair"},
+		{"2 days", now.Add(48 * time.Hour), ""},
+		{"tomorrow 9am meeting", at(1, 16, 9, 0), "meeting"},
+		{"tomorrow at 9 pm Meeting", at(1, 16, 21, 0), "Meeting"},
+		{"amanhã às 14h reunião", at(1, 16, 14, 0), "reunião"},
+		{"amanhã pagar conta", at(1, 16, 9, 0), "pagar conta"},
+		{"today 18:00 gym", at(1, 15, 18, 0), "gym"},
+		{"15:00 café", at(1, 15, 15, 0), "café"},
+		{"9:00 café", at(1, 16, 9, 0), "café"},
+		{"noon lunch", at(1, 15, 12, 0), "lunch"},
+		{"friday 18:00 beer", at(1, 17, 18, 0), "beer"},
+		{"next monday at 10am standup", at(1, 20, 10, 0), "standup"},
+		{"quarta 8h", at(1, 22, 8, 0), ""},
+		{"quarta 11h", at(1, 15, 11, 0), ""},
+		{"25/12 10:00 natal", at(12, 25, 10, 0), "natal"},
+		{"10/01 aniversário", time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC), "aniversário"},
+		{"2025-03-01 renovar", at(3, 1, 9, 0), "renovar"},
+		{"20/01/2025 9h30 dentista", at(1, 20, 9, 30), "dentista"},
+		{"tomorrow\nline one\nline two", at(1, 16, 9, 0), "line one\nline two"},
+	} {
+		got, rest, err := ParseWhen(tt.input, now)
+		require.NoError(t, err, tt.input)
+		assert.Equal(t, tt.want, got, tt.input)
+		assert.Equal(t, tt.rest, rest, tt.input)
+	}
+
+	for _, input := range []string{"", "take out pizza", "at the office", "31/02", "01/01/2024 too late", "today 8am"} {
+		_, _, err := ParseWhen(input, now)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseWhenLocation(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC).In(loc)
+
+	got, _, err := ParseWhen("tomorrow 9am", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 16, 12, 0, 0, 0, time.UTC), got.UTC())
+}